@@ -3,7 +3,9 @@ module mkv-sub-extractor
 go 1.25.6
 
 require (
+	github.com/abadojack/whatlanggo v1.0.1
 	github.com/luispater/matroska-go v1.2.4
+	github.com/saintfish/chardet v0.0.0-20230101081208-5e3ef4b5456d
 	golang.org/x/text v0.34.0
 )
 
@@ -0,0 +1,287 @@
+// Package ass models Advanced SubStation Alpha (ASS) and SubStation Alpha
+// (SSA) scripts as structured documents.
+//
+// Parse turns script text into a Document: [Script Info] key/value lines,
+// styles and events as typed structs following each section's declared
+// Format: order, and any other section ([Fonts], [Graphics], [Aegisub Project
+// Garbage], ...) kept as opaque lines. Document.String serialises it again.
+// Lines that are not modified are written back verbatim, so an untouched
+// document round-trips byte for byte (given consistent line endings).
+package ass
+
+import (
+	"io"
+	"strings"
+)
+
+// Document is a parsed ASS/SSA script.
+type Document struct {
+	BOM      bool      // the input started with a UTF-8 byte order mark
+	Newline  string    // line ending used by String: "\r\n" or "\n"
+	Preamble []string  // lines before the first section header
+	Sections []Section // sections in file order
+
+	// NoFinalNewline is set when the input did not end with a line ending.
+	NoFinalNewline bool
+}
+
+// Section is one [Name] section of a Document: *ScriptInfo, *StyleSection,
+// *EventSection or *RawSection.
+type Section interface {
+	// Name returns the section name without brackets, e.g. "V4+ Styles".
+	Name() string
+
+	// lines renders the section, header line included.
+	lines() []string
+}
+
+// Section names recognised by Parse (compared case-insensitively).
+const (
+	ScriptInfoName = "Script Info"
+	StylesName     = "V4+ Styles"
+	SSAStylesName  = "V4 Styles"
+	EventsName     = "Events"
+)
+
+// New returns an empty document using CRLF line endings (the ASS convention).
+func New() *Document {
+	return &Document{Newline: "\r\n"}
+}
+
+// Parse parses an ASS or SSA script. It never fails: lines it does not
+// understand are preserved verbatim, and fields that do not parse as numbers
+// read as zero but are still written back unchanged.
+func Parse(text string) *Document {
+	doc := New()
+
+	if rest, ok := strings.CutPrefix(text, "\uFEFF"); ok {
+		doc.BOM = true
+		text = rest
+	}
+	if !strings.Contains(text, "\r\n") && strings.Contains(text, "\n") {
+		doc.Newline = "\n"
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	if text == "" {
+		return doc
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		doc.NoFinalNewline = true
+	}
+
+	var cur sectionParser
+	for _, line := range lines {
+		if name, ok := sectionHeader(line); ok {
+			cur = newSectionParser(line, name)
+			doc.Sections = append(doc.Sections, cur.section())
+			continue
+		}
+		if cur == nil {
+			doc.Preamble = append(doc.Preamble, line)
+			continue
+		}
+		cur.parseLine(line)
+	}
+
+	return doc
+}
+
+// sectionParser accumulates the body lines of one section.
+type sectionParser interface {
+	section() Section
+	parseLine(line string)
+}
+
+func newSectionParser(header, name string) sectionParser {
+	switch strings.ToLower(name) {
+	case strings.ToLower(ScriptInfoName):
+		return &ScriptInfo{Header: header}
+	case strings.ToLower(StylesName), strings.ToLower(SSAStylesName):
+		return &StyleSection{table: table{Header: header}}
+	case strings.ToLower(EventsName):
+		return &EventSection{table: table{Header: header}}
+	default:
+		return &RawSection{Header: header}
+	}
+}
+
+// sectionHeader reports whether line is a "[Name]" section header.
+func sectionHeader(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) < 2 || trimmed[0] != '[' || trimmed[len(trimmed)-1] != ']' {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[1 : len(trimmed)-1]), true
+}
+
+// headerName extracts the section name from a header line.
+func headerName(header string) string {
+	name, _ := sectionHeader(header)
+	return name
+}
+
+// String serialises the document.
+func (d *Document) String() string {
+	var all []string
+	all = append(all, d.Preamble...)
+	for _, s := range d.Sections {
+		all = append(all, s.lines()...)
+	}
+
+	nl := d.Newline
+	if nl == "" {
+		nl = "\r\n"
+	}
+
+	var b strings.Builder
+	if d.BOM {
+		b.WriteString("\uFEFF")
+	}
+	b.WriteString(strings.Join(all, nl))
+	if len(all) > 0 && !d.NoFinalNewline {
+		b.WriteString(nl)
+	}
+	return b.String()
+}
+
+// WriteTo writes the serialised document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, d.String())
+	return int64(n), err
+}
+
+// Section returns the first section with the given name (case-insensitive),
+// or nil.
+func (d *Document) Section(name string) Section {
+	for _, s := range d.Sections {
+		if strings.EqualFold(s.Name(), name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// ScriptInfo returns the [Script Info] section, or nil.
+func (d *Document) ScriptInfo() *ScriptInfo {
+	for _, s := range d.Sections {
+		if info, ok := s.(*ScriptInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// Styles returns the [V4+ Styles] (or SSA [V4 Styles]) section, or nil.
+func (d *Document) Styles() *StyleSection {
+	for _, s := range d.Sections {
+		if styles, ok := s.(*StyleSection); ok {
+			return styles
+		}
+	}
+	return nil
+}
+
+// Events returns the [Events] section, or nil.
+func (d *Document) Events() *EventSection {
+	for _, s := range d.Sections {
+		if events, ok := s.(*EventSection); ok {
+			return events
+		}
+	}
+	return nil
+}
+
+// EnsureEvents returns the [Events] section, appending an empty one with the
+// standard ASS Format (EventFormat) if the document has none. A section
+// without a Format line gets EventFormat too.
+func (d *Document) EnsureEvents() *EventSection {
+	events := d.Events()
+	if events == nil {
+		events = NewEventSection()
+		d.AddSection(events)
+	}
+	if events.Format == nil {
+		events.Format = append([]string(nil), EventFormat...)
+	}
+	return events
+}
+
+// AddSection appends s to the document, separating it from the previous
+// section with a blank line.
+func (d *Document) AddSection(s Section) {
+	if n := len(d.Sections); n > 0 {
+		if lines := d.Sections[n-1].lines(); strings.TrimSpace(lines[len(lines)-1]) != "" {
+			appendBlankLine(d.Sections[n-1])
+		}
+	}
+	d.Sections = append(d.Sections, s)
+}
+
+// RemoveSection removes s from the document. It reports whether s was found.
+func (d *Document) RemoveSection(s Section) bool {
+	for i, cur := range d.Sections {
+		if cur == s {
+			d.Sections = append(d.Sections[:i], d.Sections[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// appendBlankLine adds a trailing blank line to a section.
+func appendBlankLine(s Section) {
+	switch s := s.(type) {
+	case *ScriptInfo:
+		s.Lines = append(s.Lines, InfoLine{raw: ""})
+	case *StyleSection:
+		s.body = append(s.body, bodyLine{kind: rawLine})
+	case *EventSection:
+		s.body = append(s.body, bodyLine{kind: rawLine})
+	case *RawSection:
+		s.Lines = append(s.Lines, "")
+	}
+}
+
+// NewScriptInfo returns an empty [Script Info] section.
+func NewScriptInfo() *ScriptInfo {
+	return &ScriptInfo{Header: "[" + ScriptInfoName + "]"}
+}
+
+// NewStyleSection returns an empty [V4+ Styles] section using StyleFormat.
+func NewStyleSection() *StyleSection {
+	return &StyleSection{table: table{
+		Header: "[" + StylesName + "]",
+		Format: append([]string(nil), StyleFormat...),
+	}}
+}
+
+// NewEventSection returns an empty [Events] section using EventFormat.
+func NewEventSection() *EventSection {
+	return &EventSection{table: table{
+		Header: "[" + EventsName + "]",
+		Format: append([]string(nil), EventFormat...),
+	}}
+}
+
+// RawSection is a section kept as opaque lines, such as [Fonts], [Graphics]
+// or [Aegisub Project Garbage].
+type RawSection struct {
+	Header string   // the header line as written, e.g. "[Fonts]"
+	Lines  []string // body lines, verbatim
+}
+
+// Name returns the section name.
+func (s *RawSection) Name() string { return headerName(s.Header) }
+
+func (s *RawSection) section() Section { return s }
+
+func (s *RawSection) parseLine(line string) { s.Lines = append(s.Lines, line) }
+
+func (s *RawSection) lines() []string {
+	return append([]string{s.Header}, s.Lines...)
+}
@@ -0,0 +1,206 @@
+package ass
+
+import (
+	"strings"
+	"testing"
+)
+
+// fullScript exercises comments, odd spacing, a second Format line, an Actor
+// column and the sections the model keeps opaque.
+const fullScript = "\uFEFF[Script Info]\r\n" +
+	"; Script generated by Aegisub 3.2.2\r\n" +
+	"Title:Sample\r\n" +
+	"ScriptType: v4.00+\r\n" +
+	"PlayResX:   1280\r\n" +
+	"PlayResY: 720\r\n" +
+	"\r\n" +
+	"[Aegisub Project Garbage]\r\n" +
+	"Audio File: ep01.mkv\r\n" +
+	"Scroll Position: 12\r\n" +
+	"\r\n" +
+	"[V4+ Styles]\r\n" +
+	"Format: Name,Fontname,Fontsize,PrimaryColour,SecondaryColour,OutlineColour,BackColour,Bold,Italic,Underline,StrikeOut,ScaleX,ScaleY,Spacing,Angle,BorderStyle,Outline,Shadow,Alignment,MarginL,MarginR,MarginV,Encoding\r\n" +
+	"Style: Default,Arial,40.0,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,-1,0,0,0,100,100,0,0,1,2,1,2,20,20,030,1\r\n" +
+	";Style: Disabled,Arial,40,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,2,20,20,30,1\r\n" +
+	"Style:  Sign , Times New Roman ,30,&H0000FFFF,&H000000FF,&H00000000,&H00000000,0,1,0,0,100,100,0,0,1,0,0,8,10,10,10,1\r\n" +
+	"\r\n" +
+	"[Fonts]\r\n" +
+	"fontname: custom_0.ttf\r\n" +
+	"M)P!>3!\"\"!)`,A(C)$``\r\n" +
+	"\r\n" +
+	"[Graphics]\r\n" +
+	"filename: logo.png\r\n" +
+	"M)P!>3!\r\n" +
+	"\r\n" +
+	"[Events]\r\n" +
+	"Format: Layer, Start, End, Style, Actor, MarginL, MarginR, MarginV, Effect, Text\r\n" +
+	"Dialogue: 0,0:00:01.00,0:00:02.50,Default,Bob,0000,0000,0000,,Hello, world\r\n" +
+	"Comment: 1,0:00:03.00,0:00:04.00,Sign,,0,0,0,karaoke, {\\k10}note \r\n" +
+	"Dialogue:0,0:00:05.00,0:00:06.00,Default,,0,0,0,,{\\pos(640,360)}Centered\r\n"
+
+func TestParse_RoundTrip(t *testing.T) {
+	inputs := map[string]string{
+		"full":           fullScript,
+		"LF":             strings.ReplaceAll(strings.TrimPrefix(fullScript, "\uFEFF"), "\r\n", "\n"),
+		"no final EOL":   "[Script Info]\nTitle: x",
+		"preamble":       "junk before\n\n[Script Info]\nTitle: x\n",
+		"unknown fields": "[V4 Styles]\nFormat: Name, Fontsize, AlphaLevel\nStyle: A,12.50,0\n",
+		"empty":          "",
+	}
+	for name, in := range inputs {
+		t.Run(name, func(t *testing.T) {
+			if got := Parse(in).String(); got != in {
+				t.Errorf("round trip changed the script:\ngot  %q\nwant %q", got, in)
+			}
+		})
+	}
+}
+
+func TestParse_Model(t *testing.T) {
+	doc := Parse(fullScript)
+
+	if !doc.BOM || doc.Newline != "\r\n" {
+		t.Errorf("BOM = %v, Newline = %q", doc.BOM, doc.Newline)
+	}
+
+	var names []string
+	for _, s := range doc.Sections {
+		names = append(names, s.Name())
+	}
+	want := "Script Info|Aegisub Project Garbage|V4+ Styles|Fonts|Graphics|Events"
+	if got := strings.Join(names, "|"); got != want {
+		t.Errorf("sections = %s, want %s", got, want)
+	}
+
+	if v, ok := doc.ScriptInfo().Get("playresx"); !ok || v != "1280" {
+		t.Errorf("PlayResX = %q, %v", v, ok)
+	}
+
+	styles := doc.Styles()
+	if len(styles.Styles) != 2 {
+		t.Fatalf("got %d styles, want 2 (commented style is not a style)", len(styles.Styles))
+	}
+	def := styles.Style("Default")
+	if def == nil || def.Fontsize != 40 || !def.Bold || def.MarginV != 30 || def.BackColour != "&H80000000" {
+		t.Errorf("Default style = %+v", def)
+	}
+	if sign := styles.Style("Sign"); sign == nil || sign.Fontname != "Times New Roman" || !sign.Italic || sign.Alignment != 8 {
+		t.Errorf("Sign style = %+v", sign)
+	}
+
+	events := doc.Events().Events
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	if ev := events[0]; ev.Type != Dialogue || ev.Start != 1_000_000_000 || ev.End != 2_500_000_000 || ev.Name != "Bob" || ev.Text != "Hello, world" {
+		t.Errorf("event 0 = %+v", ev)
+	}
+	if ev := events[1]; ev.Type != Comment || ev.Layer != 1 || ev.Effect != "karaoke" || ev.Text != ` {\k10}note ` {
+		t.Errorf("event 1 = %+v", ev)
+	}
+
+	fonts, ok := doc.Section("fonts").(*RawSection)
+	if !ok || len(fonts.Lines) != 3 || fonts.Lines[0] != "fontname: custom_0.ttf" {
+		t.Errorf("[Fonts] = %#v", doc.Section("fonts"))
+	}
+}
+
+func TestDocument_Edits(t *testing.T) {
+	doc := Parse(fullScript)
+
+	doc.ScriptInfo().Set("PlayResX", "1920")
+	doc.ScriptInfo().Set("WrapStyle", "0")
+	doc.ScriptInfo().Delete("Title")
+	doc.Styles().Style("Sign").Fontsize = 45
+	events := doc.Events()
+	events.Events[0].Text = "Changed"
+	events.Events = append(events.Events, &Event{Type: Dialogue, Start: 7_000_000_000, End: 8_000_000_000, Style: "Default", Text: "New"})
+
+	got := doc.String()
+	for _, want := range []string{
+		"PlayResX: 1920\r\nPlayResY: 720\r\nWrapStyle: 0\r\n\r\n[Aegisub",
+		// Edited lines are re-serialised, untouched ones keep their spacing.
+		// Event margins keep their digits while their value is unchanged.
+		"Style: Sign,Times New Roman,45,",
+		"Style: Default,Arial,40.0,",
+		"Dialogue: 0,0:00:01.00,0:00:02.50,Default,Bob,0000,0000,0000,,Changed\r\n",
+		"Comment: 1,0:00:03.00,0:00:04.00,Sign,,0,0,0,karaoke, {\\k10}note \r\n",
+		"{\\pos(640,360)}Centered\r\nDialogue: 0,0:00:07.00,0:00:08.00,Default,,0,0,0,,New\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("edited script missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Title:") {
+		t.Error("deleted key still present")
+	}
+}
+
+func TestDocument_EnsureEvents(t *testing.T) {
+	doc := Parse("[Script Info]\nTitle: x\n[V4+ Styles]\nFormat: Name\nStyle: Default\n")
+	events := doc.EnsureEvents()
+	events.Events = append(events.Events, &Event{Type: Dialogue, Style: "Default", Text: "Hi"})
+
+	want := "[Script Info]\nTitle: x\n[V4+ Styles]\nFormat: Name\nStyle: Default\n\n[Events]\n" +
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+		"Dialogue: 0,0:00:00.00,0:00:00.00,Default,,0,0,0,,Hi\n"
+	if got := doc.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	// An [Events] section without a Format line gets one ahead of its events.
+	doc = Parse("[Events]\n\n")
+	doc.EnsureEvents().Events = append(doc.Events().Events, &Event{Type: Comment, Text: "x"})
+	want = "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+		"Comment: 0,0:00:00.00,0:00:00.00,,,0,0,0,,x\n\n"
+	if got := doc.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestStyleSection_FormatChange(t *testing.T) {
+	doc := Parse("[V4 Styles]\nFormat: Name, Fontsize, TertiaryColour, AlphaLevel\nStyle: A,20,&H00112233,0\n")
+	styles := doc.Styles()
+	styles.Format = []string{"Name", "Fontsize", "OutlineColour", "ScaleX"}
+
+	want := "[V4 Styles]\nFormat: Name, Fontsize, OutlineColour, ScaleX\nStyle: A,20,&H00112233,100\n"
+	if got := doc.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	if styles.Styles[0].Extra["AlphaLevel"] != "0" {
+		t.Errorf("AlphaLevel not kept in Extra: %v", styles.Styles[0].Extra)
+	}
+}
+
+func TestStyle_Equal(t *testing.T) {
+	a := Parse("[V4+ Styles]\nFormat: Name, Fontsize, Bold\nStyle: A,20.0,1\n").Styles().Styles[0]
+	b := Parse("[V4+ Styles]\nFormat: Name, Bold, Fontsize\nStyle: A, -1, 20\n").Styles().Styles[0]
+	if !a.Equal(b) {
+		t.Errorf("styles parsed from different text should be equal: %+v, %+v", a, b)
+	}
+	b.Fontsize = 21
+	if a.Equal(b) {
+		t.Error("styles with different Fontsize are equal")
+	}
+	c := Parse("[V4 Styles]\nFormat: Name, Fontsize, Bold, AlphaLevel\nStyle: A,20,1,0\n").Styles().Styles[0]
+	if a.Equal(c) {
+		t.Error("styles with different Extra fields are equal")
+	}
+}
+
+func TestEvent_SetFieldKeepsText(t *testing.T) {
+	ev := &Event{Text: "x"}
+	ev.SetField("MarginL", "0010")
+	ev.SetField("MarginR", "")
+	ev.SetField("MarginV", "auto")
+	if ev.MarginL != 10 || ev.MarginR != 0 || ev.MarginV != 0 {
+		t.Fatalf("margins = %d,%d,%d, want 10,0,0", ev.MarginL, ev.MarginR, ev.MarginV)
+	}
+	if got, want := ev.format(EventFormat), "Dialogue: 0,0:00:00.00,0:00:00.00,,,0010,0,auto,,x"; got != want {
+		t.Errorf("format() = %q, want %q", got, want)
+	}
+	ev.MarginL = 15
+	if got, want := ev.format(EventFormat), "Dialogue: 0,0:00:00.00,0:00:00.00,,,15,0,auto,,x"; got != want {
+		t.Errorf("format() after edit = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+package ass
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Event line types of the [Events] section.
+const (
+	Dialogue = "Dialogue"
+	Comment  = "Comment"
+)
+
+// eventTypes lists the line keys parsed as events.
+var eventTypes = []string{Dialogue, Comment, "Picture", "Sound", "Movie", "Command"}
+
+// EventSection is the [Events] section.
+type EventSection struct {
+	table
+	Events []*Event
+}
+
+// Event is one Dialogue, Comment (or other event type) line.
+type Event struct {
+	Type    string // "Dialogue", "Comment", ...
+	Layer   int
+	Start   uint64 // nanoseconds
+	End     uint64 // nanoseconds
+	Style   string
+	Name    string // "Actor" in older scripts
+	MarginL int
+	MarginR int
+	MarginV int
+	Effect  string
+	Text    string
+
+	// Extra holds the values of Format fields without a typed counterpart,
+	// such as SSA's Marked ("Marked=0"), keyed by field name as declared.
+	Extra map[string]string
+
+	raw, canon string // the line as parsed and its re-serialisation, to detect edits
+
+	// verbatim holds the text of integer fields written other than as
+	// strconv.Itoa would, such as "0000" margins, keyed by fieldKey. It is
+	// rendered while the field keeps the value read from it.
+	verbatim map[string]string
+}
+
+// Name returns the section name.
+func (s *EventSection) Name() string { return headerName(s.Header) }
+
+func (s *EventSection) section() Section { return s }
+
+func (s *EventSection) parseLine(line string) {
+	key, value, ok := splitKeyed(line)
+	if ok && s.parseFormat(key, value, line) {
+		return
+	}
+	typ := ""
+	for _, t := range eventTypes {
+		if ok && strings.EqualFold(key, t) {
+			typ = t
+		}
+	}
+	if typ == "" {
+		s.body = append(s.body, bodyLine{kind: rawLine, raw: line})
+		return
+	}
+
+	ev := &Event{Type: typ}
+	format := s.Format
+	if format == nil {
+		format = EventFormat
+	}
+	for i, v := range fieldValues(value, len(format)) {
+		ev.set(format[i], v)
+	}
+	ev.raw, ev.canon = line, ev.format(format)
+
+	s.Events = append(s.Events, ev)
+	s.body = append(s.body, bodyLine{kind: entryLine})
+}
+
+func (s *EventSection) lines() []string {
+	format := s.Format
+	if format == nil {
+		format = EventFormat
+	}
+	entries := make([]string, len(s.Events))
+	for i, ev := range s.Events {
+		entries[i] = ev.line(format)
+	}
+	return s.render(entries)
+}
+
+// line renders the event for format, verbatim if nothing changed.
+func (ev *Event) line(format []string) string {
+	out := ev.format(format)
+	if ev.raw != "" && out == ev.canon {
+		return ev.raw
+	}
+	return out
+}
+
+// format serialises the event with the given fields.
+func (ev *Event) format(format []string) string {
+	values := make([]string, len(format))
+	for i, f := range format {
+		values[i] = ev.get(f)
+	}
+	typ := ev.Type
+	if typ == "" {
+		typ = Dialogue
+	}
+	return typ + ": " + strings.Join(values, ",")
+}
+
+// SetField assigns the field named field (as in a Format line) from its text
+// value, as parsing does: integer fields written as "0000" or not as numbers
+// keep that text until their value is changed, while empty ones read as 0.
+func (ev *Event) SetField(field, v string) {
+	ev.set(field, v)
+}
+
+// set assigns one field from its text value. Text is kept exactly as written;
+// other fields are trimmed.
+func (ev *Event) set(field, v string) {
+	key := fieldKey(field)
+	if key != "text" {
+		v = strings.TrimSpace(v)
+	}
+	if _, ok := ev.intField(key); ok {
+		delete(ev.verbatim, key)
+		if v != "" && strconv.Itoa(parseInt(v)) != v {
+			if ev.verbatim == nil {
+				ev.verbatim = make(map[string]string)
+			}
+			ev.verbatim[key] = v
+		}
+	}
+	switch key {
+	case "layer":
+		ev.Layer = parseInt(v)
+	case "start":
+		ev.Start, _ = ParseTime(v)
+	case "end":
+		ev.End, _ = ParseTime(v)
+	case "style":
+		ev.Style = v
+	case "name", "actor":
+		ev.Name = v
+	case "marginl":
+		ev.MarginL = parseInt(v)
+	case "marginr":
+		ev.MarginR = parseInt(v)
+	case "marginv":
+		ev.MarginV = parseInt(v)
+	case "effect":
+		ev.Effect = v
+	case "text":
+		ev.Text = v
+	default:
+		if ev.Extra == nil {
+			ev.Extra = make(map[string]string)
+		}
+		ev.Extra[field] = v
+	}
+}
+
+// get renders one field as text.
+func (ev *Event) get(field string) string {
+	key := fieldKey(field)
+	if n, ok := ev.intField(key); ok {
+		if v, ok := ev.verbatim[key]; ok && parseInt(v) == n {
+			return v
+		}
+		return strconv.Itoa(n)
+	}
+	switch key {
+	case "start":
+		return FormatTime(ev.Start)
+	case "end":
+		return FormatTime(ev.End)
+	case "style":
+		return ev.Style
+	case "name", "actor":
+		return ev.Name
+	case "effect":
+		return ev.Effect
+	case "text":
+		return ev.Text
+	default:
+		return ev.Extra[field]
+	}
+}
+
+// intField returns the value of the integer field with the given key.
+func (ev *Event) intField(key string) (int, bool) {
+	switch key {
+	case "layer":
+		return ev.Layer, true
+	case "marginl":
+		return ev.MarginL, true
+	case "marginr":
+		return ev.MarginR, true
+	case "marginv":
+		return ev.MarginV, true
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+package ass
+
+import "strings"
+
+// ScriptInfo is the [Script Info] section: "Key: Value" lines plus comments.
+type ScriptInfo struct {
+	Header string // the header line as written, e.g. "[Script Info]"
+	Lines  []InfoLine
+}
+
+// InfoLine is one line of [Script Info]. Comments (";" lines), blank lines and
+// anything else without a key have an empty Key and are written verbatim.
+type InfoLine struct {
+	Key   string
+	Value string
+
+	raw        string // the line as parsed
+	key, value string // Key and Value as parsed, to detect edits
+}
+
+// InfoComment returns a [Script Info] comment line ("; text").
+func InfoComment(text string) InfoLine {
+	return InfoLine{raw: "; " + text}
+}
+
+// Name returns the section name.
+func (s *ScriptInfo) Name() string { return headerName(s.Header) }
+
+func (s *ScriptInfo) section() Section { return s }
+
+func (s *ScriptInfo) parseLine(line string) {
+	trimmed := strings.TrimSpace(line)
+	key, value, ok := strings.Cut(trimmed, ":")
+	if !ok || strings.HasPrefix(trimmed, ";") || strings.TrimSpace(key) == "" {
+		s.Lines = append(s.Lines, InfoLine{raw: line})
+		return
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	s.Lines = append(s.Lines, InfoLine{Key: key, Value: value, raw: line, key: key, value: value})
+}
+
+func (s *ScriptInfo) lines() []string {
+	out := []string{s.Header}
+	for _, l := range s.Lines {
+		out = append(out, l.String())
+	}
+	return out
+}
+
+// String renders the line, verbatim unless Key or Value were changed.
+func (l InfoLine) String() string {
+	if l.Key == "" || (l.Key == l.key && l.Value == l.value) {
+		return l.raw
+	}
+	return l.Key + ": " + l.Value
+}
+
+// Get returns the value of key (case-insensitive) and whether it is present.
+func (s *ScriptInfo) Get(key string) (string, bool) {
+	if i := s.index(key); i >= 0 {
+		return s.Lines[i].Value, true
+	}
+	return "", false
+}
+
+// Set sets key to value, replacing the existing line in place or adding a new
+// one after the last key line (ahead of any trailing blank lines).
+func (s *ScriptInfo) Set(key, value string) {
+	if i := s.index(key); i >= 0 {
+		s.Lines[i].Value = value
+		return
+	}
+
+	at := 0
+	for i, l := range s.Lines {
+		if strings.TrimSpace(l.String()) != "" {
+			at = i + 1
+		}
+	}
+	s.Lines = append(s.Lines, InfoLine{})
+	copy(s.Lines[at+1:], s.Lines[at:])
+	s.Lines[at] = InfoLine{Key: key, Value: value}
+}
+
+// Delete removes every line setting key.
+func (s *ScriptInfo) Delete(key string) {
+	kept := s.Lines[:0]
+	for _, l := range s.Lines {
+		if l.Key == "" || !strings.EqualFold(l.Key, key) {
+			kept = append(kept, l)
+		}
+	}
+	s.Lines = kept
+}
+
+// index returns the position of the first line setting key, or -1.
+func (s *ScriptInfo) index(key string) int {
+	for i, l := range s.Lines {
+		if l.Key != "" && strings.EqualFold(l.Key, key) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,293 @@
+package ass
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// StyleSection is the [V4+ Styles] section, or [V4 Styles] in SSA scripts.
+type StyleSection struct {
+	table
+	Styles []*Style
+}
+
+// Style is one "Style:" line. Fields missing from the section's Format keep
+// their zero value (ScaleX and ScaleY default to 100) and are not written.
+type Style struct {
+	Name            string
+	Fontname        string
+	Fontsize        float64
+	PrimaryColour   string
+	SecondaryColour string
+	OutlineColour   string // TertiaryColour in SSA
+	BackColour      string
+	Bold            bool
+	Italic          bool
+	Underline       bool
+	StrikeOut       bool
+	ScaleX          float64
+	ScaleY          float64
+	Spacing         float64
+	Angle           float64
+	BorderStyle     int
+	Outline         float64
+	Shadow          float64
+	Alignment       int
+	MarginL         int
+	MarginR         int
+	MarginV         int
+	Encoding        int
+
+	// Extra holds the values of Format fields without a typed counterpart,
+	// such as SSA's AlphaLevel, keyed by field name as declared.
+	Extra map[string]string
+
+	raw, canon string // the line as parsed and its re-serialisation, to detect edits
+}
+
+// NewStyle returns a style with the renderer defaults for the given name.
+func NewStyle(name string) *Style {
+	return &Style{
+		Name:            name,
+		Fontname:        "Arial",
+		Fontsize:        20,
+		PrimaryColour:   "&H00FFFFFF",
+		SecondaryColour: "&H000000FF",
+		OutlineColour:   "&H00000000",
+		BackColour:      "&H00000000",
+		ScaleX:          100,
+		ScaleY:          100,
+		BorderStyle:     1,
+		Outline:         2,
+		Shadow:          2,
+		Alignment:       2,
+		MarginL:         10,
+		MarginR:         10,
+		MarginV:         10,
+		Encoding:        1,
+	}
+}
+
+// Name returns the section name.
+func (s *StyleSection) Name() string { return headerName(s.Header) }
+
+// Style returns the style with the given name, or nil. Like renderers, the
+// lookup is case-sensitive and the last definition wins.
+func (s *StyleSection) Style(name string) *Style {
+	for i := len(s.Styles) - 1; i >= 0; i-- {
+		if s.Styles[i].Name == name {
+			return s.Styles[i]
+		}
+	}
+	return nil
+}
+
+func (s *StyleSection) section() Section { return s }
+
+func (s *StyleSection) parseLine(line string) {
+	key, value, ok := splitKeyed(line)
+	if ok && s.parseFormat(key, value, line) {
+		return
+	}
+	if !ok || !strings.EqualFold(key, "Style") {
+		s.body = append(s.body, bodyLine{kind: rawLine, raw: line})
+		return
+	}
+
+	st := &Style{ScaleX: 100, ScaleY: 100}
+	format := s.Format
+	if format == nil {
+		format = StyleFormat
+	}
+	for i, v := range fieldValues(value, len(format)) {
+		st.set(format[i], strings.TrimSpace(v))
+	}
+	st.raw, st.canon = line, st.format(format)
+
+	s.Styles = append(s.Styles, st)
+	s.body = append(s.body, bodyLine{kind: entryLine})
+}
+
+func (s *StyleSection) lines() []string {
+	format := s.Format
+	if format == nil {
+		format = StyleFormat
+	}
+	entries := make([]string, len(s.Styles))
+	for i, st := range s.Styles {
+		entries[i] = st.line(format)
+	}
+	return s.render(entries)
+}
+
+// line renders the style for format, verbatim if nothing changed.
+func (st *Style) line(format []string) string {
+	out := st.format(format)
+	if st.raw != "" && out == st.canon {
+		return st.raw
+	}
+	return out
+}
+
+// format serialises the style as a "Style:" line with the given fields.
+func (st *Style) format(format []string) string {
+	values := make([]string, len(format))
+	for i, f := range format {
+		values[i] = st.get(f)
+	}
+	return "Style: " + strings.Join(values, ",")
+}
+
+// Equal reports whether two styles render alike: every typed field and every
+// Extra value match. The text the styles were parsed from is not compared.
+func (st *Style) Equal(o *Style) bool {
+	return st.format(StyleFormat) == o.format(StyleFormat) && maps.Equal(st.Extra, o.Extra)
+}
+
+// set assigns one field from its text value. Numbers that do not parse read as 0.
+func (st *Style) set(field, v string) {
+	switch fieldKey(field) {
+	case "name":
+		st.Name = v
+	case "fontname":
+		st.Fontname = v
+	case "fontsize":
+		st.Fontsize = parseFloat(v)
+	case "primarycolour":
+		st.PrimaryColour = v
+	case "secondarycolour":
+		st.SecondaryColour = v
+	case "outlinecolour", "tertiarycolour":
+		st.OutlineColour = v
+	case "backcolour":
+		st.BackColour = v
+	case "bold":
+		st.Bold = parseBool(v)
+	case "italic":
+		st.Italic = parseBool(v)
+	case "underline":
+		st.Underline = parseBool(v)
+	case "strikeout":
+		st.StrikeOut = parseBool(v)
+	case "scalex":
+		st.ScaleX = parseFloat(v)
+	case "scaley":
+		st.ScaleY = parseFloat(v)
+	case "spacing":
+		st.Spacing = parseFloat(v)
+	case "angle":
+		st.Angle = parseFloat(v)
+	case "borderstyle":
+		st.BorderStyle = parseInt(v)
+	case "outline":
+		st.Outline = parseFloat(v)
+	case "shadow":
+		st.Shadow = parseFloat(v)
+	case "alignment":
+		st.Alignment = parseInt(v)
+	case "marginl":
+		st.MarginL = parseInt(v)
+	case "marginr":
+		st.MarginR = parseInt(v)
+	case "marginv":
+		st.MarginV = parseInt(v)
+	case "encoding":
+		st.Encoding = parseInt(v)
+	default:
+		if st.Extra == nil {
+			st.Extra = make(map[string]string)
+		}
+		st.Extra[field] = v
+	}
+}
+
+// get renders one field as text.
+func (st *Style) get(field string) string {
+	switch fieldKey(field) {
+	case "name":
+		return st.Name
+	case "fontname":
+		return st.Fontname
+	case "fontsize":
+		return FormatNumber(st.Fontsize)
+	case "primarycolour":
+		return st.PrimaryColour
+	case "secondarycolour":
+		return st.SecondaryColour
+	case "outlinecolour", "tertiarycolour":
+		return st.OutlineColour
+	case "backcolour":
+		return st.BackColour
+	case "bold":
+		return formatBool(st.Bold)
+	case "italic":
+		return formatBool(st.Italic)
+	case "underline":
+		return formatBool(st.Underline)
+	case "strikeout":
+		return formatBool(st.StrikeOut)
+	case "scalex":
+		return FormatNumber(st.ScaleX)
+	case "scaley":
+		return FormatNumber(st.ScaleY)
+	case "spacing":
+		return FormatNumber(st.Spacing)
+	case "angle":
+		return FormatNumber(st.Angle)
+	case "borderstyle":
+		return strconv.Itoa(st.BorderStyle)
+	case "outline":
+		return FormatNumber(st.Outline)
+	case "shadow":
+		return FormatNumber(st.Shadow)
+	case "alignment":
+		return strconv.Itoa(st.Alignment)
+	case "marginl":
+		return strconv.Itoa(st.MarginL)
+	case "marginr":
+		return strconv.Itoa(st.MarginR)
+	case "marginv":
+		return strconv.Itoa(st.MarginV)
+	case "encoding":
+		return strconv.Itoa(st.Encoding)
+	default:
+		return st.Extra[field]
+	}
+}
+
+// FormatNumber renders a numeric field without a trailing ".0" for whole values.
+func FormatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatBool renders an ASS boolean field (-1 = true, 0 = false).
+func formatBool(b bool) string {
+	if b {
+		return "-1"
+	}
+	return "0"
+}
+
+// parseBool reads an ASS boolean field: any non-zero value is true.
+func parseBool(v string) bool {
+	return parseInt(v) != 0
+}
+
+// parseInt reads an integer field leniently: "10.5" reads as 10 and
+// unparseable text as 0.
+func parseInt(v string) int {
+	if n, err := strconv.Atoi(v); err == nil {
+		return n
+	}
+	return int(parseFloat(v))
+}
+
+// parseFloat reads a numeric field, returning 0 for unparseable text.
+func parseFloat(v string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
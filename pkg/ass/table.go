@@ -0,0 +1,160 @@
+package ass
+
+import "strings"
+
+// Standard ASS Format field lists, used for new sections and by writers that
+// generate scripts from scratch.
+var (
+	StyleFormat = []string{
+		"Name", "Fontname", "Fontsize", "PrimaryColour", "SecondaryColour",
+		"OutlineColour", "BackColour", "Bold", "Italic", "Underline", "StrikeOut",
+		"ScaleX", "ScaleY", "Spacing", "Angle", "BorderStyle", "Outline", "Shadow",
+		"Alignment", "MarginL", "MarginR", "MarginV", "Encoding",
+	}
+	EventFormat = []string{
+		"Layer", "Start", "End", "Style", "Name",
+		"MarginL", "MarginR", "MarginV", "Effect", "Text",
+	}
+)
+
+// lineKind classifies the body lines of a styles or events section.
+type lineKind int
+
+const (
+	rawLine    lineKind = iota // comment, blank or unrecognised line
+	formatLine                 // the first "Format:" line
+	entryLine                  // placeholder for the next Style or event
+)
+
+// bodyLine is one body line of a styles or events section. Entries are only
+// placeholders: the k-th entryLine renders the section's k-th entry, so
+// entries can be edited, added and removed through the typed slice while the
+// surrounding comments stay where they were.
+type bodyLine struct {
+	kind lineKind
+	raw  string
+}
+
+// table holds the state shared by the styles and events sections.
+type table struct {
+	Header string   // the header line as written, e.g. "[Events]"
+	Format []string // field names from the Format line, in declared order
+
+	body      []bodyLine
+	formatRaw []string // Format as parsed, to detect edits
+}
+
+// splitKeyed splits "Key: value" at the first colon. The key is trimmed and
+// the value has its leading spaces removed.
+func splitKeyed(line string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(line, ":")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimLeft(value, " \t"), true
+}
+
+// parseFormat handles a Format line. It reports false for lines that are not
+// a Format line, and treats every Format line after the first as a raw line.
+func (t *table) parseFormat(key, value, line string) bool {
+	if !strings.EqualFold(key, "Format") {
+		return false
+	}
+	if t.Format != nil {
+		t.body = append(t.body, bodyLine{kind: rawLine, raw: line})
+		return true
+	}
+	for _, f := range strings.Split(value, ",") {
+		t.Format = append(t.Format, strings.TrimSpace(f))
+	}
+	t.formatRaw = append([]string(nil), t.Format...)
+	t.body = append(t.body, bodyLine{kind: formatLine, raw: line})
+	return true
+}
+
+// formatString renders the Format line, verbatim unless Format was changed.
+func (t *table) formatString(raw string) string {
+	if raw != "" && strings.Join(t.Format, "\x00") == strings.Join(t.formatRaw, "\x00") {
+		return raw
+	}
+	return "Format: " + strings.Join(t.Format, ", ")
+}
+
+// render writes the section with entries substituted for their placeholders.
+// Entries beyond the original placeholders follow the last Format or entry
+// line; a section without a Format line gets one ahead of its first entry.
+func (t *table) render(entries []string) []string {
+	out := []string{t.Header}
+
+	last := -1
+	hasFormat := false
+	for i, l := range t.body {
+		if l.kind != rawLine {
+			last = i
+		}
+		hasFormat = hasFormat || l.kind == formatLine
+	}
+
+	next := 0
+	flush := func() {
+		for ; next < len(entries); next++ {
+			out = append(out, entries[next])
+		}
+	}
+	if last < 0 {
+		// Nothing to anchor to: put the Format line and entries right after
+		// the section's own lines, ahead of any blank separator lines.
+		body := t.body
+		var trailing []bodyLine
+		for len(body) > 0 && strings.TrimSpace(body[len(body)-1].raw) == "" {
+			trailing = append([]bodyLine{body[len(body)-1]}, trailing...)
+			body = body[:len(body)-1]
+		}
+		for _, l := range body {
+			out = append(out, l.raw)
+		}
+		if t.Format != nil {
+			out = append(out, t.formatString(""))
+		}
+		flush()
+		for _, l := range trailing {
+			out = append(out, l.raw)
+		}
+		return out
+	}
+	if !hasFormat && t.Format != nil {
+		out = append(out, t.formatString(""))
+	}
+
+	for i, l := range t.body {
+		switch l.kind {
+		case formatLine:
+			out = append(out, t.formatString(l.raw))
+		case entryLine:
+			if next < len(entries) {
+				out = append(out, entries[next])
+				next++
+			}
+		default:
+			out = append(out, l.raw)
+		}
+		if i == last {
+			flush()
+		}
+	}
+	return out
+}
+
+// fieldValues splits an entry's value into one string per Format field. The
+// last field takes the remainder of the line, so Text may contain commas.
+func fieldValues(value string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	return strings.SplitN(value, ",", n)
+}
+
+// fieldKey normalises a Format field name for matching.
+func fieldKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
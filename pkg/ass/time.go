@@ -0,0 +1,60 @@
+package ass
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatTime renders nanoseconds as an ASS timestamp "H:MM:SS.CC" (single-digit
+// hour, centisecond precision).
+//
+// Centiseconds are rounded (not truncated): (ns + 5_000_000) / 10_000_000.
+// Uses integer arithmetic only to avoid floating-point drift.
+func FormatTime(ns uint64) string {
+	cs := (ns + 5_000_000) / 10_000_000
+
+	hours := cs / 360000
+	cs %= 360000
+	minutes := cs / 6000
+	cs %= 6000
+	seconds := cs / 100
+	centiseconds := cs % 100
+
+	return fmt.Sprintf("%d:%02d:%02d.%02d", hours, minutes, seconds, centiseconds)
+}
+
+// ParseTime parses an ASS timestamp "H:MM:SS.CC" into nanoseconds. Like
+// renderers it is lenient: the hour may have any number of digits and the
+// fraction any precision ("0:00:01.5" is 1.5s).
+func ParseTime(s string) (uint64, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid ASS timestamp %q", s)
+	}
+
+	h, err1 := strconv.ParseUint(parts[0], 10, 64)
+	m, err2 := strconv.ParseUint(parts[1], 10, 64)
+	secs, frac, _ := strings.Cut(parts[2], ".")
+	sec, err3 := strconv.ParseUint(secs, 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("invalid ASS timestamp %q", s)
+	}
+
+	var fracNS uint64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		n, err := strconv.ParseUint(frac, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ASS timestamp %q", s)
+		}
+		for i := len(frac); i < 9; i++ {
+			n *= 10
+		}
+		fracNS = n
+	}
+
+	return ((h*60+m)*60+sec)*1_000_000_000 + fracNS, nil
+}
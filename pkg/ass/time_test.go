@@ -0,0 +1,31 @@
+package ass
+
+import "testing"
+
+func TestParseTime(t *testing.T) {
+	tests := map[string]uint64{
+		"0:00:00.00":  0,
+		"0:00:01.50":  1_500_000_000,
+		"1:02:03.04":  3_723_040_000_000,
+		"0:00:01.5":   1_500_000_000,
+		"10:00:00.00": 36_000_000_000_000,
+		" 0:00:02.00": 2_000_000_000,
+	}
+	for in, want := range tests {
+		got, err := ParseTime(in)
+		if err != nil || got != want {
+			t.Errorf("ParseTime(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "0:00", "a:00:00.00", "0:00:00.xx"} {
+		if _, err := ParseTime(bad); err == nil {
+			t.Errorf("ParseTime(%q): expected error", bad)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	if got := FormatTime(3_723_045_000_000); got != "1:02:03.05" {
+		t.Errorf("FormatTime = %s, want 1:02:03.05", got)
+	}
+}
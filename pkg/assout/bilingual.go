@@ -0,0 +1,227 @@
+package assout
+
+import (
+	"io"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+
+	"mkv-sub-extractor/pkg/ass"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// DefaultSecondaryScale is the secondary track's font size relative to the
+// primary's when BilingualOptions.SecondaryScale is zero.
+const DefaultSecondaryScale = 0.7
+
+// bilingualLineHeight is the line height, relative to the font size, used to
+// keep the two tracks' lines apart.
+const bilingualLineHeight = 1.2
+
+// BilingualTrack is one language of a bilingual file.
+type BilingualTrack struct {
+	Events []subtitle.SubtitleEvent
+
+	// SRT marks events holding SRT text, which is converted as by
+	// WriteSRTAsASS. Other events hold ASS dialogue text and keep their
+	// override tags, but not their styles.
+	SRT bool
+}
+
+// BilingualOptions controls WriteBilingualASS.
+type BilingualOptions struct {
+	// Template is the look of the primary track's "Default" style; nil uses
+	// DefaultStyleTemplate. The "Secondary" style is derived from it.
+	Template *StyleTemplate
+
+	// PlayRes rescales the template to this script resolution, as in
+	// SRTOptions. Zero keeps the template's.
+	PlayRes Resolution
+
+	// PrimaryFont and SecondaryFont set each style's font and Encoding; an
+	// empty Fontname keeps the template's font. A zero Fontsize keeps the
+	// template's size for the primary track and gives the secondary
+	// SecondaryScale times the primary's.
+	PrimaryFont, SecondaryFont LanguageFont
+
+	// SecondaryScale is the secondary font size relative to the primary's;
+	// zero means DefaultSecondaryScale.
+	SecondaryScale float64
+
+	// SecondaryAbove places the secondary track's lines above the primary
+	// track's instead of below them.
+	SecondaryAbove bool
+
+	// Stack pairs each secondary event with the primary event it overlaps
+	// longest and writes each pair as one line: the primary text and, after
+	// a \N, the secondary text with \fn and \fs overrides (or the other way
+	// round with SecondaryAbove). Events without a partner keep their own
+	// lines.
+	Stack bool
+}
+
+// WriteBilingualASS writes two subtitle tracks as one ASS file with a style
+// per track: "Default" for the primary track and a smaller "Secondary" for
+// the other. Without Stack, the styles' vertical margins keep one line of
+// each track apart, the secondary below the primary (at the template's
+// margin) or above it; the spacing assumes single-line events in a top or
+// bottom alignment. Events are written in start order.
+func WriteBilingualASS(w io.Writer, primary, secondary BilingualTrack, opts BilingualOptions) error {
+	doc, def, sec := bilingualHeader(opts)
+	section := doc.EnsureEvents()
+
+	var events []*ass.Event
+	if opts.Stack {
+		events = stackEvents(primary, secondary, def, sec, opts.SecondaryAbove)
+	} else {
+		for _, ev := range primary.Events {
+			events = append(events, primary.event(ev, def.Name))
+		}
+		for _, ev := range secondary.Events {
+			events = append(events, secondary.event(ev, sec.Name))
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start < events[j].Start })
+	section.Events = append(section.Events, events...)
+
+	return writeDocument(w, doc)
+}
+
+// bilingualHeader builds the header for WriteBilingualASS and returns it with
+// the primary and secondary styles.
+func bilingualHeader(opts BilingualOptions) (doc *ass.Document, def, sec *ass.Style) {
+	tmpl := DefaultStyleTemplate()
+	if opts.Template != nil {
+		tmpl = *opts.Template
+	}
+	scale := opts.SecondaryScale
+	if scale <= 0 {
+		scale = DefaultSecondaryScale
+	}
+
+	// Fonts and spacing are set at the template's resolution, then scaled
+	// with it.
+	doc = ass.Parse(tmpl.Header())
+	styles := doc.Styles()
+	def = styles.Style("Default")
+	setFont(def, opts.PrimaryFont)
+
+	sec = &ass.Style{}
+	*sec = *def
+	sec.Name = "Secondary"
+	sec.Extra = maps.Clone(def.Extra)
+	sec.Fontsize = round2(def.Fontsize * scale)
+	setFont(sec, opts.SecondaryFont)
+	styles.Styles = append(styles.Styles, sec)
+
+	if !opts.Stack {
+		// The style nearer the screen edge keeps the template's margin.
+		top := def.Alignment >= 7
+		near, far := sec, def
+		if opts.SecondaryAbove != top {
+			near, far = def, sec
+		}
+		far.MarginV += int(math.Round(near.Fontsize * bilingualLineHeight))
+	}
+
+	scaled := StyleTemplate{RawHeader: doc.String()}.ScaledTo(opts.PlayRes)
+	doc = ass.Parse(scaled.Header())
+	styles = doc.Styles()
+	return doc, styles.Style("Default"), styles.Style("Secondary")
+}
+
+// setFont applies a font choice to a style.
+func setFont(st *ass.Style, font LanguageFont) {
+	if font.Fontname != "" {
+		st.Fontname = font.Fontname
+		st.Encoding = font.Encoding
+	}
+	if font.Fontsize > 0 {
+		st.Fontsize = font.Fontsize
+	}
+}
+
+// event converts one of the track's events to a Dialogue line in style.
+func (t BilingualTrack) event(ev subtitle.SubtitleEvent, style string) *ass.Event {
+	line := dialogueEvent(ev)
+	line.Style = style
+	line.Text = t.text(ev)
+	return line
+}
+
+// text returns an event's text as ASS dialogue text.
+func (t BilingualTrack) text(ev subtitle.SubtitleEvent) string {
+	if !t.SRT {
+		return ev.Text
+	}
+	text := subtitle.ConvertSRTTagsToASS(ev.Text)
+	text = strings.ReplaceAll(text, "\r", "")
+	return strings.ReplaceAll(text, "\n", `\N`)
+}
+
+// stackEvents implements BilingualOptions.Stack.
+func stackEvents(primary, secondary BilingualTrack, def, sec *ass.Style, above bool) []*ass.Event {
+	partners := pairByOverlap(primary.Events, secondary.Events)
+	paired := make([]bool, len(secondary.Events))
+
+	// The secondary run resets to the line's style, then switches font and
+	// size, so tags in the primary text do not carry over (and vice versa).
+	run := `{\r\fn` + sec.Fontname + `\fs` + ass.FormatNumber(sec.Fontsize) + `}`
+
+	var events []*ass.Event
+	for i, ev := range primary.Events {
+		line := primary.event(ev, def.Name)
+		if len(partners[i]) > 0 {
+			texts := make([]string, len(partners[i]))
+			for k, j := range partners[i] {
+				texts[k] = secondary.text(secondary.Events[j])
+				paired[j] = true
+			}
+			other := run + strings.Join(texts, `\N`)
+			if above {
+				line.Text = other + `{\r}\N` + line.Text
+			} else {
+				line.Text += `\N` + other
+			}
+		}
+		events = append(events, line)
+	}
+	for j, ev := range secondary.Events {
+		if !paired[j] {
+			events = append(events, secondary.event(ev, sec.Name))
+		}
+	}
+	return events
+}
+
+// pairByOverlap assigns each event of b to the event of a it overlaps
+// longest, the earliest on ties, and returns the indices of the events of b
+// assigned to each event of a, in start order. Events of b overlapping
+// nothing in a are left out.
+func pairByOverlap(a, b []subtitle.SubtitleEvent) [][]int {
+	partners := make([][]int, len(a))
+	order := make([]int, len(b))
+	for j := range order {
+		order[j] = j
+	}
+	sort.SliceStable(order, func(x, y int) bool { return b[order[x]].Start < b[order[y]].Start })
+
+	for _, j := range order {
+		best, bestOverlap := -1, uint64(0)
+		for i := range a {
+			start := max(a[i].Start, b[j].Start)
+			end := min(a[i].End, b[j].End)
+			if end <= start {
+				continue
+			}
+			if overlap := end - start; overlap > bestOverlap || (overlap == bestOverlap && a[i].Start < a[best].Start) {
+				best, bestOverlap = i, overlap
+			}
+		}
+		if best >= 0 {
+			partners[best] = append(partners[best], j)
+		}
+	}
+	return partners
+}
@@ -0,0 +1,115 @@
+package assout
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"mkv-sub-extractor/pkg/ass"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+func bilingualEvents(lines ...string) []subtitle.SubtitleEvent {
+	// Each line is "START-END text" in seconds.
+	var events []subtitle.SubtitleEvent
+	for i, l := range lines {
+		var start, end uint64
+		span, text, _ := strings.Cut(l, " ")
+		s, e, _ := strings.Cut(span, "-")
+		for _, c := range s {
+			start = start*10 + uint64(c-'0')
+		}
+		for _, c := range e {
+			end = end*10 + uint64(c-'0')
+		}
+		events = append(events, subtitle.SubtitleEvent{Start: start * 1e9, End: end * 1e9, Text: text, ReadOrder: i})
+	}
+	return events
+}
+
+func TestWriteBilingualASS_Styles(t *testing.T) {
+	primary := BilingualTrack{Events: bilingualEvents("1-3 你好<i>世界</i>"), SRT: true}
+	secondary := BilingualTrack{Events: bilingualEvents("1-3 Hello world"), SRT: true}
+	opts := BilingualOptions{
+		PrimaryFont:   LanguageFont{Fontname: "Noto Sans CJK SC", Encoding: EncodingGB2312},
+		SecondaryFont: LanguageFont{Fontname: "Noto Sans"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBilingualASS(&buf, primary, secondary, opts); err != nil {
+		t.Fatal(err)
+	}
+	doc := ass.Parse(buf.String())
+	def, sec := doc.Styles().Style("Default"), doc.Styles().Style("Secondary")
+	if def == nil || sec == nil {
+		t.Fatalf("missing styles:\n%s", buf.String())
+	}
+	if def.Fontname != "Noto Sans CJK SC" || def.Fontsize != 58 || def.Encoding != EncodingGB2312 {
+		t.Errorf("Default style = %+v", def)
+	}
+	// Secondary below: it keeps the template margin and the primary is lifted
+	// by one secondary line (58 * 0.7 = 40.6 -> 48.72 rounds to 49).
+	if sec.Fontname != "Noto Sans" || sec.Fontsize != 40.6 || sec.MarginV != 30 || def.MarginV != 79 {
+		t.Errorf("Secondary = %+v, Default MarginV = %d", sec, def.MarginV)
+	}
+
+	events := doc.Events().Events
+	if len(events) != 2 || events[0].Style != "Default" || events[0].Text != `你好{\i1}世界{\i0}` || events[1].Style != "Secondary" {
+		t.Errorf("events = %+v", events)
+	}
+
+	opts.SecondaryAbove = true
+	buf.Reset()
+	if err := WriteBilingualASS(&buf, primary, secondary, opts); err != nil {
+		t.Fatal(err)
+	}
+	doc = ass.Parse(buf.String())
+	if def, sec := doc.Styles().Style("Default"), doc.Styles().Style("Secondary"); def.MarginV != 30 || sec.MarginV != 100 {
+		t.Errorf("secondary above: MarginV = %d / %d, want 30 / 100", def.MarginV, sec.MarginV)
+	}
+}
+
+func TestWriteBilingualASS_Stack(t *testing.T) {
+	primary := BilingualTrack{Events: bilingualEvents("1-4 我们走吧", "5-7 等等", "10-12 再见")}
+	secondary := BilingualTrack{Events: bilingualEvents("1-2 Let's", "2-4 go.", "5-6 Wait", "8-9 (music)")}
+	opts := BilingualOptions{Stack: true, SecondaryScale: 0.5, PlayRes: Resolution{X: 960, Y: 540}}
+
+	var buf bytes.Buffer
+	if err := WriteBilingualASS(&buf, primary, secondary, opts); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"PlayResY: 540",
+		`Dialogue: 0,0:00:01.00,0:00:04.00,Default,,0,0,0,,我们走吧\N{\r\fnMicrosoft YaHei\fs14.5}Let's\Ngo.`,
+		`Dialogue: 0,0:00:05.00,0:00:07.00,Default,,0,0,0,,等等\N{\r\fnMicrosoft YaHei\fs14.5}Wait`,
+		`Dialogue: 0,0:00:08.00,0:00:09.00,Secondary,,0,0,0,,(music)`,
+		`Dialogue: 0,0:00:10.00,0:00:12.00,Default,,0,0,0,,再见`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if doc := ass.Parse(out); doc.Styles().Style("Default").MarginV != 15 {
+		t.Error("stacked output should keep the template margin")
+	}
+
+	opts.SecondaryAbove = true
+	buf.Reset()
+	if err := WriteBilingualASS(&buf, primary, secondary, opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{\r\fnMicrosoft YaHei\fs14.5}Wait{\r}\N等等`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output lacks %q:\n%s", want, buf.String())
+	}
+}
+
+func TestPairByOverlap(t *testing.T) {
+	a := bilingualEvents("0-4 A", "4-8 B")
+	b := bilingualEvents("3-6 x", "1-2 y", "9-10 z", "2-6 w")
+	got := pairByOverlap(a, b)
+	// x overlaps A by 1s and B by 2s; w overlaps both by 2s and goes to A.
+	if len(got) != 2 || len(got[0]) != 2 || got[0][0] != 1 || got[0][1] != 3 || len(got[1]) != 1 || got[1][0] != 0 {
+		t.Errorf("pairByOverlap() = %v, want [[1 3] [0]]", got)
+	}
+}
@@ -0,0 +1,136 @@
+package assout
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"mkv-sub-extractor/pkg/ass"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// JoinPart is one part of an ASS/SSA track split across several files: the
+// part's CodecPrivate header and its events, already placed on the joined
+// timeline.
+type JoinPart struct {
+	CodecPrivate []byte
+	Events       []subtitle.SubtitleEvent
+}
+
+// WriteJoinedASS writes the parts of a track as one ASS file. The first
+// part's header is kept and the headers of later parts are merged into it:
+//
+//   - a part at another PlayRes is first resampled to the first part's;
+//   - its styles are added, except those identical to a style of the same
+//     name already there, which are shared;
+//   - a style whose name is taken by a different style is renamed to
+//     "Name_N", N being the 1-based part number ("Name_N_2", "Name_N_3", ...
+//     if that is taken too), and the part's events and \r tags follow.
+//
+// Events are written in start order, parts in the order given for equal
+// starts. opts applies to the joined script as in
+// WriteASSPassthroughWithOptions.
+func WriteJoinedASS(w io.Writer, parts []JoinPart, opts ASSOptions) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("no parts to join")
+	}
+	doc := passthroughDocument(parts[0].CodecPrivate, parts[0].Events)
+	res := DocumentResolution(doc)
+	events := doc.EnsureEvents()
+
+	for i, part := range parts[1:] {
+		pdoc := passthroughDocument(part.CodecPrivate, part.Events)
+		ResampleDocument(pdoc, res, ResampleOptions{Aspect: opts.Aspect})
+
+		renames := mergeStyles(doc, pdoc.Styles(), i+2)
+		for _, ev := range pdoc.EnsureEvents().Events {
+			if name, ok := renames[ev.Style]; ok {
+				ev.Style = name
+			}
+			ev.Text = renameResetTags(ev.Text, renames)
+			events.Events = append(events.Events, ev)
+		}
+	}
+	sort.SliceStable(events.Events, func(i, j int) bool {
+		return events.Events[i].Start < events.Events[j].Start
+	})
+
+	ResampleDocument(doc, opts.PlayRes, ResampleOptions{Aspect: opts.Aspect})
+	return writeDocument(w, doc)
+}
+
+// mergeStyles adds the styles of part number n to doc, as described for
+// WriteJoinedASS, and returns the renamed styles' old names mapped to their
+// new ones.
+func mergeStyles(doc *ass.Document, from *ass.StyleSection, n int) map[string]string {
+	renames := make(map[string]string)
+	if from == nil || len(from.Styles) == 0 {
+		return renames
+	}
+	into := doc.Styles()
+	if into == nil {
+		// Keep [Events] last.
+		into = ass.NewStyleSection()
+		events := doc.EnsureEvents()
+		doc.RemoveSection(events)
+		doc.AddSection(into)
+		doc.AddSection(events)
+	}
+
+	taken := make(map[string]bool)
+	for _, st := range into.Styles {
+		taken[st.Name] = true
+	}
+	for _, st := range from.Styles {
+		taken[st.Name] = true
+	}
+
+	for _, st := range from.Styles {
+		have := into.Style(st.Name)
+		if have != nil && have.Equal(st) {
+			continue
+		}
+		if have != nil {
+			name := fmt.Sprintf("%s_%d", st.Name, n)
+			for k := 2; taken[name]; k++ {
+				name = fmt.Sprintf("%s_%d_%d", st.Name, n, k)
+			}
+			taken[name] = true
+			renames[st.Name] = name
+			st.Name = name
+		}
+		into.Styles = append(into.Styles, st)
+	}
+	return renames
+}
+
+// renameResetTags rewrites \r tags naming a style in renames. Text without
+// such tags is returned unchanged.
+func renameResetTags(text string, renames map[string]string) string {
+	if len(renames) == 0 {
+		return text
+	}
+	parsed := subtitle.ParseASSText(text)
+	changed := false
+	for _, node := range parsed {
+		block, ok := node.(subtitle.OverrideBlock)
+		if !ok {
+			continue
+		}
+		for i, item := range block.Items {
+			tag, ok := item.(subtitle.Tag)
+			if !ok || tag.Kind != subtitle.TagReset {
+				continue
+			}
+			if name, ok := renames[tag.Arg(0)]; ok {
+				tag.Args = []string{name}
+				block.Items[i] = tag
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return text
+	}
+	return parsed.String()
+}
@@ -0,0 +1,75 @@
+package assout
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+func TestWriteJoinedASS(t *testing.T) {
+	// Part 2 shares Default, redefines Sign and adds Song; part 3 is at
+	// twice the resolution with the same styles as part 1.
+	part2 := strings.Replace(simpleV4PlusHeader, "Style: Default,", "Style: Sign,Georgia,30,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,8,10,10,10,1\nStyle: Song,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,1,0,0,100,100,0,0,1,2,1,2,10,10,10,1\nStyle: Default,", 1)
+	part1 := strings.Replace(simpleV4PlusHeader, "Style: Default,", "Style: Sign,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,8,10,10,10,1\nStyle: Default,", 1)
+	part3 := strings.Replace(part1, "ScriptType: v4.00+", "ScriptType: v4.00+\nPlayResX: 768\nPlayResY: 576", 1)
+	part3 = strings.ReplaceAll(part3, "Arial,20,", "Arial,40,")
+	part3 = strings.ReplaceAll(part3, ",1,2,1,8,10,10,10,1", ",1,4,2,8,20,20,20,1")
+	part3 = strings.ReplaceAll(part3, ",1,2,1,2,10,10,10,1", ",1,4,2,2,20,20,20,1")
+
+	ev := func(start uint64, style, text string) subtitle.SubtitleEvent {
+		return subtitle.SubtitleEvent{Start: start, End: start + 1_000_000_000, Style: style, Text: text}
+	}
+	parts := []JoinPart{
+		{CodecPrivate: []byte(part1), Events: []subtitle.SubtitleEvent{ev(0, "Sign", "one")}},
+		{CodecPrivate: []byte(part2), Events: []subtitle.SubtitleEvent{
+			ev(10_000_000_000, "Sign", `two{\rSong}la{\rSign}x`),
+			ev(5_000_000_000, "Default", "early"),
+		}},
+		{CodecPrivate: []byte(part3), Events: []subtitle.SubtitleEvent{ev(20_000_000_000, "Sign", `{\pos(384,288)}three`)}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJoinedASS(&buf, parts, ASSOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"Style: Sign,Arial,20,",
+		"Style: Default,Arial,20,",
+		"Style: Sign_2,Georgia,30,",
+		"Style: Song,Arial,20,",
+		"Dialogue: 0,0:00:00.00,0:00:01.00,Sign,,0,0,0,,one",
+		"Dialogue: 0,0:00:05.00,0:00:06.00,Default,,0,0,0,,early",
+		`Dialogue: 0,0:00:10.00,0:00:11.00,Sign_2,,0,0,0,,two{\rSong}la{\rSign_2}x`,
+		`Dialogue: 0,0:00:20.00,0:00:21.00,Sign,,0,0,0,,{\pos(192,144)}three`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "Style: "); n != 4 {
+		t.Errorf("got %d styles, want 4 (identical styles shared):\n%s", n, out)
+	}
+	if strings.Index(out, "early") > strings.Index(out, "two") {
+		t.Error("events not in start order")
+	}
+}
+
+func TestMergeStyles_RenameTaken(t *testing.T) {
+	base := strings.Replace(simpleV4PlusHeader, "Style: Default,", "Style: Default_2,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,2,10,10,10,1\nStyle: Default,", 1)
+	other := strings.Replace(simpleV4PlusHeader, "Arial", "Verdana", 1)
+	parts := []JoinPart{
+		{CodecPrivate: []byte(base)},
+		{CodecPrivate: []byte(other), Events: makeEvents(0)},
+	}
+	var buf bytes.Buffer
+	if err := WriteJoinedASS(&buf, parts, ASSOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Style: Default_2_2,Verdana") || !strings.Contains(out, ",Default_2_2,,") {
+		t.Errorf("conflicting style not renamed to Default_2_2:\n%s", out)
+	}
+}
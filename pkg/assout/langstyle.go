@@ -0,0 +1,250 @@
+package assout
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// ASS style Encoding values (Windows GDI character sets) used by the built-in
+// language fonts. Renderers use them to pick the matching font charset.
+const (
+	EncodingDefault    = 1
+	EncodingShiftJIS   = 128
+	EncodingHangul     = 129
+	EncodingGB2312     = 134
+	EncodingBig5       = 136
+	EncodingGreek      = 161
+	EncodingTurkish    = 162
+	EncodingVietnamese = 163
+	EncodingHebrew     = 177
+	EncodingArabic     = 178
+	EncodingCyrillic   = 204
+	EncodingThai       = 222
+)
+
+// LanguageFont is the font choice for one subtitle language.
+type LanguageFont struct {
+	Fontname string  `json:"font"`
+	Fontsize float64 `json:"size,omitempty"` // 0 keeps the template's size
+	Encoding int     `json:"encoding"`
+}
+
+// LanguageFonts maps ISO 639 language codes to font choices. Keys may use any
+// form golang.org/x/text understands ("chi", "zho", "zh"); lookups normalise
+// both sides to the ISO 639-2/T code.
+type LanguageFonts map[string]LanguageFont
+
+// DefaultLanguageFonts returns the built-in language map. It uses the Noto
+// families, which are freely available on Windows, Linux, macOS and Android.
+func DefaultLanguageFonts() LanguageFonts {
+	return LanguageFonts{
+		"zho": {Fontname: "Noto Sans CJK SC", Encoding: EncodingGB2312},
+		"jpn": {Fontname: "Noto Sans CJK JP", Encoding: EncodingShiftJIS},
+		"kor": {Fontname: "Noto Sans CJK KR", Encoding: EncodingHangul},
+		"ara": {Fontname: "Noto Sans Arabic", Encoding: EncodingArabic},
+		"fas": {Fontname: "Noto Sans Arabic", Encoding: EncodingArabic},
+		"heb": {Fontname: "Noto Sans Hebrew", Encoding: EncodingHebrew},
+		"tha": {Fontname: "Noto Sans Thai", Encoding: EncodingThai},
+		"rus": {Fontname: "Noto Sans", Encoding: EncodingCyrillic},
+		"ukr": {Fontname: "Noto Sans", Encoding: EncodingCyrillic},
+		"ell": {Fontname: "Noto Sans", Encoding: EncodingGreek},
+		"tur": {Fontname: "Noto Sans", Encoding: EncodingTurkish},
+		"vie": {Fontname: "Noto Sans", Encoding: EncodingVietnamese},
+		"eng": {Fontname: "Noto Sans", Encoding: EncodingDefault},
+	}
+}
+
+// LoadLanguageFonts reads a JSON language map from path and merges it over
+// DefaultLanguageFonts:
+//
+//	{"jpn": {"font": "Source Han Sans JP", "size": 60, "encoding": 128}}
+func LoadLanguageFonts(path string) (LanguageFonts, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read language fonts: %w", err)
+	}
+
+	var overrides LanguageFonts
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parse language fonts: %w", err)
+	}
+
+	fonts := DefaultLanguageFonts()
+	for code, font := range overrides {
+		key := normalizeLanguage(code)
+		if key == "" {
+			return nil, fmt.Errorf("language fonts: unknown language code %q", code)
+		}
+		if font.Fontname == "" {
+			return nil, fmt.Errorf("language fonts: %q has no font name", code)
+		}
+		fonts[key] = font
+	}
+	return fonts, nil
+}
+
+// Lookup returns the font for lang, accepting any ISO 639 code form.
+func (m LanguageFonts) Lookup(lang string) (LanguageFont, bool) {
+	key := normalizeLanguage(lang)
+	if key == "" {
+		return LanguageFont{}, false
+	}
+	if font, ok := m[key]; ok {
+		return font, true
+	}
+	for code, font := range m {
+		if normalizeLanguage(code) == key {
+			return font, true
+		}
+	}
+	return LanguageFont{}, false
+}
+
+// normalizeLanguage converts an ISO 639 code to its ISO 639-2/T form
+// ("chi" -> "zho"). Bibliographic codes are canonicalised the same way
+// mkvinfo.ResolveLanguageName does. Returns "" for empty, "und" or
+// unparseable codes.
+func normalizeLanguage(code string) string {
+	if code == "" || code == "und" {
+		return ""
+	}
+	parsed, err := language.ParseBase(code)
+	if err != nil {
+		return ""
+	}
+	base, _ := language.Make(parsed.String()).Base()
+	return base.ISO3()
+}
+
+// scriptLanguages maps each script to the language whose font renders it when
+// it appears as the secondary script of a bilingual line.
+var scriptLanguages = map[subtitle.Script]string{
+	subtitle.ScriptLatin:    "eng",
+	subtitle.ScriptHan:      "zho",
+	subtitle.ScriptJapanese: "jpn",
+	subtitle.ScriptHangul:   "kor",
+	subtitle.ScriptArabic:   "ara",
+	subtitle.ScriptHebrew:   "heb",
+	subtitle.ScriptCyrillic: "rus",
+	subtitle.ScriptGreek:    "ell",
+	subtitle.ScriptThai:     "tha",
+}
+
+// languageScripts maps languages to the script they are written in, for
+// languages whose script is not Latin.
+var languageScripts = map[string]subtitle.Script{
+	"zho": subtitle.ScriptHan,
+	"yue": subtitle.ScriptHan,
+	"jpn": subtitle.ScriptJapanese,
+	"kor": subtitle.ScriptHangul,
+	"ara": subtitle.ScriptArabic,
+	"fas": subtitle.ScriptArabic,
+	"urd": subtitle.ScriptArabic,
+	"heb": subtitle.ScriptHebrew,
+	"yid": subtitle.ScriptHebrew,
+	"rus": subtitle.ScriptCyrillic,
+	"ukr": subtitle.ScriptCyrillic,
+	"bel": subtitle.ScriptCyrillic,
+	"bul": subtitle.ScriptCyrillic,
+	"srp": subtitle.ScriptCyrillic,
+	"mkd": subtitle.ScriptCyrillic,
+	"ell": subtitle.ScriptGreek,
+	"tha": subtitle.ScriptThai,
+}
+
+// languageScript returns the script lang is written in. Languages not listed
+// in languageScripts are assumed to use Latin; empty or "und" return
+// ScriptUnknown.
+func languageScript(lang string) subtitle.Script {
+	key := normalizeLanguage(lang)
+	if key == "" {
+		return subtitle.ScriptUnknown
+	}
+	if script, ok := languageScripts[key]; ok {
+		return script
+	}
+	return subtitle.ScriptLatin
+}
+
+// withLanguageFont returns a copy of the template using font. Raw templates
+// are returned unchanged, since their styles are the user's own.
+func (t StyleTemplate) withLanguageFont(font LanguageFont) StyleTemplate {
+	if t.RawHeader != "" {
+		return t
+	}
+	t.Fontname = font.Fontname
+	if font.Fontsize > 0 {
+		t.Fontsize = font.Fontsize
+	}
+	t.Encoding = font.Encoding
+	return t
+}
+
+// fontSwitcher adds \fn overrides to lines written in a script other than the
+// track's primary script, so bilingual lines render each half in a suitable font.
+type fontSwitcher struct {
+	fonts   LanguageFonts
+	primary subtitle.Script
+	base    string // the style's own font name
+}
+
+// newFontSwitcher prepares secondary-font handling for a track. The primary
+// script comes from lang, or from the events themselves when lang is unknown.
+func newFontSwitcher(fonts LanguageFonts, lang string, base string, events []subtitle.SubtitleEvent) *fontSwitcher {
+	primary := languageScript(lang)
+	if primary == subtitle.ScriptUnknown {
+		var all strings.Builder
+		for _, ev := range events {
+			all.WriteString(ev.Text)
+			all.WriteByte('\n')
+		}
+		primary = subtitle.DetectScript(all.String())
+	}
+	return &fontSwitcher{fonts: fonts, primary: primary, base: base}
+}
+
+// apply rewrites SRT text line by line. A line whose dominant script differs
+// from the primary one is prefixed with {\fnFont}; the next primary line resets
+// the font with {\fn}. Lines without letters keep the current font, and a
+// leading coordinate line (see subtitle.ParseSRTCoordinates) is left for
+// subtitle.ConvertSRTTagsToASS to turn into position tags.
+func (s *fontSwitcher) apply(text string) string {
+	lines := strings.Split(text, "\n")
+	switched := false
+
+	for i, line := range lines {
+		if i == 0 {
+			if _, ok := subtitle.ParseSRTCoordinates(line); ok {
+				continue
+			}
+		}
+		script := subtitle.DetectScript(line)
+		if script == subtitle.ScriptUnknown {
+			continue
+		}
+
+		font := ""
+		if script != s.primary {
+			if f, ok := s.fonts.Lookup(scriptLanguages[script]); ok && f.Fontname != s.base {
+				font = f.Fontname
+			}
+		}
+
+		switch {
+		case font != "":
+			lines[i] = `{\fn` + font + `}` + line
+			switched = true
+		case switched:
+			lines[i] = `{\fn}` + line
+			switched = false
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
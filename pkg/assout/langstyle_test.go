@@ -0,0 +1,165 @@
+package assout
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+func TestLanguageFonts_LookupNormalizesCodes(t *testing.T) {
+	fonts := DefaultLanguageFonts()
+
+	for _, code := range []string{"chi", "zho", "zh"} {
+		font, ok := fonts.Lookup(code)
+		if !ok {
+			t.Fatalf("Lookup(%q) found nothing", code)
+		}
+		if font.Encoding != EncodingGB2312 {
+			t.Errorf("Lookup(%q).Encoding = %d, want %d", code, font.Encoding, EncodingGB2312)
+		}
+	}
+
+	if font, ok := fonts.Lookup("gre"); !ok || font.Encoding != EncodingGreek {
+		t.Errorf("Lookup(gre) = %+v, %v; want Greek encoding", font, ok)
+	}
+	if _, ok := fonts.Lookup("und"); ok {
+		t.Error("Lookup(und) should find nothing")
+	}
+	if _, ok := fonts.Lookup(""); ok {
+		t.Error("Lookup(\"\") should find nothing")
+	}
+}
+
+func TestLoadLanguageFonts_MergesOverDefaults(t *testing.T) {
+	path := writeTempFile(t, "fonts.json", `{"jpn": {"font": "Source Han Sans JP", "size": 60, "encoding": 128}, "fre": {"font": "Marianne", "encoding": 1}}`)
+
+	fonts, err := LoadLanguageFonts(path)
+	if err != nil {
+		t.Fatalf("LoadLanguageFonts error: %v", err)
+	}
+	if font, _ := fonts.Lookup("jpn"); font.Fontname != "Source Han Sans JP" || font.Fontsize != 60 {
+		t.Errorf("jpn override not applied: %+v", font)
+	}
+	if font, _ := fonts.Lookup("fra"); font.Fontname != "Marianne" {
+		t.Errorf("fre override not normalised: %+v", font)
+	}
+	if _, ok := fonts.Lookup("kor"); !ok {
+		t.Error("defaults should be kept for languages not overridden")
+	}
+}
+
+func TestLoadLanguageFonts_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"bad json":   `{"jpn": }`,
+		"bad code":   `{"xx-not-a-language": {"font": "Arial"}}`,
+		"empty font": `{"jpn": {"size": 40}}`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeTempFile(t, "fonts.json", content)
+			if _, err := LoadLanguageFonts(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestWriteSRTAsASSWithOptions_LanguageFont(t *testing.T) {
+	events := []subtitle.SubtitleEvent{
+		{Start: 0, End: 1_000_000_000, Text: "こんにちは"},
+	}
+
+	var buf bytes.Buffer
+	opts := SRTOptions{Fonts: DefaultLanguageFonts(), Language: "jpn"}
+	if err := WriteSRTAsASSWithOptions(&buf, events, opts); err != nil {
+		t.Fatalf("WriteSRTAsASSWithOptions error: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Style: Default,Noto Sans CJK JP,58,") {
+		t.Errorf("style should use the Japanese font:\n%s", output)
+	}
+	if !strings.Contains(output, ",20,20,30,128\r\n") {
+		t.Errorf("style Encoding should be 128 (Shift-JIS):\n%s", output)
+	}
+}
+
+func TestWriteSRTAsASSWithOptions_SecondaryFontForBilingualLines(t *testing.T) {
+	events := []subtitle.SubtitleEvent{
+		{Start: 0, End: 1_000_000_000, Text: "你好，世界\n<i>Hello, world</i>"},
+		{Start: 2_000_000_000, End: 3_000_000_000, Text: "Good morning\n早上好"},
+	}
+
+	var buf bytes.Buffer
+	opts := SRTOptions{Fonts: DefaultLanguageFonts(), Language: "chi"}
+	if err := WriteSRTAsASSWithOptions(&buf, events, opts); err != nil {
+		t.Fatalf("WriteSRTAsASSWithOptions error: %v", err)
+	}
+
+	output := buf.String()
+	want1 := `,,你好，世界\N{\fnNoto Sans}{\i1}Hello, world{\i0}` + "\r\n"
+	if !strings.Contains(output, want1) {
+		t.Errorf("first line missing secondary font override:\nwant suffix %q\ngot:\n%s", want1, output)
+	}
+	want2 := `,,{\fnNoto Sans}Good morning\N{\fn}早上好` + "\r\n"
+	if !strings.Contains(output, want2) {
+		t.Errorf("second line should switch back to the style font:\nwant suffix %q\ngot:\n%s", want2, output)
+	}
+}
+
+func TestWriteSRTAsASSWithOptions_CoordinateLineKeepsFont(t *testing.T) {
+	events := []subtitle.SubtitleEvent{
+		{Start: 0, End: 1_000_000_000, Text: "X1:100 X2:300 Y1:50 Y2:90\n你好\nHello"},
+	}
+
+	var buf bytes.Buffer
+	opts := SRTOptions{Fonts: DefaultLanguageFonts(), Language: "chi"}
+	if err := WriteSRTAsASSWithOptions(&buf, events, opts); err != nil {
+		t.Fatalf("WriteSRTAsASSWithOptions error: %v", err)
+	}
+
+	want := `,,{\an8\pos(200,50)}你好\N{\fnNoto Sans}Hello` + "\r\n"
+	if output := buf.String(); !strings.Contains(output, want) {
+		t.Errorf("coordinate line should become position tags:\nwant suffix %q\ngot:\n%s", want, output)
+	}
+}
+
+func TestWriteSRTAsASSWithOptions_UnknownLanguageDetectsPrimaryScript(t *testing.T) {
+	events := []subtitle.SubtitleEvent{
+		{Start: 0, End: 1_000_000_000, Text: "안녕하세요\nHello"},
+		{Start: 2_000_000_000, End: 3_000_000_000, Text: "감사합니다"},
+	}
+
+	var buf bytes.Buffer
+	opts := SRTOptions{Fonts: DefaultLanguageFonts(), Language: "und"}
+	if err := WriteSRTAsASSWithOptions(&buf, events, opts); err != nil {
+		t.Fatalf("WriteSRTAsASSWithOptions error: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Style: Default,Microsoft YaHei,") {
+		t.Errorf("unknown language should keep the template font:\n%s", output)
+	}
+	if !strings.Contains(output, `,,안녕하세요\N{\fnNoto Sans}Hello`) {
+		t.Errorf("Latin line should get a secondary font when Hangul is primary:\n%s", output)
+	}
+}
+
+func TestWriteSRTAsASSWithOptions_NilFontsUnchanged(t *testing.T) {
+	events := []subtitle.SubtitleEvent{
+		{Start: 0, End: 1_000_000_000, Text: "你好\nHello"},
+	}
+
+	var withOpts, plain bytes.Buffer
+	if err := WriteSRTAsASSWithOptions(&withOpts, events, SRTOptions{Language: "chi"}); err != nil {
+		t.Fatalf("WriteSRTAsASSWithOptions error: %v", err)
+	}
+	if err := WriteSRTAsASS(&plain, events); err != nil {
+		t.Fatalf("WriteSRTAsASS error: %v", err)
+	}
+	if withOpts.String() != plain.String() {
+		t.Error("nil Fonts should produce the same output as WriteSRTAsASS")
+	}
+}
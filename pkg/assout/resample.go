@@ -0,0 +1,470 @@
+package assout
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"mkv-sub-extractor/pkg/ass"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// Resolution is an ASS script resolution (PlayResX x PlayResY). The zero
+// value means "unknown / keep as is".
+type Resolution struct {
+	X, Y int
+}
+
+// IsZero reports whether r is unset.
+func (r Resolution) IsZero() bool {
+	return r.X <= 0 || r.Y <= 0
+}
+
+// String renders r as "WxH".
+func (r Resolution) String() string {
+	return strconv.Itoa(r.X) + "x" + strconv.Itoa(r.Y)
+}
+
+// ParseResolution parses a "WxH" resolution such as "1920x1080".
+func ParseResolution(s string) (Resolution, error) {
+	xs, ys, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if !ok {
+		return Resolution{}, fmt.Errorf("invalid resolution %q (want WIDTHxHEIGHT, e.g. 1920x1080)", s)
+	}
+	x, errX := strconv.Atoi(xs)
+	y, errY := strconv.Atoi(ys)
+	if errX != nil || errY != nil || x <= 0 || y <= 0 {
+		return Resolution{}, fmt.Errorf("invalid resolution %q (want WIDTHxHEIGHT, e.g. 1920x1080)", s)
+	}
+	return Resolution{X: x, Y: y}, nil
+}
+
+// VideoResolution returns the script resolution that matches a video track.
+// PlayResY is the frame height; PlayResX follows the display aspect ratio, so
+// anamorphic sources (a 720x480 DVD shown at 16:9) get square script pixels
+// (853x480) and text is not stretched. Zero display dimensions fall back to
+// the pixel size. Returns the zero Resolution when the pixel size is unknown.
+func VideoResolution(pixelWidth, pixelHeight, displayWidth, displayHeight int) Resolution {
+	if pixelWidth <= 0 || pixelHeight <= 0 {
+		return Resolution{}
+	}
+	if displayWidth <= 0 || displayHeight <= 0 {
+		return Resolution{X: pixelWidth, Y: pixelHeight}
+	}
+	x := int(math.Round(float64(pixelHeight) * float64(displayWidth) / float64(displayHeight)))
+	return Resolution{X: x, Y: pixelHeight}
+}
+
+// ScriptResolution reads PlayResX and PlayResY from an ASS header. Missing
+// values are filled in the way libass and VSFilter do: a lone PlayResY of 1024
+// implies 1280 (5:4), any other lone value implies 4:3, and a header with
+// neither uses 384x288.
+func ScriptResolution(header string) Resolution {
+	return DocumentResolution(ass.Parse(header))
+}
+
+// DocumentResolution is ScriptResolution for a parsed document.
+func DocumentResolution(doc *ass.Document) Resolution {
+	var r Resolution
+	if info := doc.ScriptInfo(); info != nil {
+		for _, l := range info.Lines {
+			n, err := strconv.Atoi(l.Value)
+			if err != nil || n <= 0 {
+				continue
+			}
+			switch l.Key {
+			case "PlayResX":
+				r.X = n
+			case "PlayResY":
+				r.Y = n
+			}
+		}
+	}
+
+	switch {
+	case r.X <= 0 && r.Y <= 0:
+		return Resolution{X: 384, Y: 288}
+	case r.X <= 0 && r.Y == 1024:
+		r.X = 1280
+	case r.X <= 0:
+		r.X = r.Y * 4 / 3
+	case r.Y <= 0 && r.X == 1280:
+		r.Y = 1024
+	case r.Y <= 0:
+		r.Y = r.X * 3 / 4
+	}
+	return r
+}
+
+// AspectMode selects how resampling handles a change of aspect ratio. The
+// modes mirror Aegisub's "Resample Resolution" dialog.
+type AspectMode int
+
+const (
+	// AspectStretch scales each axis independently. Text is widened or
+	// narrowed with ScaleX/\fscx so it follows the stretched picture.
+	AspectStretch AspectMode = iota
+
+	// AspectAddBorders keeps the script's aspect ratio and centres it in the
+	// new frame, as if black borders had been added (4:3 into 16:9 pillarbox).
+	AspectAddBorders
+
+	// AspectRemoveBorders keeps the aspect ratio and fills the new frame,
+	// cropping the overflowing edges (16:9 letterboxed in 4:3 cropped to 16:9).
+	AspectRemoveBorders
+)
+
+// aspectModeNames maps CLI names to aspect modes.
+var aspectModeNames = map[string]AspectMode{
+	"stretch":        AspectStretch,
+	"add-borders":    AspectAddBorders,
+	"remove-borders": AspectRemoveBorders,
+}
+
+// ParseAspectMode parses "stretch", "add-borders" or "remove-borders".
+func ParseAspectMode(s string) (AspectMode, error) {
+	mode, ok := aspectModeNames[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("unknown aspect mode %q (available: stretch, add-borders, remove-borders)", s)
+	}
+	return mode, nil
+}
+
+// ResampleOptions controls Resample.
+type ResampleOptions struct {
+	Aspect AspectMode // what to do when the aspect ratio changes
+}
+
+// aspectTolerance is the relative aspect ratio difference below which the
+// ratio is considered unchanged, as in Aegisub.
+const aspectTolerance = 0.01
+
+// resampler maps coordinates and sizes from one script resolution to another.
+// A source coordinate x becomes (x + left) * rx; left and top are the border
+// offsets introduced by the aspect mode.
+type resampler struct {
+	rx, ry    float64
+	ar        float64 // horizontal stretch applied to ScaleX and \fscx
+	left, top float64
+}
+
+func newResampler(from, to Resolution, mode AspectMode) resampler {
+	r := resampler{ar: 1}
+	srcX, srcY := float64(from.X), float64(from.Y)
+	oldAR, newAR := srcX/srcY, float64(to.X)/float64(to.Y)
+
+	if math.Abs(oldAR-newAR)/newAR > aspectTolerance {
+		widen := newAR > oldAR
+		switch mode {
+		case AspectStretch:
+			r.ar = newAR / oldAR
+		case AspectAddBorders, AspectRemoveBorders:
+			// Adding borders to a narrower script pads it left and right;
+			// removing them from a narrower script crops top and bottom.
+			if widen == (mode == AspectAddBorders) {
+				r.left = (srcY*newAR - srcX) / 2
+			} else {
+				r.top = (srcX/newAR - srcY) / 2
+			}
+		}
+	}
+
+	r.rx = float64(to.X) / (srcX + 2*r.left)
+	r.ry = float64(to.Y) / (srcY + 2*r.top)
+	return r
+}
+
+func (r resampler) x(v float64) float64 { return (v + r.left) * r.rx }
+func (r resampler) y(v float64) float64 { return (v + r.top) * r.ry }
+
+// Resample rescales a complete ASS script, or just its header, to the
+// resolution `to`, the way Aegisub's "Resample Resolution" does (see
+// ResampleDocument). The line endings of the input are kept. A script already
+// at `to` is returned unchanged.
+func Resample(script string, to Resolution, opts ResampleOptions) string {
+	doc := ass.Parse(script)
+	if !ResampleDocument(doc, to, opts) {
+		return script
+	}
+	return doc.String()
+}
+
+// ResampleDocument rescales doc in place to the resolution `to`:
+//
+//   - PlayResX/PlayResY are rewritten (added if missing);
+//   - style Fontsize, Outline, Shadow, Spacing and margins are scaled, and
+//     ScaleX absorbs any horizontal stretch;
+//   - Dialogue and Comment margins are scaled; zero margins stay zero;
+//   - override tags are rewritten: \pos, \move, \org and \clip coordinates,
+//     \fs, \fsp, \bord, \shad (and their x/y variants), \fscx, and the
+//     coordinates of vector clips and {\p} drawings, including tags inside \t.
+//
+// It reports whether anything changed: a zero `to`, or a document already at
+// `to`, is left alone.
+func ResampleDocument(doc *ass.Document, to Resolution, opts ResampleOptions) bool {
+	from := DocumentResolution(doc)
+	if to.IsZero() || from == to {
+		return false
+	}
+	resampleDocument(doc, to, newResampler(from, to, opts.Aspect))
+	return true
+}
+
+// resampleDocument implements ResampleDocument with a prepared resampler.
+func resampleDocument(doc *ass.Document, to Resolution, r resampler) {
+	if info := doc.ScriptInfo(); info != nil {
+		info.Set("PlayResX", strconv.Itoa(to.X))
+		info.Set("PlayResY", strconv.Itoa(to.Y))
+	}
+
+	if styles := doc.Styles(); styles != nil {
+		for _, st := range styles.Styles {
+			r.style(st)
+		}
+	}
+
+	if events := doc.Events(); events != nil {
+		for _, ev := range events.Events {
+			if ev.Type != ass.Dialogue && ev.Type != ass.Comment {
+				continue
+			}
+			ev.MarginL = scaleMargin(ev.MarginL, r.x)
+			ev.MarginR = scaleMargin(ev.MarginR, r.x)
+			ev.MarginV = scaleMargin(ev.MarginV, r.y)
+			ev.Text = r.text(ev.Text)
+		}
+	}
+}
+
+// ResampleFile reads the ASS script at src, resamples it with ResampleDocument
+// and writes the result to dst with CRLF line endings. A UTF-8 BOM is dropped
+// and SSA headers are converted to ASS first.
+func ResampleFile(src, dst string, to Resolution, opts ResampleOptions) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("read script: %w", err)
+	}
+
+	doc := ass.Parse(string(data))
+	if doc.ScriptInfo() == nil {
+		return fmt.Errorf("%s is not an ASS/SSA script: no [Script Info] section", src)
+	}
+	ConvertSSAToASS(doc)
+	ResampleDocument(doc, to, opts)
+
+	doc.BOM, doc.Newline = false, "\r\n"
+	if err := os.WriteFile(dst, []byte(doc.String()), 0o644); err != nil {
+		return fmt.Errorf("write script: %w", err)
+	}
+	return nil
+}
+
+// style scales the metrics of one style. Margins pushed below zero by a
+// cropping aspect mode are clamped to zero.
+func (r resampler) style(st *ass.Style) {
+	st.Fontsize = round2(st.Fontsize * r.ry)
+	st.Outline = round2(st.Outline * r.ry)
+	st.Shadow = round2(st.Shadow * r.ry)
+	st.Spacing = round2(st.Spacing * r.rx)
+	st.ScaleX = round2(st.ScaleX * r.ar)
+	st.MarginL = max(0, int(math.Round(r.x(float64(st.MarginL)))))
+	st.MarginR = max(0, int(math.Round(r.x(float64(st.MarginR)))))
+	st.MarginV = max(0, int(math.Round(r.y(float64(st.MarginV)))))
+}
+
+// scaleMargin scales an event margin. Zero margins (meaning "use the style's
+// margin") are returned unchanged. Margins pushed below zero by a cropping
+// aspect mode are clamped to 1 so they keep overriding the style.
+func scaleMargin(margin int, axis func(float64) float64) int {
+	if margin == 0 {
+		return 0
+	}
+	return max(1, int(math.Round(axis(float64(margin)))))
+}
+
+// text rewrites the override tags and drawings of a Dialogue text.
+func (r resampler) text(text string) string {
+	parsed := subtitle.ParseASSText(text)
+	for i, n := range parsed {
+		switch n := n.(type) {
+		case subtitle.Drawing:
+			parsed[i] = subtitle.Drawing{Commands: r.drawing(n.Commands, 0, 0, 1)}
+		case subtitle.OverrideBlock:
+			parsed[i] = subtitle.OverrideBlock{Items: r.items(n.Items)}
+		}
+	}
+	return parsed.String()
+}
+
+// items rewrites the tags of one override block (or of a \t animation).
+func (r resampler) items(items []subtitle.BlockItem) []subtitle.BlockItem {
+	out := make([]subtitle.BlockItem, len(items))
+	for i, item := range items {
+		if tag, ok := item.(subtitle.Tag); ok {
+			item = r.tag(tag)
+		}
+		out[i] = item
+	}
+	return out
+}
+
+// tag rewrites a single override tag.
+func (r resampler) tag(t subtitle.Tag) subtitle.Tag {
+	t.Args = append([]string(nil), t.Args...)
+
+	ry := func(v float64) float64 { return v * r.ry }
+	rx := func(v float64) float64 { return v * r.rx }
+	scale := func(f func(float64) float64) subtitle.Tag {
+		if len(t.Args) == 1 {
+			t.Args[0] = scaleScalar(t.Args[0], f)
+		}
+		return t
+	}
+
+	switch t.Kind {
+	case subtitle.TagFontSize:
+		// \fs+N / \fs-N are relative steps and are left alone.
+		if arg := t.Arg(0); !strings.HasPrefix(arg, "+") && !strings.HasPrefix(arg, "-") {
+			return scale(ry)
+		}
+	case subtitle.TagBorder, subtitle.TagShadow, subtitle.TagBorderY, subtitle.TagShadowY, subtitle.TagBaselineOffset:
+		return scale(ry)
+	case subtitle.TagSpacing, subtitle.TagBorderX, subtitle.TagShadowX:
+		return scale(rx)
+	case subtitle.TagFontScaleX:
+		return scale(func(v float64) float64 { return v * r.ar })
+	case subtitle.TagPos, subtitle.TagOrigin:
+		return r.coords(t, 2)
+	case subtitle.TagMove:
+		return r.coords(t, 4)
+	case subtitle.TagClip, subtitle.TagInverseClip:
+		return r.clip(t)
+	case subtitle.TagTransform:
+		t.Nested = r.items(t.Nested)
+	}
+	return t
+}
+
+// coords scales the first n arguments of a parenthesised tag as alternating
+// x and y coordinates. Later arguments (\move times) are kept.
+func (r resampler) coords(t subtitle.Tag, n int) subtitle.Tag {
+	if !t.Paren {
+		return t
+	}
+	args := append([]string(nil), t.Args...)
+	for i := 0; i < n && i < len(args); i++ {
+		v, err := strconv.ParseFloat(strings.TrimSpace(args[i]), 64)
+		if err != nil {
+			return t
+		}
+		if i%2 == 0 {
+			args[i] = formatScaled(r.x(v))
+		} else {
+			args[i] = formatScaled(r.y(v))
+		}
+	}
+	t.Args = args
+	return t
+}
+
+// clip rewrites rectangular clips "(x1,y1,x2,y2)" and vector clips
+// "([scale,]drawing)".
+func (r resampler) clip(t subtitle.Tag) subtitle.Tag {
+	switch {
+	case !t.Paren:
+	case len(t.Args) == 4:
+		return r.coords(t, 4)
+	case len(t.Args) == 2:
+		// The leading integer is the drawing scale.
+		if scale, err := strconv.Atoi(strings.TrimSpace(t.Args[0])); err == nil {
+			t.Args[1] = r.drawing(t.Args[1], r.left, r.top, scale)
+		}
+	case len(t.Args) == 1:
+		t.Args[0] = r.drawing(t.Args[0], r.left, r.top, 1)
+	}
+	return t
+}
+
+// drawing scales the coordinates of ASS drawing commands ("m 0 0 l 100 0 ...").
+// Coordinates are in units of 2^(scale-1) pixels; offsetX/offsetY are the
+// border offsets in script pixels (zero for {\p} drawings, which are relative
+// to the line's position).
+func (r resampler) drawing(commands string, offsetX, offsetY float64, scale int) string {
+	unit := math.Pow(2, float64(scale-1))
+	fields := strings.Fields(commands)
+	axis := 0
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			axis = 0 // a command letter restarts the x/y pairing
+			continue
+		}
+		if axis == 0 {
+			fields[i] = formatScaled((v + offsetX*unit) * r.rx)
+		} else {
+			fields[i] = formatScaled((v + offsetY*unit) * r.ry)
+		}
+		axis = 1 - axis
+	}
+	return strings.Join(fields, " ")
+}
+
+// scaleScalar scales a single numeric tag argument, leaving it alone when it
+// is not a number.
+func scaleScalar(arg string, f func(float64) float64) string {
+	v, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+	if err != nil {
+		return arg
+	}
+	return formatScaled(f(v))
+}
+
+// formatScaled formats a resampled value with at most two decimals.
+func formatScaled(v float64) string {
+	return ass.FormatNumber(round2(v))
+}
+
+// round2 rounds v to two decimals.
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ScaledTo returns a copy of the template rescaled to the script resolution r,
+// keeping the same relative look: font size, outline, shadow and vertical
+// margin follow the height ratio, horizontal margins the width ratio. Raw
+// templates are resampled like Resample, without ScaleX stretch. A zero r
+// returns t unchanged.
+func (t StyleTemplate) ScaledTo(r Resolution) StyleTemplate {
+	if r.IsZero() {
+		return t
+	}
+	if t.RawHeader != "" {
+		// SRT lines carry no positioning, so text keeps its proportions
+		// (no ScaleX stretch) whatever the new aspect ratio.
+		doc := ass.Parse(t.RawHeader)
+		if from := DocumentResolution(doc); from != r {
+			rs := newResampler(from, r, AspectStretch)
+			rs.ar = 1
+			resampleDocument(doc, r, rs)
+			t.RawHeader = doc.String()
+		}
+		return t
+	}
+	if t.PlayResX == r.X && t.PlayResY == r.Y {
+		return t
+	}
+
+	rx := float64(r.X) / float64(t.PlayResX)
+	ry := float64(r.Y) / float64(t.PlayResY)
+
+	t.PlayResX, t.PlayResY = r.X, r.Y
+	t.Fontsize = round2(t.Fontsize * ry)
+	t.Outline = round2(t.Outline * ry)
+	t.Shadow = round2(t.Shadow * ry)
+	t.MarginL = int(math.Round(float64(t.MarginL) * rx))
+	t.MarginR = int(math.Round(float64(t.MarginR) * rx))
+	t.MarginV = int(math.Round(float64(t.MarginV) * ry))
+	return t
+}
@@ -0,0 +1,252 @@
+package assout
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+func TestVideoResolution(t *testing.T) {
+	tests := []struct {
+		name           string
+		pw, ph, dw, dh int
+		want           Resolution
+	}{
+		{"1080p", 1920, 1080, 1920, 1080, Resolution{1920, 1080}},
+		{"720p", 1280, 720, 1280, 720, Resolution{1280, 720}},
+		{"anamorphic DVD", 720, 480, 853, 480, Resolution{853, 480}},
+		{"display as ratio", 720, 576, 16, 9, Resolution{1024, 576}},
+		{"no display size", 3840, 2160, 0, 0, Resolution{3840, 2160}},
+		{"no video", 0, 0, 0, 0, Resolution{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := VideoResolution(tt.pw, tt.ph, tt.dw, tt.dh)
+			if got != tt.want {
+				t.Errorf("VideoResolution() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptResolution(t *testing.T) {
+	tests := map[string]Resolution{
+		"PlayResX: 1280\nPlayResY: 720\n": {1280, 720},
+		"PlayResY: 480\n":                 {640, 480},
+		"PlayResY: 1024\n":                {1280, 1024},
+		"PlayResX: 1280\n":                {1280, 1024},
+		"PlayResX: 640\n":                 {640, 480},
+		"ScriptType: v4.00+\n":            {384, 288},
+	}
+	for header, want := range tests {
+		if got := ScriptResolution("[Script Info]\n" + header); got != want {
+			t.Errorf("ScriptResolution(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestStyleTemplateScaledTo(t *testing.T) {
+	got := DefaultStyleTemplate().ScaledTo(Resolution{1280, 720})
+
+	if got.PlayResX != 1280 || got.PlayResY != 720 {
+		t.Errorf("PlayRes = %dx%d, want 1280x720", got.PlayResX, got.PlayResY)
+	}
+	if got.Fontsize != 38.67 {
+		t.Errorf("Fontsize = %v, want 38.67", got.Fontsize)
+	}
+	if got.MarginL != 13 || got.MarginV != 20 {
+		t.Errorf("margins = L%d V%d, want L13 V20", got.MarginL, got.MarginV)
+	}
+
+	same := DefaultStyleTemplate().ScaledTo(Resolution{1920, 1080})
+	if same.Header() != legacyASSHeader {
+		t.Error("scaling to the template's own resolution should not change the header")
+	}
+}
+
+func TestResample_Header(t *testing.T) {
+	header := "[Script Info]\r\nScriptType: v4.00+\r\nPlayResX: 640\r\nPlayResY: 480\r\n\r\n" +
+		"[V4+ Styles]\r\n" +
+		"Format: Name, Fontname, Fontsize, Spacing, Outline, Shadow, MarginL, MarginR, MarginV\r\n" +
+		"Style: Default,Arial,24,1,2,1,10,10,20\r\n"
+
+	got := Resample(header, Resolution{1920, 1080}, ResampleOptions{})
+
+	for _, want := range []string{
+		"PlayResX: 1920\r\n",
+		"PlayResY: 1080\r\n",
+		"Style: Default,Arial,54,3,4.5,2.25,30,30,45\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("resampled header missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestResample_AddsMissingPlayRes(t *testing.T) {
+	header := "[Script Info]\nScriptType: v4.00+\n\n[V4+ Styles]\nFormat: Name, Fontsize\nStyle: Default,18\n"
+
+	got := Resample(header, Resolution{768, 576}, ResampleOptions{})
+
+	if !strings.Contains(got, "PlayResX: 768\nPlayResY: 576\n\n[V4+ Styles]") {
+		t.Errorf("PlayRes not added to [Script Info]:\n%s", got)
+	}
+	if !strings.Contains(got, "Style: Default,36\n") {
+		t.Errorf("style not scaled from the implied 384x288:\n%s", got)
+	}
+}
+
+func TestWriteASSPassthroughWithOptions_Resample(t *testing.T) {
+	header := "[Script Info]\nPlayResX: 1280\nPlayResY: 720\n\n[V4+ Styles]\n" +
+		"Format: Name, Fontname, Fontsize, MarginL, MarginR, MarginV\nStyle: Default,Arial,40,20,20,30\n"
+	events := []subtitle.SubtitleEvent{
+		{Start: 0, End: 1_000_000_000, Style: "Default", MarginL: "0", MarginR: "40", MarginV: "60", Text: `{\pos(640,360)}Hi`},
+	}
+
+	var buf bytes.Buffer
+	opts := ASSOptions{PlayRes: Resolution{1920, 1080}}
+	if err := WriteASSPassthroughWithOptions(&buf, []byte(header), "S_TEXT/ASS", events, opts); err != nil {
+		t.Fatalf("WriteASSPassthroughWithOptions error: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Style: Default,Arial,60,30,30,45\r\n") {
+		t.Errorf("style not resampled:\n%s", output)
+	}
+	if !strings.Contains(output, `,Default,,0,60,90,,{\pos(960,540)}Hi`+"\r\n") {
+		t.Errorf("event margins not resampled:\n%s", output)
+	}
+}
+
+func TestWriteSRTAsASSWithOptions_PlayRes(t *testing.T) {
+	events := []subtitle.SubtitleEvent{{Start: 0, End: 1_000_000_000, Text: "Hello"}}
+
+	var buf bytes.Buffer
+	opts := SRTOptions{PlayRes: Resolution{3840, 2160}}
+	if err := WriteSRTAsASSWithOptions(&buf, events, opts); err != nil {
+		t.Fatalf("WriteSRTAsASSWithOptions error: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "PlayResX: 3840\r\nPlayResY: 2160\r\n") {
+		t.Errorf("PlayRes not set from video:\n%s", output)
+	}
+	if !strings.Contains(output, "Style: Default,Microsoft YaHei,116,") {
+		t.Errorf("font size not scaled:\n%s", output)
+	}
+}
+
+func TestParseResolution(t *testing.T) {
+	got, err := ParseResolution("1280X720")
+	if err != nil || got != (Resolution{1280, 720}) {
+		t.Errorf("ParseResolution(1280X720) = %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "1280", "1280x", "0x720", "axb"} {
+		if _, err := ParseResolution(bad); err == nil {
+			t.Errorf("ParseResolution(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseAspectMode(t *testing.T) {
+	if mode, err := ParseAspectMode("Add-Borders"); err != nil || mode != AspectAddBorders {
+		t.Errorf("ParseAspectMode(Add-Borders) = %v, %v", mode, err)
+	}
+	if _, err := ParseAspectMode("letterbox"); err == nil {
+		t.Error("expected error for unknown aspect mode")
+	}
+}
+
+// resampleScript is a minimal 640x480 script used by the tag tests.
+const resampleScript = "[Script Info]\nPlayResX: 640\nPlayResY: 480\n\n" +
+	"[V4+ Styles]\nFormat: Name, Fontsize, ScaleX, MarginL, MarginR, MarginV\nStyle: Default,20,100,10,10,10\n\n" +
+	"[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n"
+
+func resampleDialogue(t *testing.T, text string, to Resolution, opts ResampleOptions) string {
+	t.Helper()
+	script := resampleScript + "Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,," + text + "\n"
+	out := Resample(script, to, opts)
+	idx := strings.LastIndex(out, ",,")
+	return strings.TrimSuffix(out[idx+2:], "\n")
+}
+
+func TestResample_OverrideTags(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"pos and sizes", `{\pos(320,240)\fs20\bord2\shad1.5\fsp1}Hi`, `{\pos(640,480)\fs40\bord4\shad3\fsp2}Hi`},
+		{"move keeps times", `{\move(0,0,100,50,0,1000)\org(10,20)}x`, `{\move(0,0,200,100,0,1000)\org(20,40)}x`},
+		{"rect clip", `{\clip(0,0,100,100)}x`, `{\clip(0,0,200,200)}x`},
+		{"vector clip with scale", `{\iclip(2,m 0 0 l 4 0 4 4)}x`, `{\iclip(2,m 0 0 l 8 0 8 8)}x`},
+		{"transform", `{\t(0,500,\fs40\clip(0,0,10,10))}x`, `{\t(0,500,\fs80\clip(0,0,20,20))}x`},
+		{"drawing", `{\p1}m 0 0 l 10 0 10 10{\p0}text 5`, `{\p1}m 0 0 l 20 0 20 20{\p0}text 5`},
+		{"untouched tags", `{\fscx100\fscy100\fs+2\fnArial\c&H0000FF&}x`, `{\fscx100\fscy100\fs+2\fnArial\c&H0000FF&}x`},
+		{"comment in block", `{note\b1}x`, `{note\b1}x`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resampleDialogue(t, tt.text, Resolution{1280, 960}, ResampleOptions{})
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResample_AspectModes(t *testing.T) {
+	to := Resolution{1920, 1080}
+
+	tests := []struct {
+		name  string
+		mode  AspectMode
+		text  string
+		want  string
+		style string
+	}{
+		{"stretch", AspectStretch, `{\pos(320,240)\fscx50}x`, `{\pos(960,540)\fscx66.67}x`, "Style: Default,45,133.33,30,30,23\n"},
+		{"add borders", AspectAddBorders, `{\pos(0,0)}x`, `{\pos(240,0)}x`, "Style: Default,45,100,262,262,23\n"},
+		{"remove borders", AspectRemoveBorders, `{\pos(0,60)}x`, `{\pos(0,0)}x`, "Style: Default,60,100,30,30,0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := ResampleOptions{Aspect: tt.mode}
+			if got := resampleDialogue(t, tt.text, to, opts); got != tt.want {
+				t.Errorf("text = %s, want %s", got, tt.want)
+			}
+			out := Resample(resampleScript, to, opts)
+			if !strings.Contains(out, tt.style) {
+				t.Errorf("style line missing %q:\n%s", tt.style, out)
+			}
+		})
+	}
+}
+
+func TestResampleFile(t *testing.T) {
+	src := writeTempFile(t, "in.ass", "\uFEFF"+strings.ReplaceAll(resampleScript, "\n", "\r\n")+
+		"Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,{\\pos(320,240)}Hi\r\n")
+	dst := filepath.Join(t.TempDir(), "out.ass")
+
+	if err := ResampleFile(src, dst, Resolution{1280, 960}, ResampleOptions{}); err != nil {
+		t.Fatalf("ResampleFile error: %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	out := string(data)
+	if !strings.HasPrefix(out, "[Script Info]\r\nPlayResX: 1280\r\n") {
+		t.Errorf("output should start with the resampled header:\n%q", out)
+	}
+	if !strings.Contains(out, `{\pos(640,480)}Hi`+"\r\n") {
+		t.Errorf("dialogue not resampled:\n%q", out)
+	}
+
+	notASS := writeTempFile(t, "in.srt", "1\n00:00:01,000 --> 00:00:02,000\nHi\n")
+	if err := ResampleFile(notASS, dst, Resolution{1280, 960}, ResampleOptions{}); err == nil {
+		t.Error("expected error for non-ASS input")
+	}
+}
@@ -1,33 +1,59 @@
 package assout
 
 import (
-	"fmt"
 	"io"
 	"sort"
 	"strings"
 
+	"mkv-sub-extractor/pkg/ass"
 	"mkv-sub-extractor/pkg/subtitle"
 )
 
+// SRTOptions controls how WriteSRTAsASSWithOptions renders converted SRT events.
+// The zero value reproduces WriteSRTAsASS.
+type SRTOptions struct {
+	Template *StyleTemplate // generated header; nil uses DefaultStyleTemplate
+
+	// Fonts enables language-aware fonts when non-nil: the Default style takes
+	// the font, size and Encoding mapped to Language, and lines written in
+	// another script get a \fn override with that script's font.
+	Fonts    LanguageFonts
+	Language string // ISO 639 track language, e.g. "jpn"; empty or "und" detects the script
+
+	// PlayRes rescales the template to this script resolution (see
+	// StyleTemplate.ScaledTo), typically the video's. Zero keeps the template's.
+	PlayRes Resolution
+}
+
 // WriteSRTAsASS writes subtitle events to w in ASS format using a default
 // generated header with Microsoft YaHei font style. Events are sorted by
 // start time. SRT HTML tags in event text are converted to ASS override tags,
 // and newlines are converted to \N hard line breaks.
 func WriteSRTAsASS(w io.Writer, events []subtitle.SubtitleEvent) error {
-	// Write the default header (Script Info + V4+ Styles)
-	if _, err := io.WriteString(w, defaultASSHeader); err != nil {
-		return fmt.Errorf("writing ASS header: %w", err)
-	}
+	return WriteSRTAsASSWithOptions(w, events, SRTOptions{})
+}
 
-	// Write Events section header
-	if _, err := io.WriteString(w, "\r\n[Events]\r\n"); err != nil {
-		return fmt.Errorf("writing events section: %w", err)
+// WriteSRTAsASSWithOptions is like WriteSRTAsASS but renders the header from
+// opts.Template, allowing user-supplied fonts, colours and PlayRes, and
+// optionally picks fonts from the track language (see SRTOptions.Fonts).
+func WriteSRTAsASSWithOptions(w io.Writer, events []subtitle.SubtitleEvent, opts SRTOptions) error {
+	tmpl := DefaultStyleTemplate()
+	if opts.Template != nil {
+		tmpl = *opts.Template
 	}
 
-	// Write the events Format line
-	if _, err := io.WriteString(w, defaultEventsFormat); err != nil {
-		return fmt.Errorf("writing events format: %w", err)
+	var switcher *fontSwitcher
+	if opts.Fonts != nil {
+		if font, ok := opts.Fonts.Lookup(opts.Language); ok {
+			tmpl = tmpl.withLanguageFont(font)
+		}
+		switcher = newFontSwitcher(opts.Fonts, opts.Language, tmpl.Fontname, events)
 	}
+	tmpl = tmpl.ScaledTo(opts.PlayRes)
+
+	// Start from the template header (Script Info + V4+ Styles)
+	doc := ass.Parse(tmpl.Header())
+	section := doc.EnsureEvents()
 
 	// Sort events by start time (ascending)
 	sorted := make([]subtitle.SubtitleEvent, len(events))
@@ -36,23 +62,28 @@ func WriteSRTAsASS(w io.Writer, events []subtitle.SubtitleEvent) error {
 		return sorted[i].Start < sorted[j].Start
 	})
 
-	// Write each event as a Dialogue line
+	// Add each event as a Dialogue line
 	for _, ev := range sorted {
-		start := FormatASSTimestamp(ev.Start)
-		end := FormatASSTimestamp(ev.End)
-
-		// Convert SRT tags to ASS override tags
-		text := subtitle.ConvertSRTTagsToASS(ev.Text)
+		// Give secondary-script lines their own font, then convert SRT tags
+		// to ASS override tags
+		text := ev.Text
+		if switcher != nil {
+			text = switcher.apply(strings.ReplaceAll(text, "\r", ""))
+		}
+		text = subtitle.ConvertSRTTagsToASS(text)
 
 		// Strip \r and convert \n to \N (ASS hard line break)
 		text = strings.ReplaceAll(text, "\r", "")
 		text = strings.ReplaceAll(text, "\n", `\N`)
 
-		line := fmt.Sprintf("Dialogue: 0,%s,%s,Default,,0,0,0,,%s\r\n", start, end, text)
-		if _, err := io.WriteString(w, line); err != nil {
-			return fmt.Errorf("writing dialogue line: %w", err)
-		}
+		section.Events = append(section.Events, &ass.Event{
+			Type:  ass.Dialogue,
+			Start: ev.Start,
+			End:   ev.End,
+			Style: "Default",
+			Text:  text,
+		})
 	}
 
-	return nil
+	return writeDocument(w, doc)
 }
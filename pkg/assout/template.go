@@ -1,30 +1,263 @@
 package assout
 
-// defaultASSHeader is the generated header for SRT-to-ASS conversion.
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mkv-sub-extractor/pkg/ass"
+)
+
+// StyleTemplate describes the generated header for SRT-to-ASS conversion: the
+// script resolution and the single "Default" style every converted event uses.
+// Colours use ASS &HAABBGGRR notation.
+//
+// A template is either structured (the fields below) or raw (RawHeader set),
+// in which case the header text loaded from a file is written verbatim.
+type StyleTemplate struct {
+	PlayResX        int     `json:"play_res_x"`
+	PlayResY        int     `json:"play_res_y"`
+	Fontname        string  `json:"font"`
+	Fontsize        float64 `json:"size"`
+	PrimaryColour   string  `json:"primary_colour"`
+	SecondaryColour string  `json:"secondary_colour"`
+	OutlineColour   string  `json:"outline_colour"`
+	BackColour      string  `json:"back_colour"`
+	Bold            bool    `json:"bold"`
+	Italic          bool    `json:"italic"`
+	BorderStyle     int     `json:"border_style"`
+	Outline         float64 `json:"outline"`
+	Shadow          float64 `json:"shadow"`
+	Alignment       int     `json:"alignment"`
+	MarginL         int     `json:"margin_l"`
+	MarginR         int     `json:"margin_r"`
+	MarginV         int     `json:"margin_v"`
+	Encoding        int     `json:"encoding"`
+
+	// RawHeader holds a complete [Script Info] + [V4+ Styles] header loaded
+	// from an ASS file. When non-empty it replaces the structured fields.
+	RawHeader string `json:"-"`
+}
+
+// DefaultStyleTemplate returns the built-in SRT-to-ASS look.
 // Font: Microsoft YaHei (user decision), Size: 58 (comparable to player default
 // SRT rendering at ~5.4% of 1080p screen height), PlayRes: 1920x1080 (modern
-// standard), CRLF line endings (ASS convention).
-const defaultASSHeader = "[Script Info]\r\n" +
-	"; Script generated by mkv-sub-extractor\r\n" +
-	"ScriptType: v4.00+\r\n" +
-	"PlayResX: 1920\r\n" +
-	"PlayResY: 1080\r\n" +
-	"WrapStyle: 0\r\n" +
-	"ScaledBorderAndShadow: yes\r\n" +
-	"\r\n" +
-	"[V4+ Styles]\r\n" +
-	"Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, " +
-	"OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, " +
-	"ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, " +
-	"Alignment, MarginL, MarginR, MarginV, Encoding\r\n" +
-	"Style: Default,Microsoft YaHei,58," +
-	"&H00FFFFFF,&H000000FF,&H00000000,&H80000000," +
-	"0,0,0,0," +
-	"100,100,0,0," +
-	"1,2,1," +
-	"2," +
-	"20,20,30," +
-	"1\r\n"
-
-// defaultEventsFormat is the Format line for the [Events] section.
-const defaultEventsFormat = "Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\r\n"
+// standard).
+func DefaultStyleTemplate() StyleTemplate {
+	return StyleTemplate{
+		PlayResX:        1920,
+		PlayResY:        1080,
+		Fontname:        "Microsoft YaHei",
+		Fontsize:        58,
+		PrimaryColour:   "&H00FFFFFF",
+		SecondaryColour: "&H000000FF",
+		OutlineColour:   "&H00000000",
+		BackColour:      "&H80000000",
+		BorderStyle:     1,
+		Outline:         2,
+		Shadow:          1,
+		Alignment:       2,
+		MarginL:         20,
+		MarginR:         20,
+		MarginV:         30,
+		Encoding:        1,
+	}
+}
+
+// stylePresets holds the named templates selectable from the CLI.
+var stylePresets = map[string]func() StyleTemplate{
+	"default": DefaultStyleTemplate,
+
+	// noto swaps in Noto Sans CJK SC, which ships with most Linux distributions
+	// and Android, keeping the default metrics.
+	"noto": func() StyleTemplate {
+		t := DefaultStyleTemplate()
+		t.Fontname = "Noto Sans CJK SC"
+		return t
+	},
+
+	// boxed renders text on a translucent opaque box instead of an outline,
+	// which stays readable over bright scenes.
+	"boxed": func() StyleTemplate {
+		t := DefaultStyleTemplate()
+		t.BorderStyle = 3
+		t.OutlineColour = "&H80000000"
+		t.Outline = 6
+		t.Shadow = 0
+		return t
+	},
+}
+
+// StylePresetNames returns the names of all built-in style presets, sorted.
+func StylePresetNames() []string {
+	names := make([]string, 0, len(stylePresets))
+	for name := range stylePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupStylePreset returns the built-in template with the given name
+// (case-insensitive).
+func LookupStylePreset(name string) (StyleTemplate, error) {
+	preset, ok := stylePresets[strings.ToLower(name)]
+	if !ok {
+		return StyleTemplate{}, fmt.Errorf("unknown style preset %q (available: %s)",
+			name, strings.Join(StylePresetNames(), ", "))
+	}
+	return preset(), nil
+}
+
+// LoadStyleTemplate reads a style template from path.
+//
+// Files with a .json extension are structured configs. Fields that are omitted
+// keep the values of the base template, which is the default look unless the
+// config names another one via "preset":
+//
+//	{"preset": "noto", "size": 64, "margin_v": 40}
+//
+// Any other file is treated as an ASS (or SSA) header. Its [Script Info] and
+// styles sections are used verbatim; an [Events] section, if present, is
+// dropped. The header must define a style named "Default", since converted
+// SRT events reference it.
+func LoadStyleTemplate(path string) (StyleTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StyleTemplate{}, fmt.Errorf("read style template: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return parseStyleConfig(data)
+	}
+	return parseStyleHeader(string(data))
+}
+
+// parseStyleConfig decodes a JSON style config on top of its base preset.
+func parseStyleConfig(data []byte) (StyleTemplate, error) {
+	var base struct {
+		Preset string `json:"preset"`
+	}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return StyleTemplate{}, fmt.Errorf("parse style config: %w", err)
+	}
+
+	tmpl := DefaultStyleTemplate()
+	if base.Preset != "" {
+		preset, err := LookupStylePreset(base.Preset)
+		if err != nil {
+			return StyleTemplate{}, err
+		}
+		tmpl = preset
+	}
+
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return StyleTemplate{}, fmt.Errorf("parse style config: %w", err)
+	}
+	if err := tmpl.validate(); err != nil {
+		return StyleTemplate{}, err
+	}
+	return tmpl, nil
+}
+
+// parseStyleHeader validates a raw ASS/SSA header and stores it as a raw template.
+func parseStyleHeader(header string) (StyleTemplate, error) {
+	doc := ass.Parse(header)
+	ConvertSSAToASS(doc)
+
+	// Drop the [Events] section; the writer emits its own.
+	if events := doc.Events(); events != nil {
+		doc.RemoveSection(events)
+	}
+
+	if doc.ScriptInfo() == nil {
+		return StyleTemplate{}, fmt.Errorf("style template has no [Script Info] section")
+	}
+	styles := doc.Styles()
+	if styles == nil {
+		return StyleTemplate{}, fmt.Errorf("style template has no [V4+ Styles] section")
+	}
+	if styles.Style("Default") == nil {
+		return StyleTemplate{}, fmt.Errorf("style template has no style named \"Default\"")
+	}
+
+	doc.BOM, doc.Newline = false, "\r\n"
+	return StyleTemplate{RawHeader: strings.TrimRight(doc.String(), "\r\n") + "\r\n"}, nil
+}
+
+// validate rejects structured templates that would produce an unusable header.
+func (t StyleTemplate) validate() error {
+	if t.PlayResX <= 0 || t.PlayResY <= 0 {
+		return fmt.Errorf("style template: PlayRes must be positive, got %dx%d", t.PlayResX, t.PlayResY)
+	}
+	if t.Fontname == "" {
+		return fmt.Errorf("style template: font name is empty")
+	}
+	if t.Fontsize <= 0 {
+		return fmt.Errorf("style template: font size must be positive, got %v", t.Fontsize)
+	}
+	if t.Alignment < 1 || t.Alignment > 9 {
+		return fmt.Errorf("style template: alignment must be 1-9, got %d", t.Alignment)
+	}
+	for _, c := range []string{t.PrimaryColour, t.SecondaryColour, t.OutlineColour, t.BackColour} {
+		if !isASSColour(c) {
+			return fmt.Errorf("style template: invalid colour %q (want &HAABBGGRR)", c)
+		}
+	}
+	return nil
+}
+
+// isASSColour reports whether s is an &H-prefixed hexadecimal colour value.
+func isASSColour(s string) bool {
+	hex, ok := strings.CutPrefix(strings.ToUpper(s), "&H")
+	if !ok || hex == "" || len(hex) > 8 {
+		return false
+	}
+	_, err := strconv.ParseUint(hex, 16, 32)
+	return err == nil
+}
+
+// Header renders the [Script Info] and [V4+ Styles] sections for the template
+// with CRLF line endings (ASS convention). Raw templates are returned verbatim.
+func (t StyleTemplate) Header() string {
+	if t.RawHeader != "" {
+		return t.RawHeader
+	}
+
+	info := ass.NewScriptInfo()
+	info.Lines = append(info.Lines, ass.InfoComment("Script generated by mkv-sub-extractor"))
+	info.Set("ScriptType", "v4.00+")
+	info.Set("PlayResX", strconv.Itoa(t.PlayResX))
+	info.Set("PlayResY", strconv.Itoa(t.PlayResY))
+	info.Set("WrapStyle", "0")
+	info.Set("ScaledBorderAndShadow", "yes")
+
+	style := ass.NewStyle("Default")
+	style.Fontname = t.Fontname
+	style.Fontsize = t.Fontsize
+	style.PrimaryColour = t.PrimaryColour
+	style.SecondaryColour = t.SecondaryColour
+	style.OutlineColour = t.OutlineColour
+	style.BackColour = t.BackColour
+	style.Bold = t.Bold
+	style.Italic = t.Italic
+	style.BorderStyle = t.BorderStyle
+	style.Outline = t.Outline
+	style.Shadow = t.Shadow
+	style.Alignment = t.Alignment
+	style.MarginL = t.MarginL
+	style.MarginR = t.MarginR
+	style.MarginV = t.MarginV
+	style.Encoding = t.Encoding
+	styles := ass.NewStyleSection()
+	styles.Styles = append(styles.Styles, style)
+
+	doc := ass.New()
+	doc.AddSection(info)
+	doc.AddSection(styles)
+	return doc.String()
+}
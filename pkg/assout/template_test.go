@@ -0,0 +1,171 @@
+package assout
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// legacyASSHeader is the hardcoded header used before style templates existed.
+// The default template must keep rendering it byte for byte.
+const legacyASSHeader = "[Script Info]\r\n" +
+	"; Script generated by mkv-sub-extractor\r\n" +
+	"ScriptType: v4.00+\r\n" +
+	"PlayResX: 1920\r\n" +
+	"PlayResY: 1080\r\n" +
+	"WrapStyle: 0\r\n" +
+	"ScaledBorderAndShadow: yes\r\n" +
+	"\r\n" +
+	"[V4+ Styles]\r\n" +
+	"Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, " +
+	"OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, " +
+	"ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, " +
+	"Alignment, MarginL, MarginR, MarginV, Encoding\r\n" +
+	"Style: Default,Microsoft YaHei,58," +
+	"&H00FFFFFF,&H000000FF,&H00000000,&H80000000," +
+	"0,0,0,0," +
+	"100,100,0,0," +
+	"1,2,1," +
+	"2," +
+	"20,20,30," +
+	"1\r\n"
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestDefaultStyleTemplate_MatchesLegacyHeader(t *testing.T) {
+	got := DefaultStyleTemplate().Header()
+	if got != legacyASSHeader {
+		t.Errorf("default header changed:\ngot:\n%q\nwant:\n%q", got, legacyASSHeader)
+	}
+}
+
+func TestLookupStylePreset(t *testing.T) {
+	tmpl, err := LookupStylePreset("Noto")
+	if err != nil {
+		t.Fatalf("LookupStylePreset(Noto) error: %v", err)
+	}
+	if tmpl.Fontname != "Noto Sans CJK SC" {
+		t.Errorf("Fontname = %q, want %q", tmpl.Fontname, "Noto Sans CJK SC")
+	}
+
+	if _, err := LookupStylePreset("nope"); err == nil {
+		t.Error("expected error for unknown preset")
+	}
+
+	for _, name := range StylePresetNames() {
+		tmpl, _ := LookupStylePreset(name)
+		if err := tmpl.validate(); err != nil {
+			t.Errorf("preset %q is invalid: %v", name, err)
+		}
+	}
+}
+
+func TestLoadStyleTemplate_JSONOverridesPreset(t *testing.T) {
+	path := writeTempFile(t, "house.json", `{"preset": "boxed", "font": "Source Han Sans", "size": 64, "margin_v": 48}`)
+
+	tmpl, err := LoadStyleTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadStyleTemplate error: %v", err)
+	}
+	if tmpl.Fontname != "Source Han Sans" || tmpl.Fontsize != 64 || tmpl.MarginV != 48 {
+		t.Errorf("overrides not applied: %+v", tmpl)
+	}
+	if tmpl.BorderStyle != 3 {
+		t.Errorf("BorderStyle = %d, want 3 from boxed preset", tmpl.BorderStyle)
+	}
+	if tmpl.PlayResY != 1080 {
+		t.Errorf("PlayResY = %d, want default 1080", tmpl.PlayResY)
+	}
+
+	header := tmpl.Header()
+	if !strings.Contains(header, "Style: Default,Source Han Sans,64,") {
+		t.Errorf("header missing overridden style line:\n%s", header)
+	}
+}
+
+func TestLoadStyleTemplate_JSONInvalid(t *testing.T) {
+	tests := map[string]string{
+		"bad json":     `{"size": }`,
+		"bad colour":   `{"primary_colour": "white"}`,
+		"zero playres": `{"play_res_y": 0}`,
+		"bad preset":   `{"preset": "nope"}`,
+		"bad align":    `{"alignment": 12}`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeTempFile(t, "style.json", content)
+			if _, err := LoadStyleTemplate(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoadStyleTemplate_ASSHeader(t *testing.T) {
+	content := "[Script Info]\nScriptType: v4.00+\nPlayResX: 1280\nPlayResY: 720\n\n" +
+		"[V4+ Styles]\nFormat: Name, Fontname, Fontsize\nStyle: Default,Arial,40\n\n" +
+		"[Events]\nFormat: Layer, Start, End, Style, Text\nDialogue: 0,0:00:00.00,0:00:01.00,Default,stale\n"
+	path := writeTempFile(t, "house.ass", content)
+
+	tmpl, err := LoadStyleTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadStyleTemplate error: %v", err)
+	}
+
+	header := tmpl.Header()
+	if strings.Contains(header, "[Events]") || strings.Contains(header, "stale") {
+		t.Errorf("events section should be dropped:\n%s", header)
+	}
+	if !strings.Contains(header, "PlayResX: 1280\r\n") {
+		t.Errorf("header should be CRLF and keep PlayRes:\n%q", header)
+	}
+	if !strings.HasSuffix(header, "Style: Default,Arial,40\r\n") {
+		t.Errorf("header should end with the style line:\n%q", header)
+	}
+}
+
+func TestLoadStyleTemplate_ASSHeaderWithoutDefaultStyle(t *testing.T) {
+	content := "[Script Info]\nScriptType: v4.00+\n\n[V4+ Styles]\nFormat: Name, Fontname\nStyle: Sign,Arial\n"
+	path := writeTempFile(t, "house.ass", content)
+
+	if _, err := LoadStyleTemplate(path); err == nil {
+		t.Fatal("expected error for header without Default style")
+	}
+}
+
+func TestLoadStyleTemplate_MissingFile(t *testing.T) {
+	if _, err := LoadStyleTemplate(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestWriteSRTAsASSWithOptions_UsesTemplate(t *testing.T) {
+	tmpl, _ := LookupStylePreset("noto")
+	events := []subtitle.SubtitleEvent{
+		{Start: 0, End: 1_000_000_000, Text: "Hello"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSRTAsASSWithOptions(&buf, events, SRTOptions{Template: &tmpl}); err != nil {
+		t.Fatalf("WriteSRTAsASSWithOptions error: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Style: Default,Noto Sans CJK SC,58,") {
+		t.Errorf("output does not use template font:\n%s", output)
+	}
+	if !strings.Contains(output, "Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,Hello\r\n") {
+		t.Errorf("dialogue line missing:\n%s", output)
+	}
+}
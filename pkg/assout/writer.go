@@ -4,93 +4,61 @@ import (
 	"fmt"
 	"io"
 	"sort"
-	"strings"
 
+	"mkv-sub-extractor/pkg/ass"
 	"mkv-sub-extractor/pkg/subtitle"
 )
 
+// ASSOptions controls how WriteASSPassthroughWithOptions rewrites an ASS/SSA
+// track. The zero value reproduces WriteASSPassthrough.
+type ASSOptions struct {
+	// PlayRes resamples the script to this resolution with Resample: the
+	// header's PlayRes and styles, event margins and override tags are all
+	// rescaled. Zero keeps the script's own resolution.
+	PlayRes Resolution
+
+	// Aspect selects how an aspect ratio change is handled when resampling.
+	Aspect AspectMode
+}
+
 // WriteASSPassthrough writes a complete ASS file from a CodecPrivate header and
-// subtitle events. The CodecPrivate bytes are written as the ASS header, with the
-// [Events] section and Dialogue lines appended.
+// subtitle events. The CodecPrivate header is parsed into an ass.Document and
+// the events are appended to its [Events] section.
 //
 // If the CodecPrivate contains [V4 Styles] (SSA format), the header is automatically
-// converted to [V4+ Styles] (ASS format) using ConvertSSAHeaderToASS. This handles
+// converted to [V4+ Styles] (ASS format) using ConvertSSAToASS. This handles
 // both S_TEXT/SSA tracks and S_TEXT/ASS tracks with legacy V4 headers.
 //
-// The function handles three CodecPrivate scenarios:
-//   - No [Events] section: appends [Events] + default Format line
-//   - [Events] section without Format line: appends default Format line
-//   - [Events] section with Format line: uses existing Format line (no duplication)
+// A header without an [Events] section, or whose [Events] section has no
+// Format line, gets the standard ones; an existing Format line is kept and
+// decides the field order of the written events.
 //
 // Events are sorted by StartTime (primary) and ReadOrder (secondary) before writing.
 // Output uses CRLF line endings per ASS convention.
 func WriteASSPassthrough(w io.Writer, codecPrivate []byte, codecID string, events []subtitle.SubtitleEvent) error {
-	header := string(codecPrivate)
-
-	// Always attempt SSA→ASS header conversion. Some MKV files have CodecID
-	// "S_TEXT/ASS" but CodecPrivate with [V4 Styles] (SSA format). The conversion
-	// function already guards on [V4 Styles] presence, so calling it unconditionally
-	// is safe — it's a no-op for proper V4+ headers.
-	header = ConvertSSAHeaderToASS(header)
+	return WriteASSPassthroughWithOptions(w, codecPrivate, codecID, events, ASSOptions{})
+}
 
-	// Normalize line endings: convert any existing CRLF to LF first for consistent processing,
-	// then we'll write with CRLF
-	header = strings.ReplaceAll(header, "\r\n", "\n")
-	header = strings.ReplaceAll(header, "\r", "\n")
+// WriteASSPassthroughWithOptions is like WriteASSPassthrough but can resample
+// the script to a new PlayRes (see ASSOptions).
+func WriteASSPassthroughWithOptions(w io.Writer, codecPrivate []byte, codecID string, events []subtitle.SubtitleEvent, opts ASSOptions) error {
+	doc := passthroughDocument(codecPrivate, events)
 
-	// Determine [Events] section handling
-	headerLower := strings.ToLower(header)
-	eventsIdx := strings.Index(headerLower, "[events]")
-	hasEventsSection := eventsIdx >= 0
-	hasFormatLine := false
+	// Resampling covers the header, event margins and override tags alike.
+	ResampleDocument(doc, opts.PlayRes, ResampleOptions{Aspect: opts.Aspect})
 
-	if hasEventsSection {
-		// Check if there's a Format: line after [Events]
-		afterEvents := header[eventsIdx:]
-		lines := strings.Split(afterEvents, "\n")
-		for _, line := range lines[1:] { // skip the [Events] line itself
-			trimmed := strings.TrimSpace(line)
-			if trimmed == "" {
-				continue
-			}
-			if strings.HasPrefix(trimmed, "Format:") {
-				hasFormatLine = true
-				break
-			}
-			// If we hit a non-empty, non-Format line or a section header, stop looking
-			if strings.HasPrefix(trimmed, "[") {
-				break
-			}
-		}
-	}
+	return writeDocument(w, doc)
+}
 
-	// Write header with CRLF line endings
-	headerLines := strings.Split(header, "\n")
-	for i, line := range headerLines {
-		// Don't add trailing CRLF if this is the last line and it's empty
-		// (which would have resulted from a trailing newline in the original)
-		if i == len(headerLines)-1 && line == "" {
-			break
-		}
-		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
-			return fmt.Errorf("writing header: %w", err)
-		}
-	}
+// passthroughDocument parses a CodecPrivate header, converting SSA headers to
+// ASS, and appends the events to it in order.
+func passthroughDocument(codecPrivate []byte, events []subtitle.SubtitleEvent) *ass.Document {
+	doc := ass.Parse(string(codecPrivate))
 
-	// Append [Events] section if not present in header
-	if !hasEventsSection {
-		if _, err := io.WriteString(w, "\r\n[Events]\r\n"); err != nil {
-			return fmt.Errorf("writing events section: %w", err)
-		}
-		if _, err := io.WriteString(w, defaultEventsFormat); err != nil {
-			return fmt.Errorf("writing events format: %w", err)
-		}
-	} else if !hasFormatLine {
-		// [Events] exists but no Format line -- add it
-		if _, err := io.WriteString(w, defaultEventsFormat); err != nil {
-			return fmt.Errorf("writing events format: %w", err)
-		}
-	}
+	// Always attempt SSA→ASS header conversion. Some MKV files have CodecID
+	// "S_TEXT/ASS" but CodecPrivate with [V4 Styles] (SSA format). The conversion
+	// is a no-op for proper V4+ headers.
+	ConvertSSAToASS(doc)
 
 	// Sort events: primary by StartTime, secondary by ReadOrder
 	sorted := make([]subtitle.SubtitleEvent, len(events))
@@ -102,22 +70,38 @@ func WriteASSPassthrough(w io.Writer, codecPrivate []byte, codecID string, event
 		return sorted[i].ReadOrder < sorted[j].ReadOrder
 	})
 
-	// Write Dialogue lines
+	section := doc.EnsureEvents()
 	for _, ev := range sorted {
-		start := FormatASSTimestamp(ev.Start)
-		end := FormatASSTimestamp(ev.End)
+		section.Events = append(section.Events, dialogueEvent(ev))
+	}
+	return doc
+}
 
-		line := fmt.Sprintf("Dialogue: %d,%s,%s,%s,%s,%s,%s,%s,%s,%s\r\n",
-			ev.Layer,
-			start, end,
-			ev.Style, ev.Name,
-			ev.MarginL, ev.MarginR, ev.MarginV,
-			ev.Effect, ev.Text,
-		)
-		if _, err := io.WriteString(w, line); err != nil {
-			return fmt.Errorf("writing dialogue line: %w", err)
-		}
+// dialogueEvent converts a Matroska subtitle event to a Dialogue line.
+// Margins are written as they were muxed, such as "0000", unless resampling
+// changes them; non-numeric margins read as 0, i.e. "use the style's margin".
+func dialogueEvent(ev subtitle.SubtitleEvent) *ass.Event {
+	line := &ass.Event{
+		Type:   ass.Dialogue,
+		Layer:  ev.Layer,
+		Start:  ev.Start,
+		End:    ev.End,
+		Style:  ev.Style,
+		Name:   ev.Name,
+		Effect: ev.Effect,
+		Text:   ev.Text,
 	}
+	line.SetField("MarginL", ev.MarginL)
+	line.SetField("MarginR", ev.MarginR)
+	line.SetField("MarginV", ev.MarginV)
+	return line
+}
 
+// writeDocument writes doc with CRLF line endings and a final line break.
+func writeDocument(w io.Writer, doc *ass.Document) error {
+	doc.Newline, doc.NoFinalNewline = "\r\n", false
+	if _, err := doc.WriteTo(w); err != nil {
+		return fmt.Errorf("writing ASS document: %w", err)
+	}
 	return nil
 }
@@ -445,3 +445,28 @@ func TestWriteASSPassthrough_CaseInsensitiveEventsDetection(t *testing.T) {
 		t.Errorf("Expected 1 [events] section (case-insensitive), got %d", eventsCount)
 	}
 }
+
+func TestWriteASSPassthrough_MarginsKeepTheirText(t *testing.T) {
+	header := strings.Replace(simpleV4PlusHeader, "ScriptType: v4.00+\n", "ScriptType: v4.00+\nPlayResX: 1280\nPlayResY: 720\n", 1)
+	events := []subtitle.SubtitleEvent{
+		{Start: 1_000_000_000, End: 2_000_000_000, Style: "Default", MarginL: "0000", MarginR: "abc", MarginV: "0020", Text: "Hello"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteASSPassthrough(&buf, []byte(header), "S_TEXT/ASS", events); err != nil {
+		t.Fatalf("WriteASSPassthrough returned error: %v", err)
+	}
+	if want := ",Default,,0000,abc,0020,,Hello"; !strings.Contains(buf.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, buf.String())
+	}
+
+	// Resampling rewrites only the margins it changes.
+	buf.Reset()
+	opts := ASSOptions{PlayRes: Resolution{X: 1920, Y: 1080}}
+	if err := WriteASSPassthroughWithOptions(&buf, []byte(header), "S_TEXT/ASS", events, opts); err != nil {
+		t.Fatalf("WriteASSPassthroughWithOptions returned error: %v", err)
+	}
+	if want := ",Default,,0000,abc,30,,Hello"; !strings.Contains(buf.String(), want) {
+		t.Errorf("resampled output missing %q:\n%s", want, buf.String())
+	}
+}
@@ -113,6 +113,31 @@ func ErrNoMKVFilesInDir(dir string) *CLIError {
 	}
 }
 
+// ErrInvalidStyle creates a CLIError for when an SRT-to-ASS style preset,
+// template file or language font map cannot be used.
+func ErrInvalidStyle(source string, reason error) *CLIError {
+	return &CLIError{
+		Code:       "E06",
+		Title:      "Invalid Style Template",
+		Context:    source,
+		Detail:     fmt.Sprintf("Cannot use style %q: %v", source, reason),
+		Suggestion: "Check the preset name, or that the file is an ASS header with a Default style or valid JSON.",
+		ExitCode:   ExitGeneral,
+	}
+}
+
+// ErrInvalidValue creates a CLIError for a flag whose value cannot be parsed.
+func ErrInvalidValue(flag string, value string, reason error) *CLIError {
+	return &CLIError{
+		Code:       "E07",
+		Title:      "Invalid Option Value",
+		Context:    fmt.Sprintf("%s %s", flag, value),
+		Detail:     fmt.Sprintf("Cannot use %q for %s: %v", value, flag, reason),
+		Suggestion: "Run with --help to see the accepted values.",
+		ExitCode:   ExitGeneral,
+	}
+}
+
 // ErrNoSubtitleTracks creates a CLIError for when the MKV has no subtitle tracks at all.
 func ErrNoSubtitleTracks(path string) *CLIError {
 	return &CLIError{
@@ -161,6 +186,19 @@ func ErrTrackNotFound(trackNum int, path string) *CLIError {
 	}
 }
 
+// ErrNoTrackInLanguage creates a CLIError for when no text track is in a
+// language given to --lang.
+func ErrNoTrackInLanguage(lang string, path string) *CLIError {
+	return &CLIError{
+		Code:       "E14",
+		Title:      "No Track In Language",
+		Context:    path,
+		Detail:     fmt.Sprintf("No text subtitle track in %q is tagged or detected as %q.", path, lang),
+		Suggestion: "Run without --lang to see the tracks and their languages in interactive mode.",
+		ExitCode:   ExitTrackError,
+	}
+}
+
 // ErrExtractionFailed creates a CLIError for when extraction of a track fails.
 func ErrExtractionFailed(trackIndex int, reason error) *CLIError {
 	return &CLIError{
@@ -184,3 +222,28 @@ func ErrPartialFailure(succeeded int, failed int) *CLIError {
 		ExitCode:   ExitExtraction,
 	}
 }
+
+// ErrCannotWriteReport creates a CLIError for when the --report file cannot be written.
+func ErrCannotWriteReport(path string, reason error) *CLIError {
+	return &CLIError{
+		Code:       "E22",
+		Title:      "Cannot Write Report",
+		Context:    path,
+		Detail:     fmt.Sprintf("Failed to write the extraction report: %v", reason),
+		Suggestion: "Check the path and ensure you have write permissions.",
+		ExitCode:   ExitFileError,
+	}
+}
+
+// ErrVideoTiming creates a CLIError for when the --timecodes or --keyframes
+// file cannot be produced.
+func ErrVideoTiming(path string, reason error) *CLIError {
+	return &CLIError{
+		Code:       "E23",
+		Title:      "Cannot Export Video Timing",
+		Context:    path,
+		Detail:     fmt.Sprintf("Failed to export the video track's timecodes or keyframes: %v", reason),
+		Suggestion: "Check that the file has a video track and that the output directory is writable.",
+		ExitCode:   ExitExtraction,
+	}
+}
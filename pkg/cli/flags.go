@@ -3,18 +3,77 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/pflag"
+
+	"mkv-sub-extractor/pkg/assout"
 )
 
 // Config holds parsed command-line arguments.
 type Config struct {
-	MKVPath      string // positional argument: path to MKV file
-	TrackNumbers []int  // --track / -t: specific track numbers to extract
-	OutputDir    string // --output / -o: output directory (default: same as MKV)
-	Quiet        bool   // --quiet / -q: suppress progress output
-	Verbose      bool   // --verbose / -v: enable debug-level output
+	MKVPath      string   // positional argument: path to MKV file
+	TrackNumbers []int    // --track / -t: specific track numbers to extract
+	Languages    []string // --lang / -l: extract the text tracks in these languages, tagged or detected
+	OutputDir    string   // --output / -o: output directory (default: same as MKV)
+	Quiet        bool     // --quiet / -q: suppress progress output
+	Verbose      bool     // --verbose / -v: enable debug-level output
+	StylePreset  string   // --style: named SRT-to-ASS style preset
+	StyleFile    string   // --style-file: SRT-to-ASS style template (ASS header or JSON)
+	LangFonts    bool     // --lang-fonts: pick SRT-to-ASS fonts from the track language
+	LangFontFile string   // --lang-fonts-file: JSON language-to-font map (implies --lang-fonts)
+	KeepPlayRes  bool     // --keep-playres: keep the template PlayRes instead of the video's
+	ResampleASS  bool     // --resample-ass: resample ASS/SSA tracks to the video resolution
+	ResampleTo   string   // --resample-to: resample ASS/SSA to WxH; also resamples a standalone .ass file
+	Aspect       string   // --aspect: aspect ratio handling when resampling
+	Strict       bool     // --strict: fail a track on the first malformed packet
+	ReportPath   string   // --report: write a JSON extraction report to this file
+	GapFill      string   // --gap-fill: policy for packets without a duration
+	GapDuration  string   // --gap-duration: fixed/maximum filled duration
+	GapCPS       float64  // --gap-cps: reading speed for --gap-fill reading-speed
+	GapMin       string   // --gap-min: minimum reading-speed duration
+	GapToEnd     bool     // --gap-to-end: let the last packet last until the segment end
+	From         string   // --from: start of the time range to extract
+	To           string   // --to: end of the time range to extract
+	Clamp        bool     // --clamp: cut events at the range edges
+	Rebase       bool     // --rebase: time the output from the first kept event
+	NoSeek       bool     // --no-seek: read from the start of the file instead of seeking to --from
+	Sync         string   // --sync: two-point sync "SRC1=DST1,SRC2=DST2"
+	Scale        float64  // --scale: multiply event times by this ratio
+	FPS          string   // --fps: retime for a frame rate change "FROM:TO"
+	Shift        string   // --shift: add a (signed) offset to event times
+	SnapFrames   bool     // --snap-frames: snap event times to the video's frames
+	Timecodes    bool     // --timecodes: write the video track's timecodes v2 file
+	Keyframes    bool     // --keyframes: write the video track's keyframes file
+	LeadIn       string   // --lead-in: padding added before each line
+	LeadOut      string   // --lead-out: padding added after each line
+	LinkGap      string   // --link-gap: link lines separated by at most this gap
+	LinkBias     float64  // --link-bias: where linked lines meet within the gap
+	SnapKeys     string   // --snap-keyframes: keyframe snapping thresholds in frames
+	Chapters     string   // --split-chapters: one file per chapter ("all") or per chapter range
+	Ordered      bool     // --ordered-chapters: follow ordered chapters and linked segments
+	Join         []string // --join: later parts of a multi-part title, joined onto FILE.mkv
+	Bilingual    bool     // --bilingual: merge the selected tracks in pairs into dual-language files
+	BiFonts      string   // --bilingual-fonts: "PRIMARY,SECONDARY" font names
+	SecScale     float64  // --secondary-scale: secondary font size relative to the primary
+	SecAbove     bool     // --secondary-above: place the secondary track above the primary
+	Stack        bool     // --stack: pair overlapping lines into one two-line event
+	Pairs        string   // --pairs: export aligned sentence pairs as "csv" or "tsv"
+	PairsCtx     bool     // --pairs-context: add source, episode and chapter columns
+	AlignTol     string   // --align-tolerance: timing difference forgiven when pairing
+	MinOverlap   float64  // --min-overlap: overlap needed to pair, as a fraction of the shorter line
+	ZhConvert    string   // --zh-convert: Chinese variant for all Chinese tracks and/or per track "N=VARIANT"
+	ZhFonts      bool     // --zh-fonts: also convert font names to the Chinese variant
+	Encoding     string   // --input-encoding: encoding of text that is not valid UTF-8 and/or per track "N=ENCODING"
+	NoDetect     bool     // --no-detect: do not detect the language and Chinese form of tracks from their text
+}
+
+// IsScriptPath reports whether path names an ASS/SSA script rather than an MKV
+// file; such paths are resampled standalone with --resample-to.
+func IsScriptPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".ass" || ext == ".ssa"
 }
 
 // ParseFlags parses command-line arguments using pflag and returns a Config.
@@ -23,9 +82,60 @@ func ParseFlags() Config {
 	var cfg Config
 
 	pflag.IntSliceVarP(&cfg.TrackNumbers, "track", "t", nil, "track numbers to extract (comma-separated, e.g., -t 1,3)")
+	pflag.StringSliceVarP(&cfg.Languages, "lang", "l", nil, "extract the text tracks in these languages, tagged or detected (e.g., -l chi,eng or -l zh-Hant)")
 	pflag.StringVarP(&cfg.OutputDir, "output", "o", "", "output directory for extracted files")
 	pflag.BoolVarP(&cfg.Quiet, "quiet", "q", false, "suppress progress output (only print file paths)")
 	pflag.BoolVarP(&cfg.Verbose, "verbose", "v", false, "enable verbose/debug output")
+	pflag.StringVar(&cfg.StylePreset, "style", "", "SRT-to-ASS style preset ("+strings.Join(assout.StylePresetNames(), ", ")+")")
+	pflag.StringVar(&cfg.StyleFile, "style-file", "", "SRT-to-ASS style template: ASS header file or JSON config")
+	pflag.BoolVar(&cfg.LangFonts, "lang-fonts", false, "pick SRT-to-ASS fonts and encoding from the track language")
+	pflag.StringVar(&cfg.LangFontFile, "lang-fonts-file", "", "JSON map of language to font/size/encoding (implies --lang-fonts)")
+	pflag.BoolVar(&cfg.KeepPlayRes, "keep-playres", false, "keep the style template's PlayRes instead of matching the video resolution")
+	pflag.BoolVar(&cfg.ResampleASS, "resample-ass", false, "resample ASS/SSA tracks to the video resolution")
+	pflag.StringVar(&cfg.ResampleTo, "resample-to", "", "resample ASS/SSA tracks, or a standalone .ass file, to WIDTHxHEIGHT")
+	pflag.StringVar(&cfg.Aspect, "aspect", "stretch", "aspect ratio change when resampling (stretch, add-borders, remove-borders)")
+	pflag.BoolVar(&cfg.Strict, "strict", false, "fail a track on the first malformed subtitle packet instead of repairing or skipping it")
+	pflag.StringVar(&cfg.ReportPath, "report", "", "write a JSON report of extracted tracks and packet diagnostics to this file")
+	pflag.StringVar(&cfg.GapFill, "gap-fill", "next-start", "end time for packets without a duration (next-start, fixed, capped, reading-speed)")
+	pflag.StringVar(&cfg.GapDuration, "gap-duration", "", "duration for --gap-fill fixed, or maximum for capped/reading-speed (e.g. 5s)")
+	pflag.Float64Var(&cfg.GapCPS, "gap-cps", 0, "reading speed in characters per second for --gap-fill reading-speed (default 15)")
+	pflag.StringVar(&cfg.GapMin, "gap-min", "", "minimum duration for --gap-fill reading-speed (default 1s)")
+	pflag.BoolVar(&cfg.GapToEnd, "gap-to-end", false, "let the last packet without a duration last until the end of the video")
+	pflag.StringVar(&cfg.From, "from", "", "extract only events from this time on (e.g. 0:12:30, 12:30.5, 750s); events starting over 30s earlier may be missed, see --no-seek")
+	pflag.StringVar(&cfg.To, "to", "", "extract only events before this time")
+	pflag.BoolVar(&cfg.Clamp, "clamp", false, "cut events overlapping --from/--to at the range edges")
+	pflag.BoolVar(&cfg.Rebase, "rebase", false, "shift events so the first kept event starts at zero")
+	pflag.BoolVar(&cfg.NoSeek, "no-seek", false, "with --from, read the file from the start instead of seeking with its cues")
+	pflag.StringVar(&cfg.Sync, "sync", "", "retime so SRC1 maps to DST1 and SRC2 to DST2 (SRC1=DST1,SRC2=DST2)")
+	pflag.Float64Var(&cfg.Scale, "scale", 0, "multiply event times by this ratio")
+	pflag.StringVar(&cfg.FPS, "fps", "", "retime for a frame rate change FROM:TO (e.g. 23.976:25)")
+	pflag.StringVar(&cfg.Shift, "shift", "", "add an offset to event times, applied last (e.g. -1.5s, +0:02)")
+	pflag.BoolVar(&cfg.SnapFrames, "snap-frames", false, "snap event times to the video track's frame timestamps (VFR aware)")
+	pflag.BoolVar(&cfg.Timecodes, "timecodes", false, "also write the video track's frame times as {name}.timecodes.txt (timecodes v2)")
+	pflag.BoolVar(&cfg.Keyframes, "keyframes", false, "also write the video track's keyframes as {name}.keyframes.txt (Aegisub keyframe format)")
+	pflag.StringVar(&cfg.LeadIn, "lead-in", "", "start each line this much earlier, up to the previous line (e.g. 200ms)")
+	pflag.StringVar(&cfg.LeadOut, "lead-out", "", "end each line this much later, up to the next line (e.g. 300ms)")
+	pflag.StringVar(&cfg.LinkGap, "link-gap", "", "make lines separated by at most this gap continuous (e.g. 500ms)")
+	pflag.Float64Var(&cfg.LinkBias, "link-bias", 0, "where linked lines meet: 0 extends the earlier line, 1 starts the later line early")
+	pflag.StringVar(&cfg.SnapKeys, "snap-keyframes", "", "snap starts/ends to video keyframes within START_BEFORE,START_AFTER,END_BEFORE,END_AFTER frames")
+	pflag.Lookup("snap-keyframes").NoOptDefVal = "5,4,5,6"
+	pflag.StringVar(&cfg.Chapters, "split-chapters", "", "write one file per chapter, or per chapter range (e.g. 1-2,3,4-6), rebased to its start")
+	pflag.Lookup("split-chapters").NoOptDefVal = "all"
+	pflag.BoolVar(&cfg.Ordered, "ordered-chapters", false, "follow ordered chapters, pulling in linked segment files (shared OP/ED) from the same directory")
+	pflag.BoolVar(&cfg.Bilingual, "bilingual", false, "merge the selected tracks in pairs (primary, secondary) into one dual-language ASS file each")
+	pflag.StringVar(&cfg.BiFonts, "bilingual-fonts", "", "fonts of the primary and secondary track for --bilingual, e.g. \"Microsoft YaHei,Arial\"")
+	pflag.Float64Var(&cfg.SecScale, "secondary-scale", 0, "secondary font size relative to the primary for --bilingual (default 0.7)")
+	pflag.BoolVar(&cfg.SecAbove, "secondary-above", false, "place the secondary track above the primary for --bilingual")
+	pflag.BoolVar(&cfg.Stack, "stack", false, "with --bilingual, pair overlapping lines into one stacked line")
+	pflag.StringVar(&cfg.Pairs, "pairs", "", "export the selected tracks in pairs as aligned sentence pairs (csv, tsv) instead of ASS")
+	pflag.BoolVar(&cfg.PairsCtx, "pairs-context", false, "add source file, episode and chapter columns to --pairs output")
+	pflag.StringVar(&cfg.AlignTol, "align-tolerance", "", "timing difference forgiven when pairing lines for --pairs (e.g. 300ms)")
+	pflag.Float64Var(&cfg.MinOverlap, "min-overlap", 0, "overlap needed to pair two lines for --pairs, as a fraction of the shorter (default 0.5)")
+	pflag.StringVar(&cfg.ZhConvert, "zh-convert", "", "convert Chinese tracks to cn, t, tw or hk; per track as N=VARIANT (e.g. tw or 3=cn,4=tw)")
+	pflag.BoolVar(&cfg.ZhFonts, "zh-fonts", false, "with --zh-convert, also convert font names in styles and \\fn tags")
+	pflag.StringVar(&cfg.Encoding, "input-encoding", "", "encoding of subtitle text that is not valid UTF-8 instead of detecting it; per track as N=ENCODING (e.g. gbk or 3=big5)")
+	pflag.BoolVar(&cfg.NoDetect, "no-detect", false, "do not read the start of the file to detect the language and Chinese form of text tracks")
+	pflag.StringSliceVar(&cfg.Join, "join", nil, "join the matching track of these later parts into one file (e.g. --join part2.mkv,part3.mkv)")
 
 	pflag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: mkv-sub-extractor [OPTIONS] [FILE.mkv]\n\n")
@@ -38,7 +148,17 @@ func ParseFlags() Config {
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor video.mkv           Extract interactively\n")
 		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor video.mkv -t 1,3    Extract tracks 1 and 3\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor video.mkv -l eng    Extract the English tracks, tagged or detected\n")
 		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor -o subs/ video.mkv  Output to subs/ directory\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor --style noto video.mkv  Convert SRT with the noto style preset\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor --resample-to 1920x1080 subs.ass  Resample an ASS script\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor -t 1 --from 22:00 --to 44:00 --rebase video.mkv  Extract one part\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor -t 1 --split-chapters season.mkv  One file per chapter\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor -t 1 part1.mkv --join part2.mkv  One file for a two-part title\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor -t 2,3 --bilingual video.mkv  Chinese-English dual subtitles\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor --zh-convert tw video.mkv  Chinese tracks in Taiwan Traditional\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor -t 2,3 --pairs tsv video.mkv  Sentence pairs for Anki\n")
+		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor -t 2 --input-encoding big5 old.mkv  Decode a Big5 track\n")
 		fmt.Fprintf(os.Stderr, "  mkv-sub-extractor                     Scan directory for MKV files\n")
 	}
 
@@ -70,6 +190,17 @@ func ValidateConfig(cfg Config) *CLIError {
 		}
 	}
 
+	if cfg.StylePreset != "" && cfg.StyleFile != "" {
+		return &CLIError{
+			Code:       "E00",
+			Title:      "Conflicting Flags",
+			Context:    "--style and --style-file",
+			Detail:     "The --style and --style-file flags cannot be used together.",
+			Suggestion: `Use --style-file alone; a JSON template can name a base preset with "preset".`,
+			ExitCode:   ExitGeneral,
+		}
+	}
+
 	// If MKV path is provided, validate it.
 	if cfg.MKVPath != "" {
 		// Check file exists.
@@ -86,9 +217,15 @@ func ValidateConfig(cfg Config) *CLIError {
 			return ErrCannotReadFile(cfg.MKVPath, fmt.Errorf("path is a directory, not a file"))
 		}
 
-		// Check .mkv extension (case-insensitive).
-		if !strings.HasSuffix(strings.ToLower(cfg.MKVPath), ".mkv") {
-			return ErrNotMKVFile(cfg.MKVPath)
+		// Check .mkv extension (case-insensitive). ASS/SSA scripts are
+		// accepted for standalone resampling.
+		standalone := cfg.ResampleTo != "" && IsScriptPath(cfg.MKVPath)
+		if !standalone && !strings.HasSuffix(strings.ToLower(cfg.MKVPath), ".mkv") {
+			cliErr := ErrNotMKVFile(cfg.MKVPath)
+			if IsScriptPath(cfg.MKVPath) {
+				cliErr.Suggestion = "To resample an ASS/SSA script, pass --resample-to WIDTHxHEIGHT."
+			}
+			return cliErr
 		}
 
 		// Check file is readable by attempting to open it.
@@ -165,10 +165,18 @@ func SelectTracks(tracks []mkvinfo.SubtitleTrack) ([]mkvinfo.SubtitleTrack, erro
 }
 
 // formatTrackOption formats a SubtitleTrack as a concise menu label.
-// Format: [{Index}] {LanguageName} ({FormatType}) "Name"
-// The optional Name suffix is included only when non-empty.
+// Format: [{Index}] {LanguageName} [{Chinese}] ({FormatType}) "Name"
+// The detected written form of a Chinese track and the Name suffix are
+// included only when known.
 func formatTrackOption(t mkvinfo.SubtitleTrack) string {
-	label := fmt.Sprintf("[%d] %s (%s)", t.Index, t.LanguageName, t.FormatType)
+	label := fmt.Sprintf("[%d] %s", t.Index, t.LanguageName)
+	if detected := t.Detected.Label(); detected != "" {
+		label += fmt.Sprintf(" [%s]", detected)
+	}
+	if chinese := t.Chinese.Label(); chinese != "" {
+		label += fmt.Sprintf(" [%s]", chinese)
+	}
+	label += fmt.Sprintf(" (%s)", t.FormatType)
 	if t.Name != "" {
 		label += fmt.Sprintf(" %q", t.Name)
 	}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"mkv-sub-extractor/pkg/assout"
+	"mkv-sub-extractor/pkg/corpus"
+	"mkv-sub-extractor/pkg/extract"
+)
+
+// flagUse is a flag as given on the command line.
+type flagUse struct {
+	name, value string
+	set         bool
+}
+
+// requireFlag rejects the first of uses that was given without the flag it
+// depends on.
+func requireFlag(dep string, uses ...flagUse) *CLIError {
+	for _, f := range uses {
+		if f.set {
+			return ErrInvalidValue(f.name, f.value, fmt.Errorf("requires %s", dep))
+		}
+	}
+	return nil
+}
+
+// pairModeConflicts rejects the flags that cannot be combined with a mode
+// taking the selected tracks in pairs (--bilingual, --pairs).
+func pairModeConflicts(cfg Config, mode string) *CLIError {
+	for _, f := range []flagUse{
+		{"--split-chapters", cfg.Chapters, cfg.Chapters != ""},
+		{"--join", strings.Join(cfg.Join, ","), len(cfg.Join) > 0},
+		{"--ordered-chapters", "true", cfg.Ordered},
+	} {
+		if f.set {
+			return ErrInvalidValue(f.name, f.value, fmt.Errorf("cannot be combined with %s", mode))
+		}
+	}
+	return nil
+}
+
+// bilingualLayout sets the bilingual options from --bilingual and the flags
+// that lay out its two tracks, which require it.
+func bilingualLayout(cfg Config, opts *extract.Options) *CLIError {
+	if !cfg.Bilingual {
+		return requireFlag("--bilingual",
+			flagUse{"--bilingual-fonts", cfg.BiFonts, cfg.BiFonts != ""},
+			flagUse{"--secondary-scale", fmt.Sprint(cfg.SecScale), cfg.SecScale != 0},
+			flagUse{"--secondary-above", "true", cfg.SecAbove},
+			flagUse{"--stack", "true", cfg.Stack},
+		)
+	}
+	if cliErr := pairModeConflicts(cfg, "--bilingual"); cliErr != nil {
+		return cliErr
+	}
+	if cfg.SecScale < 0 {
+		return ErrInvalidValue("--secondary-scale", fmt.Sprint(cfg.SecScale), fmt.Errorf("must be positive"))
+	}
+
+	layout := assout.BilingualOptions{
+		SecondaryScale: cfg.SecScale,
+		SecondaryAbove: cfg.SecAbove,
+		Stack:          cfg.Stack,
+	}
+	if cfg.BiFonts != "" {
+		primary, secondary, ok := strings.Cut(cfg.BiFonts, ",")
+		if !ok || strings.Contains(secondary, ",") {
+			return ErrInvalidValue("--bilingual-fonts", cfg.BiFonts, fmt.Errorf("expected PRIMARY,SECONDARY font names; either may be empty"))
+		}
+		layout.PrimaryFont.Fontname = strings.TrimSpace(primary)
+		layout.SecondaryFont.Fontname = strings.TrimSpace(secondary)
+	}
+	opts.Bilingual, opts.BilingualLayout = true, layout
+	return nil
+}
+
+// pairsOptions sets the sentence pair export options from --pairs and the
+// flags tuning it, which require it.
+func pairsOptions(cfg Config, opts *extract.Options) *CLIError {
+	if cfg.Pairs == "" {
+		return requireFlag("--pairs",
+			flagUse{"--pairs-context", "true", cfg.PairsCtx},
+			flagUse{"--align-tolerance", cfg.AlignTol, cfg.AlignTol != ""},
+			flagUse{"--min-overlap", fmt.Sprint(cfg.MinOverlap), cfg.MinOverlap != 0},
+		)
+	}
+	if cfg.Bilingual {
+		return ErrInvalidValue("--pairs", cfg.Pairs, fmt.Errorf("cannot be combined with --bilingual"))
+	}
+	if cliErr := pairModeConflicts(cfg, "--pairs"); cliErr != nil {
+		return cliErr
+	}
+
+	format, err := corpus.ParseFormat(cfg.Pairs)
+	if err != nil {
+		return ErrInvalidValue("--pairs", cfg.Pairs, err)
+	}
+	po := &extract.PairsOptions{Format: format, Context: cfg.PairsCtx}
+	if cfg.AlignTol != "" {
+		d, err := parseTimeFlag(cfg.AlignTol)
+		if err != nil {
+			return ErrInvalidValue("--align-tolerance", cfg.AlignTol, err)
+		}
+		po.Align.Tolerance = d
+	}
+	if cfg.MinOverlap < 0 || cfg.MinOverlap > 1 {
+		return ErrInvalidValue("--min-overlap", fmt.Sprint(cfg.MinOverlap), fmt.Errorf("must be between 0 and 1"))
+	}
+	po.Align.MinOverlap = cfg.MinOverlap
+	opts.Pairs = po
+	return nil
+}
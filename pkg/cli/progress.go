@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"fmt"
+
 	"mkv-sub-extractor/pkg/extract"
 	"mkv-sub-extractor/pkg/mkvinfo"
 
@@ -11,9 +13,14 @@ import (
 // Used for continue-on-failure extraction where each track is attempted
 // independently and all results are reported at the end.
 type TrackResult struct {
-	Track      mkvinfo.SubtitleTrack
-	OutputPath string
-	Error      error
+	Track       mkvinfo.SubtitleTrack
+	OutputPath  string
+	Diagnostics []extract.Diagnostic         // malformed packets repaired or skipped
+	Timestamps  *extract.TimestampCorrection // bit-encoded timestamps that were corrected
+	Filled      []extract.FilledPacket       // packets whose missing end time was filled
+	Missing     []string                     // linked segment UIDs not found for ordered chapters
+	Transcoded  *extract.Transcoding         // text decoded from a legacy encoding
+	Error       error
 }
 
 // ExtractWithProgress extracts multiple subtitle tracks from an MKV file,
@@ -21,11 +28,16 @@ type TrackResult struct {
 // independently — if one fails, extraction continues for remaining tracks.
 //
 // A shared existingPaths map is used across all tracks to ensure collision-aware
-// output naming (e.g., two "chi" tracks get distinct filenames).
+// output naming (e.g., two "chi" tracks get distinct filenames), and Chinese
+// tracks that would share a name are named by their written form (see
+// extract.ChineseOptions.TagSharedNames).
 //
-// Returns a TrackResult for every track in the same order as the input slice.
-func ExtractWithProgress(mkvPath string, tracks []mkvinfo.SubtitleTrack, outputDir string, quiet bool) []TrackResult {
-	results := make([]TrackResult, len(tracks))
+// Returns a TrackResult for every track in the same order as the input slice,
+// or with opts.SplitChapters, one for every chapter file written. With
+// opts.Bilingual the tracks are taken in pairs, primary first, and each pair
+// gets one TrackResult under its primary track.
+func ExtractWithProgress(mkvPath string, tracks []mkvinfo.SubtitleTrack, outputDir string, quiet bool, opts extract.Options) []TrackResult {
+	results := make([]TrackResult, 0, len(tracks))
 	existingPaths := make(map[string]bool)
 
 	var bar *progressbar.ProgressBar
@@ -38,16 +50,33 @@ func ExtractWithProgress(mkvPath string, tracks []mkvinfo.SubtitleTrack, outputD
 		)
 	}
 
-	for i, track := range tracks {
-		outputPath, err := extract.ExtractTrackToASSShared(mkvPath, track, outputDir, existingPaths)
-		results[i] = TrackResult{
-			Track:      track,
-			OutputPath: outputPath,
-			Error:      err,
+	paired := opts.Bilingual || opts.Pairs != nil
+	step := 1
+	if paired {
+		step = 2
+	} else {
+		opts.Chinese.TagSharedNames(tracks)
+	}
+	for i := 0; i < len(tracks); i += step {
+		track := tracks[i]
+		switch {
+		case paired && i+1 == len(tracks):
+			results = append(results, trackResult(track, nil, fmt.Errorf("tracks are taken in pairs: no second track for this one")))
+		case opts.Pairs != nil:
+			res, err := extract.ExtractPairs(mkvPath, track, tracks[i+1], outputDir, existingPaths, opts)
+			results = append(results, trackResult(track, res, err))
+		case opts.Bilingual:
+			res, err := extract.ExtractBilingual(mkvPath, track, tracks[i+1], outputDir, existingPaths, opts)
+			results = append(results, trackResult(track, res, err))
+		case opts.SplitChapters:
+			results = append(results, extractChapters(mkvPath, track, outputDir, existingPaths, opts)...)
+		default:
+			res, err := extract.ExtractTrack(mkvPath, track, outputDir, existingPaths, opts)
+			results = append(results, trackResult(track, res, err))
 		}
 
 		if bar != nil {
-			_ = bar.Add(1)
+			_ = bar.Add(min(step, len(tracks)-i))
 		}
 	}
 
@@ -57,3 +86,34 @@ func ExtractWithProgress(mkvPath string, tracks []mkvinfo.SubtitleTrack, outputD
 
 	return results
 }
+
+// extractChapters splits a track by chapter, returning a TrackResult per
+// file written, followed by one for the error if a chapter failed.
+func extractChapters(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, opts extract.Options) []TrackResult {
+	parts, err := extract.ExtractTrackChapters(mkvPath, track, outputDir, existingPaths, opts)
+	results := make([]TrackResult, 0, len(parts)+1)
+	for _, res := range parts {
+		results = append(results, trackResult(track, res, nil))
+	}
+	if err != nil {
+		results = append(results, trackResult(track, nil, err))
+	}
+	return results
+}
+
+// trackResult builds the TrackResult for an extraction's outcome.
+func trackResult(track mkvinfo.SubtitleTrack, res *extract.Result, err error) TrackResult {
+	r := TrackResult{
+		Track: track,
+		Error: err,
+	}
+	if err == nil {
+		r.OutputPath = res.OutputPath
+		r.Diagnostics = res.Diagnostics
+		r.Timestamps = res.Timestamps
+		r.Filled = res.Filled
+		r.Missing = res.MissingSegments
+		r.Transcoded = res.Transcoded
+	}
+	return r
+}
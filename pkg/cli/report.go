@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"mkv-sub-extractor/pkg/ass"
+	"mkv-sub-extractor/pkg/extract"
+)
+
+// Report is the JSON document written by --report: one entry per attempted
+// track, with the packet diagnostics of lenient extraction, any timestamp
+// correction and the packets whose end times were gap-filled.
+type Report struct {
+	File   string        `json:"file"`
+	Strict bool          `json:"strict"`
+	Tracks []TrackReport `json:"tracks"`
+}
+
+// TrackReport is the outcome of one track in a Report.
+type TrackReport struct {
+	Index       int                  `json:"index"`
+	Number      uint8                `json:"track_number"`
+	Language    string               `json:"language,omitempty"`
+	Chinese     string               `json:"chinese,omitempty"` // detected written form, e.g. "zh-Hant"
+	Detected    string               `json:"detected_language,omitempty"`
+	Confidence  float64              `json:"detected_confidence,omitempty"`
+	CodecID     string               `json:"codec_id"`
+	OutputPath  string               `json:"output,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	Diagnostics []extract.Diagnostic `json:"diagnostics"`
+
+	TimestampCorrection *extract.TimestampCorrection `json:"timestamp_correction,omitempty"`
+	Filled              []extract.FilledPacket       `json:"filled,omitempty"`
+	MissingSegments     []string                     `json:"missing_segments,omitempty"`
+	Transcoded          *extract.Transcoding         `json:"transcoded,omitempty"`
+}
+
+// writeReport writes the JSON extraction report for results to path.
+func writeReport(path, mkvPath string, strict bool, results []TrackResult) error {
+	report := Report{File: mkvPath, Strict: strict, Tracks: make([]TrackReport, len(results))}
+	for i, r := range results {
+		tr := TrackReport{
+			Index:       r.Track.Index,
+			Number:      r.Track.Number,
+			Language:    r.Track.Language,
+			Chinese:     r.Track.Chinese.Tag(),
+			Detected:    r.Track.Detected.Code,
+			Confidence:  r.Track.Detected.Confidence,
+			CodecID:     r.Track.CodecID,
+			OutputPath:  r.OutputPath,
+			Diagnostics: r.Diagnostics,
+
+			TimestampCorrection: r.Timestamps,
+			Filled:              r.Filled,
+			MissingSegments:     r.Missing,
+			Transcoded:          r.Transcoded,
+		}
+		if tr.Diagnostics == nil {
+			tr.Diagnostics = []extract.Diagnostic{}
+		}
+		if r.Error != nil {
+			tr.Error = r.Error.Error()
+		}
+		report.Tracks[i] = tr
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+// finishExtraction writes the --timecodes, --keyframes and --report files,
+// if requested, and prints the packet diagnostics in verbose mode. A failure
+// to write them is returned as a CLIError.
+func finishExtraction(cfg Config, mkvPath string, results []TrackResult) *CLIError {
+	if cfg.Verbose {
+		printDiagnostics(results)
+	}
+	if cliErr := exportVideoTiming(cfg, mkvPath); cliErr != nil {
+		return cliErr
+	}
+	if cfg.ReportPath == "" {
+		return nil
+	}
+	if err := writeReport(cfg.ReportPath, mkvPath, cfg.Strict, results); err != nil {
+		return ErrCannotWriteReport(cfg.ReportPath, err)
+	}
+	return nil
+}
+
+// printDiagnostics lists, per track, the malformed packets repaired or
+// skipped and the packets whose end times were gap-filled.
+func printDiagnostics(results []TrackResult) {
+	for _, r := range results {
+		if len(r.Diagnostics) > 0 {
+			fmt.Println()
+			fmt.Println(boldStyle.Render(fmt.Sprintf("Track [%d]: %d malformed packet(s)", r.Track.Index, len(r.Diagnostics))))
+			for _, d := range r.Diagnostics {
+				fmt.Println(dimStyle.Render("  " + d.String()))
+			}
+		}
+		if len(r.Filled) > 0 {
+			fmt.Println()
+			fmt.Println(boldStyle.Render(fmt.Sprintf("Track [%d]: %d end time(s) gap-filled", r.Track.Index, len(r.Filled))))
+			for _, f := range r.Filled {
+				fmt.Println(dimStyle.Render(fmt.Sprintf("  packet %d: %s --> %s",
+					f.PacketIndex, ass.FormatTime(f.Start), ass.FormatTime(f.End))))
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mkv-sub-extractor/pkg/subtitle"
+	"mkv-sub-extractor/pkg/vfr"
+)
+
+// ntscRates maps the rounded NTSC frame rates people type to their exact
+// values, so "23.976" means 24000/1001.
+var ntscRates = map[string]float64{
+	"23.976": 24000.0 / 1001,
+	"29.97":  30000.0 / 1001,
+	"47.952": 48000.0 / 1001,
+	"59.94":  60000.0 / 1001,
+	"119.88": 120000.0 / 1001,
+}
+
+// retime builds the timing transform from --sync, --scale, --fps and --shift,
+// applied in that order.
+func retime(cfg Config) (subtitle.Retime, *CLIError) {
+	var r subtitle.Retime
+
+	if cfg.Sync != "" {
+		sync, err := parseSync(cfg.Sync)
+		if err != nil {
+			return r, ErrInvalidValue("--sync", cfg.Sync, err)
+		}
+		r = r.Then(sync)
+	}
+	if cfg.Scale != 0 {
+		if cfg.Scale < 0 {
+			return r, ErrInvalidValue("--scale", fmt.Sprint(cfg.Scale), fmt.Errorf("ratio must be positive"))
+		}
+		r = r.Then(subtitle.ScaleBy(cfg.Scale))
+	}
+	if cfg.FPS != "" {
+		from, to, ok := strings.Cut(cfg.FPS, ":")
+		src, err1 := parseFPS(from)
+		dst, err2 := parseFPS(to)
+		if !ok || err1 != nil || err2 != nil {
+			return r, ErrInvalidValue("--fps", cfg.FPS, fmt.Errorf("expected FROM:TO frame rates, e.g. 23.976:25"))
+		}
+		r = r.Then(subtitle.FPS(src, dst))
+	}
+	if cfg.Shift != "" {
+		d, err := parseShift(cfg.Shift)
+		if err != nil {
+			return r, ErrInvalidValue("--shift", cfg.Shift, err)
+		}
+		r = r.Then(subtitle.Retime{Scale: 1, Offset: d})
+	}
+	return r, nil
+}
+
+// timingOptions builds the timing post-processor options from --lead-in,
+// --lead-out, --link-gap, --link-bias and --snap-keyframes.
+func timingOptions(cfg Config) (vfr.TimingOptions, *CLIError) {
+	var opts vfr.TimingOptions
+
+	durations := []struct {
+		flag, value string
+		dst         *uint64
+	}{
+		{"--lead-in", cfg.LeadIn, &opts.LeadIn},
+		{"--lead-out", cfg.LeadOut, &opts.LeadOut},
+		{"--link-gap", cfg.LinkGap, &opts.LinkGap},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		t, err := parseTimeFlag(d.value)
+		if err != nil {
+			return opts, ErrInvalidValue(d.flag, d.value, err)
+		}
+		*d.dst = t
+	}
+
+	if cfg.LinkBias < 0 || cfg.LinkBias > 1 {
+		return opts, ErrInvalidValue("--link-bias", fmt.Sprint(cfg.LinkBias), fmt.Errorf("must be between 0 and 1"))
+	}
+	opts.LinkBias = cfg.LinkBias
+
+	if cfg.SnapKeys != "" {
+		snap, err := vfr.ParseKeyframeSnap(cfg.SnapKeys)
+		if err != nil {
+			return opts, ErrInvalidValue("--snap-keyframes", cfg.SnapKeys, err)
+		}
+		opts.Keyframes = &snap
+	}
+	return opts, nil
+}
+
+// parseFPS parses a frame rate as a decimal ("25", "23.976") or a fraction
+// ("24000/1001").
+func parseFPS(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if v, ok := ntscRates[s]; ok {
+		return v, nil
+	}
+	num, den, isFraction := strings.Cut(s, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	d := 1.0
+	if err == nil && isFraction {
+		d, err = strconv.ParseFloat(den, 64)
+	}
+	if err != nil || n <= 0 || d <= 0 {
+		return 0, fmt.Errorf("invalid frame rate %q", s)
+	}
+	return n / d, nil
+}
+
+// parseShift parses a signed time offset such as "-1.5s", "+2s" or
+// "-0:01.500", returning nanoseconds.
+func parseShift(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	sign := 1.0
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	t, err := parseTimeFlag(s)
+	if err != nil {
+		return 0, err
+	}
+	return sign * float64(t), nil
+}
+
+// parseSync parses "SRC1=DST1,SRC2=DST2" into a two-point sync.
+func parseSync(s string) (subtitle.Retime, error) {
+	pairs := strings.Split(s, ",")
+	if len(pairs) != 2 {
+		return subtitle.Retime{}, fmt.Errorf("expected two SOURCE=TARGET pairs, e.g. 0:01:00=0:01:02,0:20:00=0:20:10")
+	}
+	var times [4]uint64
+	for i, pair := range pairs {
+		src, dst, ok := strings.Cut(pair, "=")
+		if !ok {
+			return subtitle.Retime{}, fmt.Errorf("expected SOURCE=TARGET, got %q", pair)
+		}
+		var err error
+		if times[2*i], err = parseTimeFlag(src); err != nil {
+			return subtitle.Retime{}, err
+		}
+		if times[2*i+1], err = parseTimeFlag(dst); err != nil {
+			return subtitle.Retime{}, err
+		}
+	}
+	return subtitle.TwoPointSync(times[0], times[1], times[2], times[3])
+}
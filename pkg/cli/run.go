@@ -4,17 +4,28 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/text/encoding"
 
+	"mkv-sub-extractor/pkg/ass"
+	"mkv-sub-extractor/pkg/assout"
+	"mkv-sub-extractor/pkg/extract"
 	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/output"
+	"mkv-sub-extractor/pkg/textenc"
+	"mkv-sub-extractor/pkg/zhconv"
 )
 
 // Lipgloss styles for the completion summary.
 var (
 	successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10")) // green
 	failStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))  // red
+	warnStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("11")) // yellow
 	dimStyle     = lipgloss.NewStyle().Faint(true)
 	boldStyle    = lipgloss.NewStyle().Bold(true)
 )
@@ -30,16 +41,362 @@ func Run() int {
 		return err.ExitCode
 	}
 
-	// Dispatch based on mode: --track present means scriptable, otherwise interactive.
-	if len(cfg.TrackNumbers) > 0 {
-		return runScriptable(cfg)
+	opts, cliErr := extractOptions(cfg)
+	if cliErr != nil {
+		fmt.Fprintln(os.Stderr, cliErr.Format())
+		return cliErr.ExitCode
+	}
+
+	// Dispatch based on mode: an ASS/SSA path means standalone resampling,
+	// --track or --lang present means scriptable, otherwise interactive.
+	if IsScriptPath(cfg.MKVPath) {
+		return runResample(cfg, opts)
+	}
+	if len(cfg.TrackNumbers) > 0 || len(cfg.Languages) > 0 {
+		return runScriptable(cfg, opts)
+	}
+	return runInteractive(cfg, opts)
+}
+
+// extractOptions builds the extraction options from the parsed flags,
+// loading the SRT-to-ASS style template if one was requested.
+func extractOptions(cfg Config) (extract.Options, *CLIError) {
+	opts := extract.Options{
+		KeepPlayRes:  cfg.KeepPlayRes,
+		ResampleASS:  cfg.ResampleASS,
+		Lenient:      !cfg.Strict,
+		SnapToFrames: cfg.SnapFrames,
+	}
+
+	if cfg.ResampleTo != "" {
+		res, err := assout.ParseResolution(cfg.ResampleTo)
+		if err != nil {
+			return opts, ErrInvalidValue("--resample-to", cfg.ResampleTo, err)
+		}
+		opts.ResampleTo = res
+	}
+	aspect, err := assout.ParseAspectMode(cfg.Aspect)
+	if err != nil {
+		return opts, ErrInvalidValue("--aspect", cfg.Aspect, err)
+	}
+	opts.Aspect = aspect
+
+	policy, cliErr := gapFillPolicy(cfg)
+	if cliErr != nil {
+		return opts, cliErr
+	}
+	opts.GapFill = policy
+	opts.GapFillToSegmentEnd = cfg.GapToEnd
+
+	r, cliErr := timeRange(cfg)
+	if cliErr != nil {
+		return opts, cliErr
+	}
+	opts.Range = r
+
+	opts.OrderedChapters = cfg.Ordered
+	if cfg.Chapters != "" {
+		if cfg.Ordered {
+			return opts, ErrInvalidValue("--split-chapters", cfg.Chapters, fmt.Errorf("cannot be combined with --ordered-chapters"))
+		}
+		if !r.IsZero() {
+			return opts, ErrInvalidValue("--split-chapters", cfg.Chapters, fmt.Errorf("cannot be combined with --from/--to"))
+		}
+		opts.SplitChapters = true
+		if cfg.Chapters != "all" {
+			ranges, err := extract.ParseChapterRanges(cfg.Chapters)
+			if err != nil {
+				return opts, ErrInvalidValue("--split-chapters", cfg.Chapters, err)
+			}
+			opts.ChapterRanges = ranges
+		}
+	}
+
+	if len(cfg.Join) > 0 {
+		if cliErr := joinParts(cfg); cliErr != nil {
+			return opts, cliErr
+		}
+		opts.JoinParts = cfg.Join
+	}
+
+	if cliErr := bilingualLayout(cfg, &opts); cliErr != nil {
+		return opts, cliErr
+	}
+	if cliErr := pairsOptions(cfg, &opts); cliErr != nil {
+		return opts, cliErr
+	}
+
+	rt, cliErr := retime(cfg)
+	if cliErr != nil {
+		return opts, cliErr
+	}
+	opts.Retime = rt
+
+	timing, cliErr := timingOptions(cfg)
+	if cliErr != nil {
+		return opts, cliErr
+	}
+	opts.Timing = timing
+
+	switch {
+	case cfg.StylePreset != "":
+		tmpl, err := assout.LookupStylePreset(cfg.StylePreset)
+		if err != nil {
+			return opts, ErrInvalidStyle(cfg.StylePreset, err)
+		}
+		opts.StyleTemplate = &tmpl
+	case cfg.StyleFile != "":
+		tmpl, err := assout.LoadStyleTemplate(cfg.StyleFile)
+		if err != nil {
+			return opts, ErrInvalidStyle(cfg.StyleFile, err)
+		}
+		opts.StyleTemplate = &tmpl
+	}
+
+	switch {
+	case cfg.LangFontFile != "":
+		fonts, err := assout.LoadLanguageFonts(cfg.LangFontFile)
+		if err != nil {
+			return opts, ErrInvalidStyle(cfg.LangFontFile, err)
+		}
+		opts.LanguageFonts = fonts
+	case cfg.LangFonts:
+		opts.LanguageFonts = assout.DefaultLanguageFonts()
 	}
-	return runInteractive(cfg)
+
+	chinese, cliErr := chineseOptions(cfg)
+	if cliErr != nil {
+		return opts, cliErr
+	}
+	opts.Chinese = chinese
+
+	enc, cliErr := encodingOptions(cfg)
+	if cliErr != nil {
+		return opts, cliErr
+	}
+	opts.Encoding = enc
+
+	return opts, nil
+}
+
+// encodingOptions builds the text encoding options from --input-encoding, a
+// comma-separated list of an encoding for every track that is not valid
+// UTF-8 and N=ENCODING entries for track N.
+func encodingOptions(cfg Config) (extract.EncodingOptions, *CLIError) {
+	var opts extract.EncodingOptions
+	if cfg.Encoding == "" {
+		return opts, nil
+	}
+
+	for _, entry := range strings.Split(cfg.Encoding, ",") {
+		num, name, perTrack := strings.Cut(entry, "=")
+		if !perTrack {
+			name = num
+		}
+		enc, err := textenc.Lookup(name)
+		if err != nil {
+			return opts, ErrInvalidValue("--input-encoding", cfg.Encoding, err)
+		}
+		if !perTrack {
+			opts.Default = enc
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil || n <= 0 {
+			return opts, ErrInvalidValue("--input-encoding", cfg.Encoding, fmt.Errorf("invalid track number %q", num))
+		}
+		if opts.Tracks == nil {
+			opts.Tracks = make(map[int]encoding.Encoding)
+		}
+		opts.Tracks[n] = enc
+	}
+	return opts, nil
+}
+
+// chineseOptions builds the Chinese conversion options from --zh-convert, a
+// comma-separated list of a variant for every Chinese track and N=VARIANT
+// entries for track N, and --zh-fonts.
+func chineseOptions(cfg Config) (extract.ChineseOptions, *CLIError) {
+	opts := extract.ChineseOptions{Fonts: cfg.ZhFonts}
+	if cfg.ZhConvert == "" {
+		if cfg.ZhFonts {
+			return opts, ErrInvalidValue("--zh-fonts", "true", fmt.Errorf("requires --zh-convert"))
+		}
+		return opts, nil
+	}
+
+	for _, entry := range strings.Split(cfg.ZhConvert, ",") {
+		num, name, perTrack := strings.Cut(entry, "=")
+		if !perTrack {
+			name = num
+		}
+		v, err := zhconv.ParseVariant(name)
+		if err != nil {
+			return opts, ErrInvalidValue("--zh-convert", cfg.ZhConvert, err)
+		}
+		if !perTrack {
+			opts.Variant = v
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil || n <= 0 {
+			return opts, ErrInvalidValue("--zh-convert", cfg.ZhConvert, fmt.Errorf("invalid track number %q", num))
+		}
+		if opts.Tracks == nil {
+			opts.Tracks = make(map[int]zhconv.Variant)
+		}
+		opts.Tracks[n] = v
+	}
+	return opts, nil
+}
+
+// gapFillPolicy builds the gap-fill policy from the --gap-* flags.
+func gapFillPolicy(cfg Config) (extract.GapFillPolicy, *CLIError) {
+	var policy extract.GapFillPolicy
+
+	mode, err := extract.ParseGapFillMode(cfg.GapFill)
+	if err != nil {
+		return policy, ErrInvalidValue("--gap-fill", cfg.GapFill, err)
+	}
+	policy.Mode = mode
+
+	durations := []struct {
+		flag, value string
+		dst         *uint64
+	}{
+		{"--gap-duration", cfg.GapDuration, &policy.Duration},
+		{"--gap-min", cfg.GapMin, &policy.MinDuration},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err == nil && v <= 0 {
+			err = fmt.Errorf("duration must be positive")
+		}
+		if err != nil {
+			return policy, ErrInvalidValue(d.flag, d.value, err)
+		}
+		*d.dst = uint64(v)
+	}
+
+	if cfg.GapCPS < 0 {
+		return policy, ErrInvalidValue("--gap-cps", fmt.Sprint(cfg.GapCPS), fmt.Errorf("reading speed must be positive"))
+	}
+	policy.CPS = cfg.GapCPS
+
+	return policy, nil
+}
+
+// timeRange builds the extraction time range from --from, --to, --clamp,
+// --rebase and --no-seek.
+func timeRange(cfg Config) (extract.TimeRange, *CLIError) {
+	r := extract.TimeRange{Clamp: cfg.Clamp, Rebase: cfg.Rebase, NoSeek: cfg.NoSeek}
+
+	bounds := []struct {
+		flag, value string
+		dst         *uint64
+	}{
+		{"--from", cfg.From, &r.From},
+		{"--to", cfg.To, &r.To},
+	}
+	for _, b := range bounds {
+		if b.value == "" {
+			continue
+		}
+		t, err := parseTimeFlag(b.value)
+		if err != nil {
+			return r, ErrInvalidValue(b.flag, b.value, err)
+		}
+		*b.dst = t
+	}
+
+	if r.To != 0 && r.To <= r.From {
+		return r, ErrInvalidValue("--to", cfg.To, fmt.Errorf("must be after --from"))
+	}
+	return r, nil
+}
+
+// joinParts checks --join: the parts must be readable MKV files, joined onto
+// an MKV file given on the command line, and the flag rules out the options
+// that pick a part of one file.
+func joinParts(cfg Config) *CLIError {
+	value := strings.Join(cfg.Join, ",")
+	switch {
+	case cfg.MKVPath == "" || IsScriptPath(cfg.MKVPath):
+		return ErrInvalidValue("--join", value, fmt.Errorf("requires the first part as FILE.mkv"))
+	case cfg.Ordered:
+		return ErrInvalidValue("--join", value, fmt.Errorf("cannot be combined with --ordered-chapters"))
+	case cfg.Chapters != "":
+		return ErrInvalidValue("--join", value, fmt.Errorf("cannot be combined with --split-chapters"))
+	case cfg.From != "" || cfg.To != "":
+		return ErrInvalidValue("--join", value, fmt.Errorf("cannot be combined with --from/--to"))
+	}
+	for _, path := range cfg.Join {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return ErrFileNotFound(path)
+		}
+		if err != nil {
+			return ErrCannotReadFile(path, err)
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".mkv") {
+			return ErrNotMKVFile(path)
+		}
+	}
+	return nil
+}
+
+// parseTimeFlag parses a time given as a Go duration ("90s", "1h2m"), as
+// [[H:]MM:]SS[.fff] with "." or "," before the fraction, or as seconds.
+func parseTimeFlag(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return 0, fmt.Errorf("time must not be negative")
+		}
+		return uint64(d), nil
+	}
+
+	ts := strings.Replace(s, ",", ".", 1)
+	switch strings.Count(ts, ":") {
+	case 0:
+		ts = "0:0:" + ts
+	case 1:
+		ts = "0:" + ts
+	}
+	t, err := ass.ParseTime(ts)
+	if err != nil {
+		return 0, fmt.Errorf("expected a time like 1:02:03.5, 62:03, 3723.5 or 1h2m3s")
+	}
+	return t, nil
+}
+
+// runResample handles standalone resampling of an ASS/SSA script given as the
+// positional argument with --resample-to.
+func runResample(cfg Config, opts extract.Options) int {
+	outPath := output.ResampledPath(cfg.MKVPath, cfg.OutputDir, opts.ResampleTo.String())
+
+	err := assout.ResampleFile(cfg.MKVPath, outPath, opts.ResampleTo, assout.ResampleOptions{Aspect: opts.Aspect})
+	if err != nil {
+		cliErr := ErrCannotReadFile(cfg.MKVPath, err)
+		fmt.Fprintln(os.Stderr, cliErr.Format())
+		return cliErr.ExitCode
+	}
+
+	if cfg.Quiet {
+		fmt.Println(outPath)
+		return 0
+	}
+	fmt.Println(successStyle.Render(fmt.Sprintf("Resampled %s -> %s (%s)",
+		filepath.Base(cfg.MKVPath), filepath.Base(outPath), opts.ResampleTo)))
+	return 0
 }
 
 // runInteractive handles the interactive mode: file picker -> MKV parse ->
 // track selection -> extraction -> completion summary.
-func runInteractive(cfg Config) int {
+func runInteractive(cfg Config, opts extract.Options) int {
 	mkvPath := cfg.MKVPath
 
 	// If no MKV path provided, present the interactive file picker.
@@ -64,6 +421,8 @@ func runInteractive(cfg Config) int {
 		return cliErr.ExitCode
 	}
 
+	detectText(cfg, opts, mkvPath, result.Tracks)
+
 	// Check for subtitle tracks.
 	if result.Info.SubtitleCount == 0 {
 		cliErr := ErrNoSubtitleTracks(mkvPath)
@@ -93,17 +452,37 @@ func runInteractive(cfg Config) int {
 	}
 
 	// Extract tracks with progress.
-	results := ExtractWithProgress(mkvPath, selectedTracks, cfg.OutputDir, cfg.Quiet)
+	results := ExtractWithProgress(mkvPath, selectedTracks, cfg.OutputDir, cfg.Quiet, opts)
 
 	// Print completion summary.
 	printCompletionSummary(results, cfg.Quiet)
+	if cliErr := finishExtraction(cfg, mkvPath, results); cliErr != nil {
+		fmt.Fprintln(os.Stderr, cliErr.Format())
+		return cliErr.ExitCode
+	}
 
 	// Return exit code based on results.
 	return exitCodeFromResults(results)
 }
 
-// runScriptable handles the non-interactive scriptable mode with --track flag.
-func runScriptable(cfg Config) int {
+// detectText detects the language of the file's untagged tracks and the
+// written form of its Chinese tracks, for the listing, track selection and
+// output names, unless --no-detect is given. Detection is best effort: a
+// file that cannot be read fails extraction anyway, so the error is only
+// shown with --verbose.
+func detectText(cfg Config, opts extract.Options, mkvPath string, tracks []mkvinfo.SubtitleTrack) {
+	if cfg.NoDetect {
+		return
+	}
+	if err := extract.DetectText(mkvPath, tracks, opts.Encoding); err != nil && cfg.Verbose {
+		fmt.Fprintf(os.Stderr, "warning: detect track text: %v\n", err)
+	}
+}
+
+// runScriptable handles the non-interactive scriptable mode with the --track
+// and --lang flags: the tracks listed by --track, then those in the languages
+// of --lang not listed already.
+func runScriptable(cfg Config, opts extract.Options) int {
 	// MKV path is required in scriptable mode.
 	if cfg.MKVPath == "" {
 		cliErr := ErrFileNotFound("(no file specified)")
@@ -121,6 +500,8 @@ func runScriptable(cfg Config) int {
 		return cliErr.ExitCode
 	}
 
+	detectText(cfg, opts, cfg.MKVPath, result.Tracks)
+
 	// Build a lookup of subtitle tracks by display index.
 	trackByIndex := make(map[int]mkvinfo.SubtitleTrack)
 	for _, t := range result.Tracks {
@@ -143,6 +524,18 @@ func runScriptable(cfg Config) int {
 		}
 		resolvedTracks = append(resolvedTracks, track)
 	}
+	for _, lang := range cfg.Languages {
+		matched := languageTracks(result.Tracks, lang)
+		if len(matched) == 0 {
+			validationErrors = append(validationErrors, ErrNoTrackInLanguage(lang, cfg.MKVPath))
+			continue
+		}
+		for _, track := range matched {
+			if !slices.ContainsFunc(resolvedTracks, func(t mkvinfo.SubtitleTrack) bool { return t.Index == track.Index }) {
+				resolvedTracks = append(resolvedTracks, track)
+			}
+		}
+	}
 
 	// If any track validation errors, print all and exit.
 	if len(validationErrors) > 0 {
@@ -169,13 +562,16 @@ func runScriptable(cfg Config) int {
 			if t.Language != "" {
 				fmt.Printf(" lang=%s", t.Language)
 			}
+			if !t.Detected.IsZero() {
+				fmt.Printf(" detected=%s", t.Detected.Code)
+			}
 		}
 		fmt.Println()
 		fmt.Println()
 	}
 
 	// Extract tracks with progress.
-	results := ExtractWithProgress(cfg.MKVPath, resolvedTracks, cfg.OutputDir, cfg.Quiet)
+	results := ExtractWithProgress(cfg.MKVPath, resolvedTracks, cfg.OutputDir, cfg.Quiet, opts)
 
 	// Quiet mode: only print output file paths on stdout.
 	if cfg.Quiet {
@@ -183,16 +579,48 @@ func runScriptable(cfg Config) int {
 			if r.Error == nil {
 				fmt.Println(r.OutputPath)
 			}
+			if r.Timestamps != nil {
+				fmt.Fprintf(os.Stderr, "warning: track %d: %s\n", r.Track.Index, r.Timestamps)
+			}
+			if r.Transcoded != nil {
+				fmt.Fprintf(os.Stderr, "warning: track %d: %s\n", r.Track.Index, r.Transcoded)
+			}
+			if len(r.Missing) > 0 {
+				fmt.Fprintf(os.Stderr, "warning: track %d: %s\n", r.Track.Index, missingSegmentsWarning(r.Missing))
+			}
 		}
-		return exitCodeFromResults(results)
+	} else {
+		// Normal output: print completion summary.
+		printCompletionSummary(results, false)
 	}
 
-	// Normal output: print completion summary.
-	printCompletionSummary(results, false)
+	if cliErr := finishExtraction(cfg, cfg.MKVPath, results); cliErr != nil {
+		fmt.Fprintln(os.Stderr, cliErr.Format())
+		return cliErr.ExitCode
+	}
 
 	return exitCodeFromResults(results)
 }
 
+// languageTracks returns the text tracks whose language, tagged or detected
+// (see mkvinfo.SubtitleTrack.EffectiveLanguage), is lang: an ISO 639 code such
+// as "eng" or "zh", or "zh-Hans" or "zh-Hant" for the Chinese tracks detected
+// as Simplified or Traditional.
+func languageTracks(tracks []mkvinfo.SubtitleTrack, lang string) []mkvinfo.SubtitleTrack {
+	code, script, _ := strings.Cut(strings.TrimSpace(lang), "-")
+	var matched []mkvinfo.SubtitleTrack
+	for _, t := range tracks {
+		if !t.IsExtractable || !mkvinfo.SameLanguage(t.EffectiveLanguage(), code) {
+			continue
+		}
+		if script != "" && !strings.EqualFold(t.Chinese.Script, script) {
+			continue
+		}
+		matched = append(matched, t)
+	}
+	return matched
+}
+
 // printCompletionSummary prints a styled summary of extraction results.
 func printCompletionSummary(results []TrackResult, quiet bool) {
 	if quiet {
@@ -225,7 +653,19 @@ func printCompletionSummary(results []TrackResult, quiet bool) {
 			// Show just the filename, not the full path.
 			outName := filepath.Base(r.OutputPath)
 			line := fmt.Sprintf("%s -> %s", prefix, outName)
+			if n := len(r.Diagnostics); n > 0 {
+				line += fmt.Sprintf(" (%d malformed packet(s) repaired or skipped)", n)
+			}
 			fmt.Println(successStyle.Render(line))
+			if r.Timestamps != nil {
+				fmt.Println(warnStyle.Render("      warning: " + r.Timestamps.String()))
+			}
+			if r.Transcoded != nil {
+				fmt.Println(warnStyle.Render("      warning: " + r.Transcoded.String()))
+			}
+			if len(r.Missing) > 0 {
+				fmt.Println(warnStyle.Render("      warning: " + missingSegmentsWarning(r.Missing)))
+			}
 		} else {
 			line := fmt.Sprintf("%s -> FAILED: %v", prefix, r.Error)
 			fmt.Println(failStyle.Render(line))
@@ -242,6 +682,13 @@ func printCompletionSummary(results []TrackResult, quiet bool) {
 	}
 }
 
+// missingSegmentsWarning describes linked segments that ordered chapters
+// refer to but that were not found next to the file.
+func missingSegmentsWarning(uids []string) string {
+	return fmt.Sprintf("%d linked segment(s) not found, their chapters were skipped: %s",
+		len(uids), strings.Join(uids, ", "))
+}
+
 // exitCodeFromResults returns 0 if all extractions succeeded,
 // ExitExtraction (4) if any failed.
 func exitCodeFromResults(results []TrackResult) int {
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"mkv-sub-extractor/pkg/extract"
+	"mkv-sub-extractor/pkg/output"
+	"mkv-sub-extractor/pkg/vfr"
+)
+
+// exportVideoTiming writes the video track's timecodes v2 file (--timecodes)
+// and keyframes file (--keyframes) next to the subtitles, printing their
+// paths.
+func exportVideoTiming(cfg Config, mkvPath string) *CLIError {
+	if !cfg.Timecodes && !cfg.Keyframes {
+		return nil
+	}
+
+	frames, err := extract.ReadVideoFrames(mkvPath)
+	if err != nil {
+		return ErrVideoTiming(mkvPath, err)
+	}
+
+	files := []struct {
+		enabled bool
+		suffix  string
+		label   string
+		write   func(io.Writer) error
+	}{
+		{cfg.Timecodes, "timecodes.txt", "Timecodes", func(w io.Writer) error {
+			return vfr.WriteTimecodesV2(w, extract.FrameTimes(frames))
+		}},
+		{cfg.Keyframes, "keyframes.txt", "Keyframes", func(w io.Writer) error {
+			return vfr.WriteKeyframes(w, extract.KeyframeIndices(frames))
+		}},
+	}
+	for _, file := range files {
+		if !file.enabled {
+			continue
+		}
+		path := output.SidecarPath(mkvPath, cfg.OutputDir, file.suffix)
+		if err := writeFile(path, file.write); err != nil {
+			return ErrVideoTiming(path, err)
+		}
+		if cfg.Quiet {
+			fmt.Println(path)
+		} else {
+			fmt.Println(successStyle.Render(fmt.Sprintf("  %s (%d frames) -> %s", file.label, len(frames), filepath.Base(path))))
+		}
+	}
+	return nil
+}
+
+// writeFile creates path and fills it with write.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
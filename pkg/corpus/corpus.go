@@ -0,0 +1,143 @@
+// Package corpus writes aligned sentence pairs from two subtitle tracks as
+// CSV or TSV, for parallel corpora, flashcards (Anki) and spreadsheets.
+package corpus
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a delimited text format.
+type Format int
+
+const (
+	CSV Format = iota // comma-separated, RFC 4180 quoting
+	TSV               // tab-separated, with the same quoting
+)
+
+// ParseFormat parses "csv" or "tsv".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "csv":
+		return CSV, nil
+	case "tsv":
+		return TSV, nil
+	}
+	return 0, fmt.Errorf("unknown format %q (available: csv, tsv)", s)
+}
+
+// Ext returns the file extension for the format, without the dot.
+func (f Format) Ext() string {
+	if f == TSV {
+		return "tsv"
+	}
+	return "csv"
+}
+
+// Row is one aligned sentence pair.
+type Row struct {
+	Start, End   uint64 // nanoseconds
+	TextA, TextB string // plain text in each language
+
+	// Context columns, written with Options.Context.
+	Source  string // source file name
+	Episode string // episode number, e.g. "S01E02" or "05"
+	Chapter string // title of the chapter the pair starts in
+}
+
+// Options controls Write.
+type Options struct {
+	Format Format
+
+	// LangA and LangB name the text columns in the header, e.g. "chi" and
+	// "eng"; empty names give "text_a" and "text_b".
+	LangA, LangB string
+
+	// Context adds the source, episode and chapter columns.
+	Context bool
+}
+
+// Write writes rows with a header line: start, end, the two text columns
+// and, with opts.Context, source, episode and chapter. Times are written as
+// H:MM:SS.mmm, which spreadsheets read as durations.
+func Write(w io.Writer, rows []Row, opts Options) error {
+	cw := csv.NewWriter(w)
+	if opts.Format == TSV {
+		cw.Comma = '\t'
+	}
+
+	column := func(lang, fallback string) string {
+		if lang == "" {
+			return fallback
+		}
+		return lang
+	}
+	header := []string{"start", "end", column(opts.LangA, "text_a"), column(opts.LangB, "text_b")}
+	if opts.Context {
+		header = append(header, "source", "episode", "chapter")
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write %s: %w", opts.Format.Ext(), err)
+	}
+
+	for _, r := range rows {
+		record := []string{FormatTime(r.Start), FormatTime(r.End), r.TextA, r.TextB}
+		if opts.Context {
+			record = append(record, r.Source, r.Episode, r.Chapter)
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("write %s: %w", opts.Format.Ext(), err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("write %s: %w", opts.Format.Ext(), err)
+	}
+	return nil
+}
+
+// FormatTime formats nanoseconds as H:MM:SS.mmm.
+func FormatTime(ns uint64) string {
+	d := time.Duration(ns).Round(time.Millisecond)
+	h := d / time.Hour
+	m := d % time.Hour / time.Minute
+	s := d % time.Minute / time.Second
+	ms := d % time.Second / time.Millisecond
+	return fmt.Sprintf("%d:%02d:%02d.%03d", h, m, s, ms)
+}
+
+// Episode patterns, tried in order: S01E02, 1x02, E02/EP02, and a number
+// standing alone after " - " or in brackets, as in "[Group] Show - 05 [1080p]".
+var episodePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bS(\d{1,2})[ ._-]?E(\d{1,4})\b`),
+	regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`),
+	regexp.MustCompile(`(?i)\bEP?[ ._]?(\d{1,4})\b`),
+	regexp.MustCompile(` - (\d{1,4})(?:v\d)?\b`),
+	regexp.MustCompile(`[\[(](\d{1,3})(?:v\d)?[\])]`),
+}
+
+// EpisodeFromPath guesses the episode from a file name: "S01E02" for season
+// and episode numbers (from "S1E2" or "1x02"), or the bare episode number
+// ("05"); "" if the name has none.
+func EpisodeFromPath(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for i, re := range episodePatterns {
+		m := re.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		if i < 2 {
+			season, _ := strconv.Atoi(m[1])
+			episode, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("S%02dE%02d", season, episode)
+		}
+		return m[1]
+	}
+	return ""
+}
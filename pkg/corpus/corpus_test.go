@@ -0,0 +1,63 @@
+package corpus
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	rows := []Row{
+		{Start: 1500 * uint64(time.Millisecond), End: 3 * uint64(time.Second), TextA: "你好", TextB: `Hello, "world"`, Source: "ep01.mkv", Episode: "01", Chapter: "Part A"},
+		{Start: uint64(time.Hour + 2*time.Minute), End: uint64(time.Hour + 2*time.Minute + 5*time.Second), TextA: "再见", TextB: "Bye"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, rows, Options{LangA: "chi", LangB: "eng"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "start,end,chi,eng\n" +
+		"0:00:01.500,0:00:03.000,你好,\"Hello, \"\"world\"\"\"\n" +
+		"1:02:00.000,1:02:05.000,再见,Bye\n"
+	if got := buf.String(); got != want {
+		t.Errorf("CSV:\ngot  %q\nwant %q", got, want)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, rows[:1], Options{Format: TSV, Context: true}); err != nil {
+		t.Fatal(err)
+	}
+	want = "start\tend\ttext_a\ttext_b\tsource\tepisode\tchapter\n" +
+		"0:00:01.500\t0:00:03.000\t你好\t\"Hello, \"\"world\"\"\"\tep01.mkv\t01\tPart A\n"
+	if got := buf.String(); got != want {
+		t.Errorf("TSV:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat(" TSV "); err != nil || f != TSV || f.Ext() != "tsv" {
+		t.Errorf("ParseFormat(TSV) = %v, %v", f, err)
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("ParseFormat(xlsx): expected error")
+	}
+}
+
+func TestEpisodeFromPath(t *testing.T) {
+	tests := map[string]string{
+		"Show.S01E02.1080p.mkv":                  "S01E02",
+		"show.s2e5.mkv":                          "S02E05",
+		"Show 1x03 x264.mkv":                     "S01E03",
+		"Show EP07.mkv":                          "07",
+		"[Group] Show - 05 [1080p].mkv":          "05",
+		"[Group] Show - 12v2 [BD 1920x1080].mkv": "12",
+		"[Group] Show [08][720p].mkv":            "08",
+		"Movie (2019).mkv":                       "",
+		"/videos/part1.mkv":                      "",
+	}
+	for path, want := range tests {
+		if got := EpisodeFromPath(path); got != want {
+			t.Errorf("EpisodeFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// Package ebml reads the EBML structure of Matroska files: element headers,
+// walked without loading payloads, and the payloads of the small elements a
+// caller asks for.
+//
+// matroska-go exposes packets and a flattened view of the metadata; this
+// package serves the places that need the element tree itself, such as
+// block flags, chapter editions and segment UIDs.
+package ebml
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Matroska element IDs used in this module.
+const (
+	IDEBML    = 0x1A45DFA3
+	IDSegment = 0x18538067
+
+	IDInfo          = 0x1549A966
+	IDTimecodeScale = 0x2AD7B1
+	IDDuration      = 0x4489
+	IDSegmentUID    = 0x73A4
+	IDTitle         = 0x7BA9
+
+	IDTracks      = 0x1654AE6B
+	IDTrackEntry  = 0xAE
+	IDTrackNumber = 0xD7
+	IDTrackType   = 0x83
+
+	IDCluster        = 0x1F43B675
+	IDClusterTime    = 0xE7
+	IDSimpleBlock    = 0xA3
+	IDBlockGroup     = 0xA0
+	IDBlock          = 0xA1
+	IDReferenceBlock = 0xFB
+
+	IDChapters           = 0x1043A770
+	IDEditionEntry       = 0x45B9
+	IDEditionUID         = 0x45BC
+	IDEditionFlagHidden  = 0x45BD
+	IDEditionFlagDefault = 0x45DB
+	IDEditionFlagOrdered = 0x45DD
+	IDChapterAtom        = 0xB6
+	IDChapterUID         = 0x73C4
+	IDChapterTimeStart   = 0x91
+	IDChapterTimeEnd     = 0x92
+	IDChapterFlagHidden  = 0x98
+	IDChapterFlagEnabled = 0x4598
+	IDChapterSegmentUID  = 0x6E67
+	IDChapterDisplay     = 0x80
+	IDChapString         = 0x85
+	IDChapLanguage       = 0x437C
+
+	IDSeekHead     = 0x114D9B74
+	IDSeek         = 0x4DBB
+	IDSeekID       = 0x53AB
+	IDSeekPosition = 0x53AC
+
+	IDCues = 0x1C53BB6B
+	IDTags = 0x1254C367
+	IDVoid = 0xEC
+)
+
+// maxPayload bounds the payloads Reader.Data loads into memory (16 MiB).
+const maxPayload = 16 << 20
+
+// UnknownSize is the Size of an element whose size is not coded (live
+// streams leave Segment and Cluster sizes open).
+const UnknownSize = -1
+
+// Element is an element header.
+type Element struct {
+	ID     uint32
+	Size   int64 // payload size in bytes, or UnknownSize
+	Offset int64 // file offset of the payload
+}
+
+// End returns the offset just past the payload, or -1 for an unknown size.
+func (e Element) End() int64 {
+	if e.Size == UnknownSize {
+		return -1
+	}
+	return e.Offset + e.Size
+}
+
+// Reader walks EBML elements in a seekable stream.
+type Reader struct {
+	r   io.ReadSeeker
+	pos int64
+}
+
+// NewReader returns a Reader positioned at the start of r.
+func NewReader(r io.ReadSeeker) *Reader {
+	return &Reader{r: r}
+}
+
+// Pos returns the current offset.
+func (r *Reader) Pos() int64 {
+	return r.pos
+}
+
+// SeekTo moves to offset pos.
+func (r *Reader) SeekTo(pos int64) error {
+	if _, err := r.r.Seek(pos, io.SeekStart); err != nil {
+		return err
+	}
+	r.pos = pos
+	return nil
+}
+
+// Next reads the element header at the current offset, leaving the reader at
+// the start of its payload. It returns io.EOF at the end of the stream.
+func (r *Reader) Next() (Element, error) {
+	id, _, err := r.vint(true)
+	if err != nil {
+		return Element{}, err
+	}
+	size, n, err := r.vint(false)
+	if err != nil {
+		return Element{}, noEOF(err)
+	}
+	e := Element{ID: uint32(id), Size: int64(size), Offset: r.pos}
+	if size == 1<<(7*n)-1 {
+		e.Size = UnknownSize
+	}
+	return e, nil
+}
+
+// Segment skips to the first Segment element and returns its header, leaving
+// the reader at the start of its payload.
+func (r *Reader) Segment() (Element, error) {
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return Element{}, fmt.Errorf("no Segment element")
+		}
+		if err != nil {
+			return Element{}, err
+		}
+		if e.ID == IDSegment {
+			return e, nil
+		}
+		if err := r.Skip(e); err != nil {
+			return Element{}, err
+		}
+	}
+}
+
+// Skip moves past the payload of e. Elements of unknown size cannot be
+// skipped.
+func (r *Reader) Skip(e Element) error {
+	if e.Size == UnknownSize {
+		return fmt.Errorf("element 0x%X has unknown size", e.ID)
+	}
+	return r.SeekTo(e.End())
+}
+
+// Data reads the payload of e, which must be at most 16 MiB.
+func (r *Reader) Data(e Element) ([]byte, error) {
+	if e.Size == UnknownSize || e.Size > maxPayload {
+		return nil, fmt.Errorf("element 0x%X payload too large to read", e.ID)
+	}
+	if err := r.SeekTo(e.Offset); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, e.Size)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return nil, noEOF(err)
+	}
+	r.pos += e.Size
+	return buf, nil
+}
+
+// Head reads up to n bytes from the start of the payload of e, for elements
+// such as blocks whose header is needed but whose body is not.
+func (r *Reader) Head(e Element, n int) ([]byte, error) {
+	if e.Size != UnknownSize && int64(n) > e.Size {
+		n = int(e.Size)
+	}
+	if err := r.SeekTo(e.Offset); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return nil, noEOF(err)
+	}
+	r.pos += int64(n)
+	return buf, nil
+}
+
+// Uint reads an unsigned integer element.
+func (r *Reader) Uint(e Element) (uint64, error) {
+	data, err := r.Data(e)
+	if err != nil {
+		return 0, err
+	}
+	return Uint(data), nil
+}
+
+// Float reads a float element (4 or 8 bytes; empty is 0).
+func (r *Reader) Float(e Element) (float64, error) {
+	data, err := r.Data(e)
+	if err != nil {
+		return 0, err
+	}
+	return Float(data)
+}
+
+// String reads a string or UTF-8 element, dropping trailing NUL padding.
+func (r *Reader) String(e Element) (string, error) {
+	data, err := r.Data(e)
+	if err != nil {
+		return "", err
+	}
+	return String(data), nil
+}
+
+// Children calls fn for each child of parent, with the reader at the child's
+// payload. fn may read or skip the child; the reader moves to the next child
+// either way, except after a child of unknown size, which fn must walk to its
+// end (with Children) itself. For a parent of unknown size, iteration stops at the first
+// element that is not a child, which is one of levelOne (typically the IDs of
+// the parent's siblings); the reader is left at that element's header.
+func (r *Reader) Children(parent Element, levelOne map[uint32]bool, fn func(Element) error) error {
+	end := parent.End()
+	for end < 0 || r.pos < end {
+		start := r.pos
+		child, err := r.Next()
+		if err == io.EOF && end < 0 {
+			return nil
+		}
+		if err != nil {
+			return noEOF(err)
+		}
+		if end < 0 && levelOne[child.ID] {
+			return r.SeekTo(start)
+		}
+		if err := fn(child); err != nil {
+			return err
+		}
+		if child.Size == UnknownSize {
+			continue
+		}
+		if err := r.SeekTo(child.End()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vint reads a variable-length integer. IDs keep their length marker bit;
+// sizes drop it. It returns the value and its length in bytes.
+func (r *Reader) vint(keepMarker bool) (uint64, int, error) {
+	var first [1]byte
+	if _, err := io.ReadFull(r.r, first[:]); err != nil {
+		return 0, 0, err
+	}
+	n := 1
+	for mask := byte(0x80); n <= 8 && first[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 {
+		return 0, 0, fmt.Errorf("invalid EBML variable-length integer at offset %d", r.pos)
+	}
+
+	v := uint64(first[0])
+	if !keepMarker {
+		v &= uint64(0xFF >> n)
+	}
+	rest := make([]byte, n-1)
+	if _, err := io.ReadFull(r.r, rest); err != nil {
+		return 0, 0, noEOF(err)
+	}
+	for _, b := range rest {
+		v = v<<8 | uint64(b)
+	}
+	r.pos += int64(n)
+	return v, n, nil
+}
+
+// noEOF turns an EOF in the middle of an element into io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Uint decodes a big-endian unsigned integer payload.
+func Uint(data []byte) uint64 {
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// Float decodes a float payload.
+func Float(data []byte) (float64, error) {
+	switch len(data) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	}
+	return 0, fmt.Errorf("invalid float size %d", len(data))
+}
+
+// String decodes a string payload, dropping trailing NUL padding.
+func String(data []byte) string {
+	for len(data) > 0 && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+	return string(data)
+}
+
+// Vint decodes a size-style variable-length integer (marker bit dropped) at
+// the start of data, as in block headers. It returns the value and its
+// length, or a length of 0 if data is too short or invalid.
+func Vint(data []byte) (uint64, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	n := 1
+	for mask := byte(0x80); n <= 8 && data[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 || len(data) < n {
+		return 0, 0
+	}
+	v := uint64(data[0]) & uint64(0xFF>>n)
+	for _, b := range data[1:n] {
+		v = v<<8 | uint64(b)
+	}
+	return v, n
+}
@@ -0,0 +1,81 @@
+package ebml
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestNext(t *testing.T) {
+	data := []byte{
+		0x1A, 0x45, 0xDF, 0xA3, 0x81, 0x00, // EBML, size 1
+		0x18, 0x53, 0x80, 0x67, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // Segment, unknown size
+	}
+	r := NewReader(bytes.NewReader(data))
+
+	e, err := r.Next()
+	if err != nil || e.ID != IDEBML || e.Size != 1 || e.Offset != 5 {
+		t.Fatalf("Next() = %+v, %v", e, err)
+	}
+	if err := r.Skip(e); err != nil {
+		t.Fatal(err)
+	}
+	e, err = r.Next()
+	if err != nil || e.ID != IDSegment || e.Size != UnknownSize {
+		t.Fatalf("Next() = %+v, %v", e, err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next() at end = %v, want io.EOF", err)
+	}
+}
+
+func TestChildren(t *testing.T) {
+	data := []byte{
+		0x15, 0x49, 0xA9, 0x66, 0xFF, // Info, unknown size
+		0x2A, 0xD7, 0xB1, 0x83, 0x0F, 0x42, 0x40, // TimecodeScale 1000000
+		0x7B, 0xA9, 0x84, 'T', 'e', 's', 't', // Title "Test"
+		0x1F, 0x43, 0xB6, 0x75, 0x80, // Cluster, empty
+	}
+	r := NewReader(bytes.NewReader(data))
+	info, _ := r.Next()
+
+	var scale uint64
+	var title string
+	err := r.Children(info, map[uint32]bool{IDCluster: true}, func(c Element) error {
+		var err error
+		switch c.ID {
+		case IDTimecodeScale:
+			scale, err = r.Uint(c)
+		case IDTitle:
+			title, err = r.String(c)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scale != 1_000_000 || title != "Test" {
+		t.Errorf("scale = %d, title = %q", scale, title)
+	}
+	if e, _ := r.Next(); e.ID != IDCluster {
+		t.Errorf("next element after Info = 0x%X, want Cluster", e.ID)
+	}
+}
+
+func TestVint(t *testing.T) {
+	tests := []struct {
+		data []byte
+		v    uint64
+		n    int
+	}{
+		{[]byte{0x81}, 1, 1},
+		{[]byte{0x40, 0x02}, 2, 2},
+		{[]byte{0x40}, 0, 0},
+		{[]byte{0x00}, 0, 0},
+	}
+	for _, tt := range tests {
+		if v, n := Vint(tt.data); v != tt.v || n != tt.n {
+			t.Errorf("Vint(% X) = %d, %d; want %d, %d", tt.data, v, n, tt.v, tt.n)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"mkv-sub-extractor/pkg/assout"
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/output"
+)
+
+// ExtractBilingual extracts two tracks of an MKV file, typically the same
+// dialogue in two languages, and writes them as one dual-language ASS file
+// laid out by opts.BilingualLayout (see assout.WriteBilingualASS). primary
+// takes the main "Default" style, secondary the smaller "Secondary" one. The
+// file is named after both languages (see output.GenerateBilingualOutputPath).
+func ExtractBilingual(mkvPath string, primary, secondary mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, opts Options) (*Result, error) {
+	var tracks [2]*trackEvents
+	for i, track := range []mkvinfo.SubtitleTrack{primary, secondary} {
+		te, err := readTrackEvents(mkvPath, track, opts)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: %w", track.Number, err)
+		}
+		tracks[i] = te
+	}
+	rebaseEvents(opts.Range, tracks[:]...)
+	for _, te := range tracks {
+		opts.Retime.ApplyEvents(te.events)
+	}
+
+	layout := opts.BilingualLayout
+	layout.Template = opts.StyleTemplate
+	if !opts.KeepPlayRes {
+		layout.PlayRes = tracks[0].videoRes
+	}
+	if opts.LanguageFonts != nil {
+		layout.PrimaryFont = languageFont(layout.PrimaryFont, opts.LanguageFonts, primary.EffectiveLanguage())
+		layout.SecondaryFont = languageFont(layout.SecondaryFont, opts.LanguageFonts, secondary.EffectiveLanguage())
+	}
+
+	if outputDir == "" {
+		outputDir = filepath.Dir(mkvPath)
+	}
+	outputPath := output.GenerateBilingualOutputPath(filepath.Join(outputDir, filepath.Base(mkvPath)), opts.Chinese.named(primary), opts.Chinese.named(secondary), existingPaths)
+	err := createOutput(outputPath, func(w io.Writer) error {
+		return assout.WriteBilingualASS(w, tracks[0].bilingual(), tracks[1].bilingual(), layout)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := tracks[0].result
+	res.OutputPath = outputPath
+	res.merge(tracks[1].result)
+	return &res, nil
+}
+
+// bilingual returns the track as one side of a bilingual file.
+func (te *trackEvents) bilingual() assout.BilingualTrack {
+	return assout.BilingualTrack{Events: te.events, SRT: te.track.CodecID == "S_TEXT/UTF8"}
+}
+
+// languageFont fills in a font without a name from fonts by lang, keeping
+// its size if one was given.
+func languageFont(font assout.LanguageFont, fonts assout.LanguageFonts, lang string) assout.LanguageFont {
+	if font.Fontname != "" {
+		return font
+	}
+	mapped, ok := fonts.Lookup(lang)
+	if !ok {
+		return font
+	}
+	if font.Fontsize > 0 {
+		mapped.Fontsize = font.Fontsize
+	}
+	return mapped
+}
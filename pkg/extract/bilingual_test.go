@@ -0,0 +1,27 @@
+package extract
+
+import (
+	"testing"
+
+	"mkv-sub-extractor/pkg/assout"
+)
+
+func TestLanguageFont(t *testing.T) {
+	fonts := assout.DefaultLanguageFonts()
+
+	got := languageFont(assout.LanguageFont{}, fonts, "chi")
+	if got.Fontname != "Noto Sans CJK SC" || got.Encoding != assout.EncodingGB2312 {
+		t.Errorf("languageFont(chi) = %+v", got)
+	}
+	got = languageFont(assout.LanguageFont{Fontsize: 40}, fonts, "eng")
+	if got.Fontname != "Noto Sans" || got.Fontsize != 40 {
+		t.Errorf("languageFont(eng, size 40) = %+v, want the size kept", got)
+	}
+	got = languageFont(assout.LanguageFont{Fontname: "Arial"}, fonts, "eng")
+	if got.Fontname != "Arial" {
+		t.Errorf("languageFont() replaced a named font: %+v", got)
+	}
+	if got = languageFont(assout.LanguageFont{}, fonts, "und"); got.Fontname != "" {
+		t.Errorf("languageFont(und) = %+v, want the template's font", got)
+	}
+}
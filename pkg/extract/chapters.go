@@ -0,0 +1,139 @@
+package extract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+)
+
+// ChapterRange selects chapters First through Last (1-based, inclusive) of
+// the default edition's listed chapters.
+type ChapterRange struct {
+	First, Last int
+}
+
+// ParseChapterRanges parses a comma-separated list of chapter numbers and
+// ranges, e.g. "1-3,4,5-6".
+func ParseChapterRanges(s string) ([]ChapterRange, error) {
+	var ranges []ChapterRange
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		first, last, isRange := strings.Cut(item, "-")
+		a, err1 := strconv.Atoi(strings.TrimSpace(first))
+		b, err2 := a, error(nil)
+		if isRange {
+			b, err2 = strconv.Atoi(strings.TrimSpace(last))
+		}
+		if err1 != nil || err2 != nil || a < 1 || b < a {
+			return nil, fmt.Errorf("invalid chapter range %q: expected N or FIRST-LAST, e.g. 1-3,4", item)
+		}
+		ranges = append(ranges, ChapterRange{First: a, Last: b})
+	}
+	return ranges, nil
+}
+
+// ChapterPart is the span of a track written to one file when splitting by
+// chapter.
+type ChapterPart struct {
+	Label string // chapter number or range for naming, e.g. "02" or "01-03"
+	Title string // title of the part's first chapter
+	Start uint64 // nanoseconds
+	End   uint64 // nanoseconds; 0 means the end of the segment
+}
+
+// ChapterParts returns the parts for chapters (as from
+// mkvinfo.Edition.ListedChapters): one per chapter when ranges is nil,
+// otherwise one per range. Titles are taken in lang where the chapter has
+// one. Chapters played from another segment (ordered chapters with a
+// SegmentUID) have no events in this file and are left out.
+func ChapterParts(chapters []mkvinfo.Chapter, ranges []ChapterRange, lang string) ([]ChapterPart, error) {
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("no chapters")
+	}
+	if ranges == nil {
+		for i := range chapters {
+			ranges = append(ranges, ChapterRange{First: i + 1, Last: i + 1})
+		}
+	}
+
+	width := max(2, len(strconv.Itoa(len(chapters))))
+	number := func(n int) string { return fmt.Sprintf("%0*d", width, n) }
+
+	var parts []ChapterPart
+	for _, r := range ranges {
+		if r.Last > len(chapters) {
+			return nil, fmt.Errorf("chapter %d out of range: the file has %d chapters", r.Last, len(chapters))
+		}
+		span := chapters[r.First-1 : r.Last]
+		switch local(span) {
+		case 0:
+			continue
+		case len(span):
+		default:
+			return nil, fmt.Errorf("chapters %d-%d mix this and other segments", r.First, r.Last)
+		}
+
+		first, last := span[0], span[len(span)-1]
+		part := ChapterPart{
+			Label: number(r.First),
+			Title: first.Title(lang),
+			Start: first.Start,
+			End:   last.End,
+		}
+		if r.Last != r.First {
+			part.Label += "-" + number(r.Last)
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// local counts the chapters in span that play from this segment.
+func local(span []mkvinfo.Chapter) int {
+	n := 0
+	for _, c := range span {
+		if c.SegmentUID == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// ExtractTrackChapters is like ExtractTrack but writes one file per chapter
+// of the file's default edition, or per entry of opts.ChapterRanges, named
+// by chapter number and title (see output.GenerateChapterOutputPath). Each
+// file holds the events overlapping its chapters, cut at the chapter edges
+// and rebased to the first chapter's start. opts.Range is ignored.
+//
+// Results are returned in chapter order; on error, those of the parts
+// already written are returned with it.
+func ExtractTrackChapters(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, opts Options) ([]*Result, error) {
+	editions, err := mkvinfo.ReadChapters(mkvPath)
+	if err != nil {
+		return nil, err
+	}
+	ed := mkvinfo.DefaultEdition(editions)
+	if ed == nil {
+		return nil, fmt.Errorf("file has no chapters")
+	}
+	parts, err := ChapterParts(ed.ListedChapters(), opts.ChapterRanges, track.EffectiveLanguage())
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*Result
+	for i := range parts {
+		part := &parts[i]
+		o := opts
+		o.Range = clipRange(part.Start, part.End)
+		o.part = part
+		res, err := extractTrackToASS(mkvPath, track, outputDir, existingPaths, o)
+		if err != nil {
+			return results, fmt.Errorf("chapter %s: %w", part.Label, err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
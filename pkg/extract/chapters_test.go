@@ -0,0 +1,59 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+)
+
+func TestParseChapterRanges(t *testing.T) {
+	got, err := ParseChapterRanges("1-3, 4,5 - 6")
+	want := []ChapterRange{{1, 3}, {4, 4}, {5, 6}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseChapterRanges() = %v, %v; want %v", got, err, want)
+	}
+	for _, s := range []string{"", "0", "3-1", "a-b", "1,,2"} {
+		if _, err := ParseChapterRanges(s); err == nil {
+			t.Errorf("ParseChapterRanges(%q): expected error", s)
+		}
+	}
+}
+
+func TestChapterParts(t *testing.T) {
+	title := func(s string) []mkvinfo.ChapterDisplay {
+		return []mkvinfo.ChapterDisplay{{String: s, Languages: []string{"eng"}}}
+	}
+	chapters := []mkvinfo.Chapter{
+		{Start: 0, End: 100, Displays: title("Episode 1")},
+		{Start: 100, End: 200, Displays: title("Episode 2")},
+		{Start: 0, End: 50, SegmentUID: []byte{1}}, // borrowed OP
+		{Start: 200, End: 0, Displays: title("Episode 3")},
+	}
+
+	parts, err := ChapterParts(chapters, nil, "eng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChapterPart{
+		{Label: "01", Title: "Episode 1", Start: 0, End: 100},
+		{Label: "02", Title: "Episode 2", Start: 100, End: 200},
+		{Label: "04", Title: "Episode 3", Start: 200, End: 0},
+	}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("ChapterParts(nil) = %+v, want %+v", parts, want)
+	}
+
+	parts, err = ChapterParts(chapters, []ChapterRange{{1, 2}}, "eng")
+	want = []ChapterPart{{Label: "01-02", Title: "Episode 1", Start: 0, End: 200}}
+	if err != nil || !reflect.DeepEqual(parts, want) {
+		t.Errorf("ChapterParts(1-2) = %+v, %v; want %+v", parts, err, want)
+	}
+
+	if _, err := ChapterParts(chapters, []ChapterRange{{2, 4}}, "eng"); err == nil {
+		t.Error("expected error for a range mixing segments")
+	}
+	if _, err := ChapterParts(chapters, []ChapterRange{{4, 5}}, "eng"); err == nil {
+		t.Error("expected error for a range past the last chapter")
+	}
+}
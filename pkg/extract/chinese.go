@@ -0,0 +1,203 @@
+package extract
+
+import (
+	"strings"
+
+	"mkv-sub-extractor/pkg/ass"
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/subtitle"
+	"mkv-sub-extractor/pkg/zhconv"
+)
+
+// ChineseOptions selects the tracks converted to another written form of
+// Chinese with zhconv. A converted track has the text of its events converted
+// outside override blocks, drawings and SRT markup, except for lines with
+// kana (the Japanese lines of a bilingual track), and the names of its
+// ASS/SSA styles converted along with the \r tags naming them. Its output
+// file carries the variant's tag in place of the track language, e.g.
+// "ep01.zh-TW.ass".
+type ChineseOptions struct {
+	// Variant is the variant Chinese tracks (language chi, zho or zh, tagged
+	// or detected) are converted to. zhconv.None converts none.
+	Variant zhconv.Variant
+
+	// Tracks sets the variant of tracks by index (the number they are listed
+	// and selected by), whatever their language, overriding Variant;
+	// zhconv.None keeps a track as it is.
+	Tracks map[int]zhconv.Variant
+
+	// Tagged names tracks by index by the tag of their detected written form
+	// (see mkvinfo.ChineseText.Tag) in place of their language, as a
+	// converted track is named by its variant's. See TagSharedNames.
+	Tagged map[int]bool
+
+	// Fonts also converts font names, in styles and \fn tags, so that a font
+	// named in Simplified characters is asked for by its Traditional name and
+	// vice versa.
+	Fonts bool
+}
+
+// variant returns the variant track is converted to.
+func (c ChineseOptions) variant(track mkvinfo.SubtitleTrack) zhconv.Variant {
+	if v, ok := c.Tracks[track.Index]; ok {
+		return v
+	}
+	if isChinese(track.EffectiveLanguage()) {
+		return c.Variant
+	}
+	return zhconv.None
+}
+
+// sameAs returns the options converting src, track's counterpart in another
+// file, as track is converted.
+func (c ChineseOptions) sameAs(track, src mkvinfo.SubtitleTrack) ChineseOptions {
+	c.Tracks = map[int]zhconv.Variant{src.Index: c.variant(track)}
+	return c
+}
+
+// named returns track as it names output files: with the tag of its variant
+// in place of its language when it is converted.
+func (c ChineseOptions) named(track mkvinfo.SubtitleTrack) mkvinfo.SubtitleTrack {
+	if v := c.variant(track); v != zhconv.None {
+		track.Language = v.Tag()
+		if second := track.Chinese.Second; second != "" {
+			track.Language += "+" + second
+		}
+		track.Chinese = mkvinfo.ChineseText{}
+	} else if tag := track.Chinese.Tag(); tag != "" && c.Tagged[track.Index] {
+		track.Language = tag
+		track.Chinese = mkvinfo.ChineseText{}
+	}
+	return track
+}
+
+// TagSharedNames sets Tagged to the tracks with a detected written form that
+// would otherwise share their output name with another of tracks, each
+// extracted to a file of its own. Every one of them then says which form it
+// holds: "ep01.zh-Hans.ass" and "ep01.zh-Hant.ass" rather than "ep01.chi.ass"
+// and "ep01.zh-Hant.ass".
+func (c *ChineseOptions) TagSharedNames(tracks []mkvinfo.SubtitleTrack) {
+	c.Tagged = nil
+	names := make(map[string]int)
+	for _, track := range tracks {
+		names[c.named(track).EffectiveLanguage()]++
+	}
+	for _, track := range tracks {
+		if track.Chinese.Tag() == "" || names[c.named(track).EffectiveLanguage()] < 2 {
+			continue
+		}
+		if c.Tagged == nil {
+			c.Tagged = make(map[int]bool)
+		}
+		c.Tagged[track.Index] = true
+	}
+}
+
+// isChinese reports whether lang, an ISO 639 code, is Chinese.
+func isChinese(lang string) bool {
+	return mkvinfo.SameLanguage(lang, "zh")
+}
+
+// convertChinese converts the track's events and ASS/SSA header as opts
+// asks.
+func (te *trackEvents) convertChinese(opts ChineseOptions) {
+	v := opts.variant(te.track)
+	if v == zhconv.None {
+		return
+	}
+	conv := chineseConverter{zhconv.For(v)}
+
+	srt := te.track.CodecID == "S_TEXT/UTF8"
+	for i := range te.events {
+		ev := &te.events[i]
+		if srt {
+			ev.Text = convertSRT(ev.Text, conv)
+		} else {
+			ev.Text = convertDialogue(ev.Text, conv, opts.Fonts)
+		}
+		ev.Style = conv.Convert(ev.Style)
+	}
+
+	if len(te.codecPrivate) == 0 {
+		return
+	}
+	doc := ass.Parse(string(te.codecPrivate))
+	if styles := doc.Styles(); styles != nil {
+		for _, st := range styles.Styles {
+			st.Name = conv.Convert(st.Name)
+			if opts.Fonts {
+				st.Fontname = conv.Convert(st.Fontname)
+			}
+		}
+	}
+	te.codecPrivate = []byte(doc.String())
+}
+
+// chineseConverter converts a track's text to a Chinese variant.
+type chineseConverter struct {
+	*zhconv.Converter
+}
+
+// hasKana reports whether a line of text holds kana, which makes it one of
+// the Japanese lines of a bilingual track: those keep their kanji.
+func hasKana(line string) bool {
+	return subtitle.CountScripts(line)[subtitle.ScriptJapanese] > 0
+}
+
+// convertSRT converts SRT text line by line, outside its markup, leaving the
+// lines with kana as they are.
+func convertSRT(text string, conv chineseConverter) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if !hasKana(line) {
+			lines[i] = subtitle.MapSRTText(line, conv.Convert)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// convertDialogue converts the text runs of ASS dialogue text, except on the
+// lines (between \N breaks) that hold kana, and the style names of its \r
+// tags and, with fonts, the font names of its \fn tags.
+func convertDialogue(text string, conv chineseConverter, fonts bool) string {
+	parsed := subtitle.ParseASSText(text)
+	japanese := make(map[int]bool)
+	for i, line := range strings.Split(parsed.PlainText(), "\n") {
+		japanese[i] = hasKana(line)
+	}
+
+	line := 0
+	for i, node := range parsed {
+		switch node := node.(type) {
+		case subtitle.Break:
+			if node.Kind == subtitle.HardBreak {
+				line++
+			}
+		case subtitle.TextRun:
+			if !japanese[line] {
+				parsed[i] = subtitle.TextRun{Text: conv.Convert(node.Text)}
+			}
+		case subtitle.OverrideBlock:
+			convertTags(node.Items, conv, fonts)
+		}
+	}
+	return parsed.String()
+}
+
+// convertTags converts the names in \r and \fn tags, including those
+// animated by \t, in place.
+func convertTags(items []subtitle.BlockItem, conv chineseConverter, fonts bool) {
+	for i, item := range items {
+		tag, ok := item.(subtitle.Tag)
+		if !ok {
+			continue
+		}
+		convertTags(tag.Nested, conv, fonts)
+		if (tag.Kind == subtitle.TagReset || fonts && tag.Kind == subtitle.TagFontName) && len(tag.Args) > 0 {
+			if name := conv.Convert(tag.Args[0]); name != tag.Args[0] {
+				tag.Args = []string{name}
+				items[i] = tag
+			}
+		}
+	}
+}
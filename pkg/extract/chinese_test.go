@@ -0,0 +1,144 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/subtitle"
+	"mkv-sub-extractor/pkg/zhconv"
+)
+
+func TestChineseOptions_Variant(t *testing.T) {
+	opts := ChineseOptions{
+		Variant: zhconv.Taiwan,
+		Tracks:  map[int]zhconv.Variant{3: zhconv.None, 4: zhconv.Simplified},
+	}
+	tests := []struct {
+		track mkvinfo.SubtitleTrack
+		want  zhconv.Variant
+	}{
+		{mkvinfo.SubtitleTrack{Index: 2, Language: "chi"}, zhconv.Taiwan},
+		{mkvinfo.SubtitleTrack{Index: 5, Language: "zho"}, zhconv.Taiwan},
+		{mkvinfo.SubtitleTrack{Index: 6, Language: "eng"}, zhconv.None},
+		{mkvinfo.SubtitleTrack{Index: 3, Language: "chi"}, zhconv.None},
+		{mkvinfo.SubtitleTrack{Index: 4, Language: "und"}, zhconv.Simplified},
+	}
+	for _, tt := range tests {
+		if got := opts.variant(tt.track); got != tt.want {
+			t.Errorf("variant(track %d, %s) = %v, want %v", tt.track.Index, tt.track.Language, got, tt.want)
+		}
+	}
+
+	named := opts.named(mkvinfo.SubtitleTrack{Index: 2, Language: "chi"})
+	if named.Language != "zh-TW" {
+		t.Errorf("named().Language = %q, want zh-TW", named.Language)
+	}
+	if named = opts.named(mkvinfo.SubtitleTrack{Index: 3, Language: "chi"}); named.Language != "chi" {
+		t.Errorf("named() of an unconverted track = %q, want chi", named.Language)
+	}
+
+	detected := mkvinfo.SubtitleTrack{Index: 2, Language: "chi", Chinese: mkvinfo.ChineseText{Script: "Hans", Second: "jpn"}}
+	if named = opts.named(detected); named.Language != "zh-TW+jpn" || !named.Chinese.IsZero() {
+		t.Errorf("named() of a bilingual track = %q %+v, want zh-TW+jpn", named.Language, named.Chinese)
+	}
+
+	// Track 4 is matched by a track numbered 7 in another file.
+	same := opts.sameAs(mkvinfo.SubtitleTrack{Index: 4, Language: "und"}, mkvinfo.SubtitleTrack{Index: 7, Language: "und"})
+	if got := same.variant(mkvinfo.SubtitleTrack{Index: 7, Language: "und"}); got != zhconv.Simplified {
+		t.Errorf("sameAs().variant() = %v, want zh-Hans", got)
+	}
+}
+
+func TestChineseOptions_TagSharedNames(t *testing.T) {
+	tracks := []mkvinfo.SubtitleTrack{
+		{Index: 1, Language: "chi", Chinese: mkvinfo.ChineseText{Script: "Hans"}},
+		{Index: 2, Language: "chi", Chinese: mkvinfo.ChineseText{Script: "Hant"}},
+		{Index: 3, Language: "chi", Chinese: mkvinfo.ChineseText{Script: "Hans", Second: "jpn"}},
+		{Index: 4, Language: "chi"},
+		{Index: 5, Language: "eng"},
+	}
+	var opts ChineseOptions
+	opts.TagSharedNames(tracks)
+	want := []string{"zh-Hans", "zh-Hant", "zh-Hans+jpn", "chi", "eng"}
+	for i, track := range tracks {
+		if got := opts.named(track).Language; got != want[i] {
+			t.Errorf("named(track %d) = %q, want %q", track.Index, got, want[i])
+		}
+	}
+
+	// A Chinese track alone keeps its language code, here once the other is
+	// converted.
+	opts = ChineseOptions{Tracks: map[int]zhconv.Variant{2: zhconv.Taiwan}}
+	opts.TagSharedNames(tracks[:2])
+	if got := opts.named(tracks[0]).Language; got != "chi" {
+		t.Errorf("named(track 1) = %q, want chi", got)
+	}
+	if got := opts.named(tracks[1]).Language; got != "zh-TW" {
+		t.Errorf("named(track 2) = %q, want zh-TW", got)
+	}
+}
+
+func TestConvertDialogue(t *testing.T) {
+	conv := chineseConverter{zhconv.For(zhconv.Traditional)}
+	tests := []struct {
+		in    string
+		fonts bool
+		want  string
+	}{
+		{`{\fn方正黑体\b1}头发\N{\r注释}发展`, false, `{\fn方正黑体\b1}頭髮\N{\r註釋}發展`},
+		{`{\fn方正黑体}头发`, true, `{\fn方正黑體}頭髮`},
+		{`{\t(0,500,\fn黑体)}发`, true, `{\t(0,500,\fn黑體)}發`},
+		{`{翻译注：发}发`, false, `{翻译注：发}發`},
+		{`{\p1}m 0 0 l 10 0{\p0}汉字`, false, `{\p1}m 0 0 l 10 0{\p0}漢字`},
+		{`Hello`, false, `Hello`},
+		{`头发\N髪の毛が長い`, false, `頭髮\N髪の毛が長い`},                       // Japanese line of a bilingual track
+		{`{\i1}日本語です{\i0}漢字\N头发`, false, `{\i1}日本語です{\i0}漢字\N頭髮`}, // kana anywhere on the line
+	}
+	for _, tt := range tests {
+		if got := convertDialogue(tt.in, conv, tt.fonts); got != tt.want {
+			t.Errorf("convertDialogue(%q, fonts=%v) = %q, want %q", tt.in, tt.fonts, got, tt.want)
+		}
+	}
+}
+
+func TestConvertSRT(t *testing.T) {
+	conv := chineseConverter{zhconv.For(zhconv.Traditional)}
+	in := "<i>日本語です</i>漢字\n<i>头发</i>"
+	if got, want := convertSRT(in, conv), "<i>日本語です</i>漢字\n<i>頭髮</i>"; got != want {
+		t.Errorf("convertSRT(%q) = %q, want %q", in, got, want)
+	}
+}
+
+func TestConvertChinese(t *testing.T) {
+	header := "[Script Info]\nScriptType: v4.00+\n\n" +
+		"[V4+ Styles]\nFormat: Name, Fontname, Fontsize\nStyle: 注释,方正黑体,20\nStyle: Default,Arial,20\n\n" +
+		"[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n"
+	te := &trackEvents{
+		track:        mkvinfo.SubtitleTrack{Index: 2, Language: "chi", CodecID: "S_TEXT/ASS"},
+		codecPrivate: []byte(header),
+		events:       []subtitle.SubtitleEvent{{Style: "注释", Text: "头发"}},
+	}
+	te.convertChinese(ChineseOptions{Variant: zhconv.Taiwan})
+
+	if ev := te.events[0]; ev.Style != "註釋" || ev.Text != "頭髮" {
+		t.Errorf("event = %q %q, want 註釋 頭髮", ev.Style, ev.Text)
+	}
+	got := string(te.codecPrivate)
+	if !strings.Contains(got, "Style: 註釋,方正黑体,20") || !strings.Contains(got, "Style: Default,Arial,20") {
+		t.Errorf("header styles not converted as expected:\n%s", got)
+	}
+
+	srt := &trackEvents{
+		track:  mkvinfo.SubtitleTrack{Index: 3, Language: "eng", CodecID: "S_TEXT/UTF8"},
+		events: []subtitle.SubtitleEvent{{Style: "Default", Text: "<i>头发</i>"}},
+	}
+	srt.convertChinese(ChineseOptions{Variant: zhconv.Taiwan})
+	if srt.events[0].Text != "<i>头发</i>" {
+		t.Errorf("non-Chinese track converted: %q", srt.events[0].Text)
+	}
+	srt.convertChinese(ChineseOptions{Tracks: map[int]zhconv.Variant{3: zhconv.HongKong}})
+	if srt.events[0].Text != "<i>頭髮</i>" {
+		t.Errorf("SRT text = %q, want <i>頭髮</i>", srt.events[0].Text)
+	}
+}
@@ -0,0 +1,190 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	matroska "github.com/luispater/matroska-go"
+
+	"mkv-sub-extractor/pkg/langid"
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/subtitle"
+	"mkv-sub-extractor/pkg/zhconv"
+)
+
+const (
+	// detectSample is the number of events per track read to detect its
+	// language or written form.
+	detectSample = 100
+
+	// detectWindow and detectBytes cap the start of the file read for the
+	// sample, in playback time (nanoseconds) and in packet bytes of all
+	// tracks: a sparse track would otherwise have the whole file read.
+	detectWindow uint64 = 10 * 60 * 1_000_000_000
+	detectBytes         = 256 << 20
+)
+
+const (
+	// minLanguageConfidence is the confidence a detected language needs to
+	// be used; below it, a track stays untagged.
+	minLanguageConfidence = 0.5
+
+	// minFormChars is the number of characters written in only one form
+	// needed to tell Simplified from Traditional.
+	minFormChars = 5
+
+	// formShare is the share of those characters one form needs to win;
+	// names and quotes may bring in a few of the other.
+	formShare = 0.8
+
+	// minSecondLines and secondShare are the number and share of lines in
+	// another language that make a track bilingual. A few Japanese song
+	// lyrics or English signs in a Chinese track stay well below.
+	minSecondLines = 10
+	secondShare    = 0.35
+)
+
+// DetectText detects from their first events what the text tracks among
+// tracks hold: the language of untagged tracks (see langid.Detect), set in
+// their Detected field and LanguageName when the confidence reaches
+// minLanguageConfidence, and the written form of Chinese tracks, tagged or
+// detected (see classifyChinese), set in their Chinese field. Other tracks
+// are left as they are. The tracks are sampled together in one pass over the
+// start of the file, at most detectWindow or detectBytes of it, and their
+// text decoded as enc asks.
+func DetectText(mkvPath string, tracks []mkvinfo.SubtitleTrack, enc EncodingOptions) error {
+	sample := make(map[uint8][]RawSubtitlePacket)
+	for _, t := range tracks {
+		if t.IsExtractable && (mkvinfo.IsUntagged(t.Language) || isChinese(t.Language)) {
+			sample[t.Number] = nil
+		}
+	}
+	if len(sample) == 0 {
+		return nil
+	}
+	if err := readSample(mkvPath, sample); err != nil {
+		return err
+	}
+
+	for i, t := range tracks {
+		packets, ok := sample[t.Number]
+		if !ok {
+			continue
+		}
+		var header []byte
+		if _, err := decodeText(t, packets, &header, enc); err != nil {
+			continue
+		}
+		events, _, err := convertPackets(packets, t.CodecID, true)
+		if err != nil {
+			continue
+		}
+		if mkvinfo.IsUntagged(t.Language) {
+			code, confidence := langid.Detect(plainLines(events))
+			if code == "" || confidence < minLanguageConfidence {
+				continue
+			}
+			tracks[i].Detected = mkvinfo.DetectedLanguage{Code: code, Confidence: confidence}
+			tracks[i].LanguageName = mkvinfo.ResolveLanguageName(code)
+		}
+		if isChinese(tracks[i].EffectiveLanguage()) {
+			tracks[i].Chinese = classifyChinese(events)
+		}
+	}
+	return nil
+}
+
+// readSample reads the first detectSample packets of each track of sample
+// into it, stopping at the first packet past detectWindow or detectBytes.
+func readSample(mkvPath string, sample map[uint8][]RawSubtitlePacket) error {
+	file, err := os.Open(mkvPath)
+	if err != nil {
+		return fmt.Errorf("open MKV file: %w", err)
+	}
+	defer file.Close()
+
+	demuxer, err := matroska.NewDemuxer(file)
+	if err != nil {
+		return fmt.Errorf("create demuxer: %w", err)
+	}
+	defer demuxer.Close()
+
+	read := 0
+	for full := 0; full < len(sample); {
+		pkt, err := demuxer.ReadPacket()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read packet error: %w", err)
+		}
+		read += len(pkt.Data)
+		if pkt.StartTime > detectWindow || read > detectBytes {
+			break
+		}
+		packets, ok := sample[pkt.Track]
+		if !ok || len(packets) == detectSample {
+			continue
+		}
+		sample[pkt.Track] = append(packets, RawSubtitlePacket{StartTime: pkt.StartTime, EndTime: pkt.EndTime, Data: pkt.Data})
+		if len(sample[pkt.Track]) == detectSample {
+			full++
+		}
+	}
+	return nil
+}
+
+// plainLines returns the lines of the events' text, without markup.
+func plainLines(events []subtitle.SubtitleEvent) []string {
+	var lines []string
+	for _, ev := range events {
+		plain := subtitle.ParseASSText(ev.Text).PlainText()
+		lines = append(lines, strings.Split(plain, "\n")...)
+	}
+	return lines
+}
+
+// classifyChinese classifies the text of a Chinese track, line by line. Lines
+// with kana are Japanese, lines of Latin letters without Chinese characters
+// English, and the characters of the other lines are counted by form (see
+// zhconv.CountForms). The script is Simplified or Traditional when one form
+// clearly dominates, and the track is bilingual when many lines are in one
+// other language.
+func classifyChinese(events []subtitle.SubtitleEvent) mkvinfo.ChineseText {
+	var simplified, traditional, chinese, japanese, english int
+	for _, line := range plainLines(events) {
+		counts := subtitle.CountScripts(line)
+		switch {
+		case counts[subtitle.ScriptJapanese] > 0:
+			japanese++
+		case counts[subtitle.ScriptHan] > 0:
+			chinese++
+			s, t := zhconv.CountForms(line)
+			simplified += s
+			traditional += t
+		case counts[subtitle.ScriptLatin] > 0:
+			english++
+		}
+	}
+
+	var c mkvinfo.ChineseText
+	if total := simplified + traditional; total >= minFormChars {
+		switch {
+		case float64(simplified) >= formShare*float64(total):
+			c.Script = "Hans"
+		case float64(traditional) >= formShare*float64(total):
+			c.Script = "Hant"
+		}
+	}
+
+	second, lines := "jpn", japanese
+	if english > japanese {
+		second, lines = "eng", english
+	}
+	if lines >= minSecondLines && float64(lines) >= secondShare*float64(chinese+lines) {
+		c.Second = second
+	}
+	return c
+}
@@ -0,0 +1,85 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// events returns one event per line of text.
+func events(lines ...string) []subtitle.SubtitleEvent {
+	evs := make([]subtitle.SubtitleEvent, len(lines))
+	for i, line := range lines {
+		evs[i] = subtitle.SubtitleEvent{Text: line}
+	}
+	return evs
+}
+
+// repeat returns n copies of lines.
+func repeat(n int, lines ...string) []string {
+	var out []string
+	for range n {
+		out = append(out, lines...)
+	}
+	return out
+}
+
+func TestClassifyChinese(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []subtitle.SubtitleEvent
+		want   mkvinfo.ChineseText
+	}{
+		{"simplified", events(repeat(5, `{\an8}我们走吧`, "这是什么东西")...), mkvinfo.ChineseText{Script: "Hans"}},
+		{"traditional", events(repeat(5, "我們走吧", "這是什麼東西")...), mkvinfo.ChineseText{Script: "Hant"}},
+		{"too few characters", events("我们", "好"), mkvinfo.ChineseText{}},
+		{"mixed", events(repeat(5, "我们走吧", "我們走吧")...), mkvinfo.ChineseText{}},
+		{
+			"Chinese-Japanese",
+			events(repeat(10, `我们走吧\N行きましょう`)...),
+			mkvinfo.ChineseText{Script: "Hans", Second: "jpn"},
+		},
+		{
+			"Chinese-English",
+			events(repeat(10, "這是什麼東西", "What is this")...),
+			mkvinfo.ChineseText{Script: "Hant", Second: "eng"},
+		},
+		{
+			"a few lyrics",
+			events(append(repeat(40, "这是什么东西"), repeat(10, "君の名は")...)...),
+			mkvinfo.ChineseText{Script: "Hans"},
+		},
+	}
+	for _, tt := range tests {
+		if got := classifyChinese(tt.events); got != tt.want {
+			t.Errorf("%s: classifyChinese() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectText_NothingToDetect(t *testing.T) {
+	// Without untagged or Chinese text tracks the file is not even opened.
+	tracks := []mkvinfo.SubtitleTrack{
+		{Number: 1, Language: "eng", IsExtractable: true},
+		{Number: 2, Language: "und", IsText: false},
+	}
+	if err := DetectText("does-not-exist.mkv", tracks, EncodingOptions{}); err != nil {
+		t.Errorf("DetectText() = %v, want nil", err)
+	}
+	for _, lang := range []string{"chi", "und", ""} {
+		err := DetectText("does-not-exist.mkv", []mkvinfo.SubtitleTrack{{Number: 2, Language: lang, IsExtractable: true}}, EncodingOptions{})
+		if err == nil || !strings.Contains(err.Error(), "open MKV file") {
+			t.Errorf("DetectText() of a missing file with a %q track = %v", lang, err)
+		}
+	}
+}
+
+func TestPlainLines(t *testing.T) {
+	got := plainLines(events(`{\an8}Hello\Nworld`, "Bye"))
+	want := []string{"Hello", "world", "Bye"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("plainLines() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package extract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mkv-sub-extractor/pkg/ass"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// Diagnostic actions: what lenient extraction did with a malformed packet.
+const (
+	ActionRepaired = "repaired" // the packet was kept with some fields defaulted
+	ActionSkipped  = "skipped"  // the packet was dropped
+)
+
+// Diagnostic describes one malformed subtitle packet found during lenient
+// extraction (see Options.Lenient).
+type Diagnostic struct {
+	PacketIndex int    `json:"packet_index"` // RawSubtitlePacket.Index: position in the track as read
+	Timestamp   uint64 `json:"timestamp_ns"` // packet start time in nanoseconds
+	Reason      string `json:"reason"`       // what was wrong with the packet
+	Action      string `json:"action"`       // ActionRepaired or ActionSkipped
+	Data        []byte `json:"data"`         // raw block data (base64 in JSON)
+}
+
+// String formats the diagnostic for log output, e.g.
+//
+//	packet 12 at 0:01:02.35: invalid ReadOrder "x1" (repaired): "x1,0,Default,,0,0,0,,Hi"
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("packet %d at %s: %s (%s): %q",
+		d.PacketIndex, ass.FormatTime(d.Timestamp), d.Reason, d.Action, d.Data)
+}
+
+// packetConverter converts raw packets to events, either failing on the first
+// malformed packet (strict) or repairing/skipping it and recording a
+// Diagnostic (lenient).
+type packetConverter struct {
+	lenient     bool
+	diagnostics []Diagnostic
+}
+
+// record adds a diagnostic for pkt.
+func (c *packetConverter) record(pkt RawSubtitlePacket, action, reason string) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		PacketIndex: pkt.Index,
+		Timestamp:   pkt.StartTime,
+		Reason:      reason,
+		Action:      action,
+		Data:        pkt.Data,
+	})
+}
+
+// assEvent converts a Matroska ASS/SSA block ("ReadOrder,Layer,Style,Name,
+// MarginL,MarginR,MarginV,Effect,Text"). In lenient mode a non-numeric
+// ReadOrder becomes the packet's Index, a non-numeric Layer becomes 0, and data
+// with fewer than nine fields is kept as the text of a Default-style event.
+// Empty data is skipped (ok is false).
+func (c *packetConverter) assEvent(pkt RawSubtitlePacket) (ev subtitle.SubtitleEvent, ok bool, err error) {
+	readOrder, layer, remaining, err := subtitle.ParseASSBlockData(pkt.Data)
+	if err == nil {
+		// Split remaining into at most 7 parts: Style, Name, MarginL, MarginR, MarginV, Effect, Text
+		parts := strings.SplitN(remaining, ",", 7)
+		return assFieldsEvent(pkt, readOrder, layer, parts), true, nil
+	}
+	if !c.lenient {
+		return ev, false, fmt.Errorf("packet %d: parse ASS block data: %w", pkt.Index, err)
+	}
+
+	if len(pkt.Data) == 0 {
+		c.record(pkt, ActionSkipped, err.Error())
+		return ev, false, nil
+	}
+
+	parts := strings.SplitN(string(pkt.Data), ",", 9)
+	if len(parts) < 9 {
+		c.record(pkt, ActionRepaired, err.Error()+"; kept as text")
+		return textEvent(pkt, pkt.Index), true, nil
+	}
+
+	// Nine fields but a bad ReadOrder or Layer: default the bad numbers.
+	// Empty fields read as 0, as in subtitle.ParseASSBlockData.
+	var reasons []string
+	readOrder, rerr := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if rerr != nil && strings.TrimSpace(parts[0]) != "" {
+		readOrder = pkt.Index
+		reasons = append(reasons, fmt.Sprintf("invalid ReadOrder %q", parts[0]))
+	}
+	layer, lerr := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if lerr != nil && strings.TrimSpace(parts[1]) != "" {
+		reasons = append(reasons, fmt.Sprintf("invalid Layer %q", parts[1]))
+	}
+	c.record(pkt, ActionRepaired, strings.Join(reasons, ", "))
+	return assFieldsEvent(pkt, readOrder, layer, parts[2:]), true, nil
+}
+
+// assFieldsEvent builds an event from the seven Style..Text fields.
+func assFieldsEvent(pkt RawSubtitlePacket, readOrder, layer int, parts []string) subtitle.SubtitleEvent {
+	return subtitle.SubtitleEvent{
+		Start:     pkt.StartTime,
+		End:       pkt.EndTime,
+		Layer:     layer,
+		Style:     parts[0],
+		Name:      parts[1],
+		MarginL:   parts[2],
+		MarginR:   parts[3],
+		MarginV:   parts[4],
+		Effect:    parts[5],
+		Text:      parts[6],
+		ReadOrder: readOrder,
+	}
+}
+
+// textEvent builds a Default-style event whose text is the packet data, as
+// for SRT packets.
+func textEvent(pkt RawSubtitlePacket, readOrder int) subtitle.SubtitleEvent {
+	return subtitle.SubtitleEvent{
+		Start:     pkt.StartTime,
+		End:       pkt.EndTime,
+		Layer:     0,
+		Style:     "Default",
+		Name:      "",
+		MarginL:   "0",
+		MarginR:   "0",
+		MarginV:   "0",
+		Effect:    "",
+		Text:      string(pkt.Data),
+		ReadOrder: readOrder,
+	}
+}
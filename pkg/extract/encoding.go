@@ -0,0 +1,148 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/textenc"
+)
+
+// encodingSample is the number of bytes of a track's text read to detect its
+// encoding.
+const encodingSample = 64 << 10
+
+// EncodingOptions selects the encoding text tracks are decoded from. Matroska
+// text tracks should be UTF-8, but old muxes often hold GBK, Big5, Shift_JIS
+// or Windows-1252 bytes; a track whose packets or CodecPrivate header are not
+// valid UTF-8 is decoded from the detected encoding (see textenc.Detect)
+// unless one is given.
+type EncodingOptions struct {
+	// Default is the encoding of the packets and headers that are not valid
+	// UTF-8, in place of the detected one. Nil detects it.
+	Default encoding.Encoding
+
+	// Tracks sets the encoding of tracks by index (the number they are listed
+	// and selected by), whether or not they are valid UTF-8, overriding
+	// Default.
+	Tracks map[int]encoding.Encoding
+}
+
+// sameAs returns the options decoding src, track's counterpart in another
+// file, as track is decoded.
+func (o EncodingOptions) sameAs(track, src mkvinfo.SubtitleTrack) EncodingOptions {
+	enc, ok := o.Tracks[track.Index]
+	o.Tracks = nil
+	if ok {
+		o.Tracks = map[int]encoding.Encoding{src.Index: enc}
+	}
+	return o
+}
+
+// Transcoding reports a track decoded from a legacy encoding to UTF-8.
+type Transcoding struct {
+	Encoding   string `json:"encoding"`             // WHATWG name, e.g. "gbk"
+	Detected   bool   `json:"detected"`             // guessed rather than given
+	Confidence int    `json:"confidence,omitempty"` // of the guess, from 1 to 100
+	Invalid    int    `json:"invalid_packets"`      // packets that were not valid UTF-8
+}
+
+// String describes the transcoding in one line, e.g.
+//
+//	text of 120 packets was not valid UTF-8; decoded from gbk (detected, confidence 100)
+func (t Transcoding) String() string {
+	msg := fmt.Sprintf("text of %d packets was not valid UTF-8; decoded from %s", t.Invalid, t.Encoding)
+	if t.Invalid == 0 {
+		msg = "text decoded from " + t.Encoding
+	}
+	if t.Detected {
+		msg += fmt.Sprintf(" (detected, confidence %d)", t.Confidence)
+	}
+	return msg
+}
+
+// decodeText decodes the text of track's packets and CodecPrivate header to
+// UTF-8 in place: all of it from the encoding opts sets for the track, or
+// otherwise the packets and header that are not valid UTF-8, from
+// opts.Default or the detected encoding, so that a UTF-8 track with a few
+// broken packets keeps its valid ones. It returns nil when the text was left
+// as it was, which includes text whose encoding could not be detected.
+func decodeText(track mkvinfo.SubtitleTrack, packets []RawSubtitlePacket, codecPrivate *[]byte, opts EncodingOptions) (*Transcoding, error) {
+	invalid := 0
+	for _, pkt := range packets {
+		if !utf8.Valid(pkt.Data) {
+			invalid++
+		}
+	}
+	validHeader := utf8.Valid(*codecPrivate)
+
+	t := &Transcoding{Invalid: invalid}
+	enc, given := opts.Tracks[track.Index]
+	switch {
+	case given:
+	case invalid == 0 && validHeader:
+		return nil, nil
+	case opts.Default != nil:
+		enc = opts.Default
+	default:
+		enc, t.Confidence = textenc.Detect(textSample(packets, *codecPrivate))
+		if enc == nil {
+			return nil, nil
+		}
+		t.Detected = true
+	}
+	t.Encoding = textenc.Name(enc)
+	if t.Encoding == "utf-8" && invalid == 0 && validHeader {
+		return nil, nil
+	}
+
+	for i := range packets {
+		if !given && utf8.Valid(packets[i].Data) {
+			continue
+		}
+		data, err := textenc.Decode(enc, packets[i].Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", t.Encoding, err)
+		}
+		packets[i].Data = data
+	}
+	if given || !validHeader {
+		header, err := textenc.Decode(enc, *codecPrivate)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s header: %w", t.Encoding, err)
+		}
+		*codecPrivate = header
+	}
+	return t, nil
+}
+
+// textSample returns up to encodingSample bytes of the text that tells its
+// encoding: the CodecPrivate header and the packets with bytes other than
+// ASCII, one per line.
+func textSample(packets []RawSubtitlePacket, codecPrivate []byte) []byte {
+	var sample bytes.Buffer
+	add := func(data []byte) {
+		if len(data) > 0 && !isASCII(data) && sample.Len() < encodingSample {
+			sample.Write(data)
+			sample.WriteByte('\n')
+		}
+	}
+	add(codecPrivate)
+	for _, pkt := range packets {
+		add(pkt.Data)
+	}
+	return sample.Bytes()
+}
+
+// isASCII reports whether data is plain ASCII.
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+)
+
+// encodedPackets returns one packet per line, encoded to enc.
+func encodedPackets(t *testing.T, enc encoding.Encoding, lines ...string) []RawSubtitlePacket {
+	t.Helper()
+	packets := make([]RawSubtitlePacket, len(lines))
+	for i, line := range lines {
+		data, err := enc.NewEncoder().Bytes([]byte(line))
+		if err != nil {
+			t.Fatalf("encode %q: %v", line, err)
+		}
+		packets[i] = RawSubtitlePacket{Data: data}
+	}
+	return packets
+}
+
+// packetText returns the packets' data, one per line.
+func packetText(packets []RawSubtitlePacket) string {
+	var lines []string
+	for _, pkt := range packets {
+		lines = append(lines, string(pkt.Data))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDecodeText(t *testing.T) {
+	lines := repeat(5, "我们必须在天黑之前离开这里。", "没有人知道那天晚上发生了什么。", "<i>把钥匙给我</i>")
+	want := strings.Join(lines, "\n")
+	track := mkvinfo.SubtitleTrack{Index: 2, CodecID: "S_TEXT/UTF8"}
+
+	t.Run("detected", func(t *testing.T) {
+		packets := encodedPackets(t, simplifiedchinese.GBK, lines...)
+		var header []byte
+		got, err := decodeText(track, packets, &header, EncodingOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || !got.Detected || got.Invalid != len(lines) || (got.Encoding != "gbk" && got.Encoding != "gb18030") {
+			t.Fatalf("decodeText() = %+v, want detected gbk", got)
+		}
+		if text := packetText(packets); text != want {
+			t.Errorf("decoded text = %q, want %q", text, want)
+		}
+	})
+
+	t.Run("UTF-8 left alone", func(t *testing.T) {
+		packets := encodedPackets(t, unicode.UTF8, lines...)
+		header := []byte("[Script Info]\n")
+		got, err := decodeText(track, packets, &header, EncodingOptions{Default: traditionalchinese.Big5})
+		if got != nil || err != nil {
+			t.Errorf("decodeText() = %+v, %v, want nil", got, err)
+		}
+		if text := packetText(packets); text != want {
+			t.Errorf("text = %q, want it unchanged", text)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		packets := encodedPackets(t, traditionalchinese.Big5, "我們走吧")
+		var header []byte
+		got, err := decodeText(track, packets, &header, EncodingOptions{Default: traditionalchinese.Big5})
+		if err != nil || got == nil || got.Encoding != "big5" || got.Detected {
+			t.Fatalf("decodeText() = %+v, %v, want given big5", got, err)
+		}
+		if text := packetText(packets); text != "我們走吧" {
+			t.Errorf("decoded text = %q", text)
+		}
+	})
+
+	t.Run("default decodes only broken packets", func(t *testing.T) {
+		packets := encodedPackets(t, unicode.UTF8, lines...)
+		packets = append(packets, encodedPackets(t, simplifiedchinese.GBK, "我们走吧")...)
+		var header []byte
+		got, err := decodeText(track, packets, &header, EncodingOptions{Default: simplifiedchinese.GBK})
+		if err != nil || got == nil || got.Invalid != 1 {
+			t.Fatalf("decodeText() = %+v, %v, want 1 packet decoded", got, err)
+		}
+		if text := packetText(packets); text != want+"\n我们走吧" {
+			t.Errorf("decoded text = %q, want the UTF-8 packets unchanged", text)
+		}
+	})
+
+	t.Run("per track", func(t *testing.T) {
+		// A legacy header decodes along with valid (ASCII) packets.
+		packets := encodedPackets(t, unicode.UTF8, "Dialogue")
+		header, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("Style: 黑体"))
+		opts := EncodingOptions{Tracks: map[int]encoding.Encoding{2: simplifiedchinese.GBK}}
+		got, err := decodeText(track, packets, &header, opts)
+		if err != nil || got == nil || got.Encoding != "gbk" || got.Invalid != 0 {
+			t.Fatalf("decodeText() = %+v, %v, want given gbk", got, err)
+		}
+		if string(header) != "Style: 黑体" {
+			t.Errorf("decoded header = %q", header)
+		}
+	})
+}
+
+func TestTranscoding_String(t *testing.T) {
+	tests := []struct {
+		t    Transcoding
+		want string
+	}{
+		{
+			Transcoding{Encoding: "gbk", Detected: true, Confidence: 100, Invalid: 120},
+			"text of 120 packets was not valid UTF-8; decoded from gbk (detected, confidence 100)",
+		},
+		{Transcoding{Encoding: "big5", Invalid: 3}, "text of 3 packets was not valid UTF-8; decoded from big5"},
+		{Transcoding{Encoding: "shift_jis"}, "text decoded from shift_jis"},
+	}
+	for _, tt := range tests {
+		if got := tt.t.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestEncodingOptions_SameAs(t *testing.T) {
+	opts := EncodingOptions{Default: simplifiedchinese.GBK, Tracks: map[int]encoding.Encoding{3: traditionalchinese.Big5}}
+
+	same := opts.sameAs(mkvinfo.SubtitleTrack{Index: 3}, mkvinfo.SubtitleTrack{Index: 1})
+	if same.Tracks[1] != traditionalchinese.Big5 || len(same.Tracks) != 1 || same.Default != simplifiedchinese.GBK {
+		t.Errorf("sameAs() = %+v, want track 1 in big5", same)
+	}
+	if other := opts.sameAs(mkvinfo.SubtitleTrack{Index: 2}, mkvinfo.SubtitleTrack{Index: 3}); other.Tracks != nil {
+		t.Errorf("sameAs() of a track without an encoding = %+v, want no tracks", other)
+	}
+}
@@ -1,15 +1,18 @@
 // Package extract provides MKV subtitle packet extraction functionality.
 //
 // It reads raw subtitle packets from a matroska-go Demuxer, filters by track
-// number, and applies gap-fill logic for missing end times.
+// number, corrects IEEE 754 bit-encoded timestamps, and applies gap-fill logic
+// for missing end times.
 package extract
 
 import (
 	"fmt"
 	"io"
-	"sort"
+	"math"
 
 	matroska "github.com/luispater/matroska-go"
+
+	"mkv-sub-extractor/pkg/ass"
 )
 
 // RawSubtitlePacket holds raw packet data from matroska-go before codec-specific parsing.
@@ -17,6 +20,12 @@ type RawSubtitlePacket struct {
 	StartTime uint64 // nanoseconds
 	EndTime   uint64 // nanoseconds (may be 0 if BlockDuration missing)
 	Data      []byte // raw block data
+
+	// Index is the packet's position among the track's packets in the
+	// order read (from the seek point when a time range is given), before
+	// sorting or time-range filtering; diagnostics and filled packets are
+	// reported by it.
+	Index int
 }
 
 // defaultEndTimePadding is the fallback duration (5 seconds in nanoseconds) applied
@@ -28,15 +37,84 @@ const defaultEndTimePadding uint64 = 5_000_000_000
 // which is generous for any real subtitle.
 const timestampSanityThreshold uint64 = 10_000_000_000_000_000
 
+// TimestampCorrection reports packet timestamps that the demuxer returned as
+// the raw bits of an IEEE 754 float64 rather than as nanoseconds, and that
+// were converted back (see CorrectTimestamps).
+type TimestampCorrection struct {
+	Packets     int    `json:"packets"`     // packets with a bit-encoded start or end time
+	Total       int    `json:"total"`       // packets in the track
+	Uncorrected int    `json:"uncorrected"` // of Packets, those whose times could not be decoded
+	FirstRaw    uint64 `json:"first_raw"`   // raw StartTime of the first affected packet
+	FirstFixed  uint64 `json:"first_fixed"` // its corrected StartTime in nanoseconds
+	WholeTrack  bool   `json:"whole_track"` // every packet in the track was affected
+}
+
+// String describes the correction in one line, e.g.
+//
+//	timestamps of all 120 packets were IEEE 754 bit-encoded; converted to nanoseconds (first 4741671816366391296 -> 0:00:01.00)
+func (c TimestampCorrection) String() string {
+	scope := fmt.Sprintf("timestamps of %d of %d packets were", c.Packets, c.Total)
+	if c.WholeTrack {
+		scope = fmt.Sprintf("timestamps of all %d packets were", c.Total)
+	}
+	msg := fmt.Sprintf("%s IEEE 754 bit-encoded; converted to nanoseconds (first %d -> %s)",
+		scope, c.FirstRaw, ass.FormatTime(c.FirstFixed))
+	if c.Uncorrected > 0 {
+		msg += fmt.Sprintf("; %d could not be decoded and were left as-is", c.Uncorrected)
+	}
+	return msg
+}
+
 // ExtractSubtitlePackets reads all packets from the demuxer, filters by trackNumber,
 // collects them into RawSubtitlePackets, and applies gap-fill for missing EndTime values.
 //
 // Returns an error wrapping the underlying demuxer read error if one occurs (other than io.EOF).
-// A warning is logged (via the returned warning string) if timestamp values appear to be
-// IEEE 754 bit-encoded rather than real nanosecond values.
+// IEEE 754 bit-encoded timestamps are corrected, and the correction is
+// described by the returned warning string (empty if none was needed).
 func ExtractSubtitlePackets(demuxer *matroska.Demuxer, trackNumber uint8) ([]RawSubtitlePacket, string, error) {
-	var packets []RawSubtitlePacket
-	var warning string
+	packets, correction, err := ReadSubtitlePackets(demuxer, trackNumber)
+	if err != nil || correction == nil {
+		return packets, "", err
+	}
+	return packets, "WARNING: " + correction.String(), nil
+}
+
+// ReadSubtitlePackets is like ExtractSubtitlePackets but reports a timestamp
+// correction as a TimestampCorrection (nil if none was needed).
+func ReadSubtitlePackets(demuxer *matroska.Demuxer, trackNumber uint8) ([]RawSubtitlePacket, *TimestampCorrection, error) {
+	read, err := trackReader{track: trackNumber}.read(demuxer)
+	if err != nil {
+		return nil, nil, err
+	}
+	ApplyGapFill(read.packets)
+	return read.packets, read.correction, nil
+}
+
+// trackReader reads one subtitle track's packets and, optionally, the frame
+// timestamps of a video track in the same pass over the file.
+type trackReader struct {
+	track uint8 // subtitle track number
+
+	// stopAt, when non-zero, ends reading after the first subtitle packet
+	// starting at or after it, which is kept so gap-fill can end the previous
+	// packet there. Video frames are read on for seekLookback more.
+	stopAt uint64
+
+	video uint8 // video track number whose frame timestamps to collect; 0 for none
+}
+
+// trackPackets is what a trackReader read.
+type trackPackets struct {
+	packets    []RawSubtitlePacket
+	frames     []uint64 // video frame timestamps in nanoseconds, in file order
+	correction *TimestampCorrection
+}
+
+// read collects the packets and corrects their timestamps, leaving missing
+// EndTime values for the caller's gap-fill policy.
+func (r trackReader) read(demuxer *matroska.Demuxer) (*trackPackets, error) {
+	var out trackPackets
+	done := false // all subtitle packets up to stopAt were read
 
 	for {
 		pkt, err := demuxer.ReadPacket()
@@ -44,34 +122,112 @@ func ExtractSubtitlePackets(demuxer *matroska.Demuxer, trackNumber uint8) ([]Raw
 			break
 		}
 		if err != nil {
-			return nil, "", fmt.Errorf("read packet error: %w", err)
+			return nil, fmt.Errorf("read packet error: %w", err)
 		}
 
-		if pkt.Track != trackNumber {
+		if r.video != 0 && pkt.Track == r.video {
+			out.frames = append(out.frames, pkt.StartTime)
+			if done && pkt.StartTime >= r.stopAt+seekLookback {
+				break
+			}
+			continue
+		}
+		if pkt.Track != r.track || done {
 			continue
 		}
 
-		raw := RawSubtitlePacket{
+		out.packets = append(out.packets, RawSubtitlePacket{
 			StartTime: pkt.StartTime,
 			EndTime:   pkt.EndTime,
 			Data:      pkt.Data,
+			Index:     len(out.packets),
+		})
+
+		if r.stopAt != 0 {
+			start := pkt.StartTime
+			if start > timestampSanityThreshold {
+				start, _ = decodeFloatTimestamp(start)
+			}
+			if start >= r.stopAt {
+				done = true
+				if r.video == 0 {
+					break
+				}
+			}
 		}
+	}
+
+	out.correction = CorrectTimestamps(out.packets)
+	return &out, nil
+}
+
+// CorrectTimestamps converts StartTime and EndTime values above the sanity
+// threshold back to nanoseconds by reinterpreting them as IEEE 754 float64
+// bits. Unlike the segment Duration that mkvinfo.SegmentDuration decodes,
+// packet times are already scaled by the demuxer (ticks * TimecodeScale), so
+// the float holds nanoseconds and no scale is applied; a float32 pattern would
+// fit in 32 bits and never reach the threshold. This handles a whole track
+// of bit-encoded times as well as individual damaged packets. The demuxer
+// computes EndTime by adding the block duration to StartTime, so an EndTime
+// a plausible duration after a bit-encoded StartTime keeps that duration;
+// other large EndTime values are decoded on their own.
+//
+// Returns nil when no packet needed correcting. Call before ApplyGapFill,
+// which sorts by StartTime.
+func CorrectTimestamps(packets []RawSubtitlePacket) *TimestampCorrection {
+	c := TimestampCorrection{Total: len(packets)}
+
+	for i := range packets {
+		pkt := &packets[i]
+		if pkt.StartTime <= timestampSanityThreshold && pkt.EndTime <= timestampSanityThreshold {
+			continue
+		}
+		rawStart := pkt.StartTime
+		ok := true
 
-		// Sanity check on first collected packet
-		if len(packets) == 0 && raw.StartTime > timestampSanityThreshold {
-			warning = fmt.Sprintf(
-				"WARNING: first subtitle packet StartTime=%d exceeds sanity threshold (%d), "+
-					"timestamps may be IEEE 754 bit-encoded rather than nanoseconds",
-				raw.StartTime, timestampSanityThreshold,
-			)
+		if pkt.StartTime > timestampSanityThreshold {
+			if start, decoded := decodeFloatTimestamp(pkt.StartTime); decoded {
+				pkt.StartTime = start
+			} else {
+				ok = false
+			}
+		}
+		if pkt.EndTime > timestampSanityThreshold {
+			switch end, decoded := decodeFloatTimestamp(pkt.EndTime); {
+			case rawStart != pkt.StartTime && pkt.EndTime >= rawStart && pkt.EndTime-rawStart <= timestampSanityThreshold:
+				pkt.EndTime = pkt.StartTime + (pkt.EndTime - rawStart)
+			case decoded:
+				pkt.EndTime = end
+			default:
+				ok = false
+			}
 		}
 
-		packets = append(packets, raw)
+		if c.Packets == 0 {
+			c.FirstRaw, c.FirstFixed = rawStart, pkt.StartTime
+		}
+		c.Packets++
+		if !ok {
+			c.Uncorrected++
+		}
 	}
 
-	ApplyGapFill(packets)
+	if c.Packets == 0 {
+		return nil
+	}
+	c.WholeTrack = c.Packets == c.Total
+	return &c
+}
 
-	return packets, warning, nil
+// decodeFloatTimestamp reinterprets v as float64 bits holding a nanosecond
+// value (not TimecodeScale ticks), reporting whether the result is a
+// plausible timestamp.
+func decodeFloatTimestamp(v uint64) (uint64, bool) {
+	f := math.Float64frombits(v)
+	if math.IsNaN(f) || f < 0 || f > float64(timestampSanityThreshold) {
+		return 0, false
+	}
+	return uint64(math.Round(f)), true
 }
 
 // ApplyGapFill fills in missing EndTime values using a gap-fill strategy:
@@ -79,23 +235,8 @@ func ExtractSubtitlePackets(demuxer *matroska.Demuxer, trackNumber uint8) ([]Raw
 //   - If a packet's EndTime <= StartTime, set EndTime to the next packet's StartTime
 //   - For the last packet with missing EndTime, set EndTime = StartTime + 5 seconds
 //   - Empty slices are handled safely (no-op)
+//
+// ApplyGapFillPolicy offers other strategies.
 func ApplyGapFill(packets []RawSubtitlePacket) {
-	if len(packets) == 0 {
-		return
-	}
-
-	// Sort by StartTime for correct gap-fill ordering
-	sort.Slice(packets, func(i, j int) bool {
-		return packets[i].StartTime < packets[j].StartTime
-	})
-
-	for i := range packets {
-		if packets[i].EndTime <= packets[i].StartTime {
-			if i+1 < len(packets) {
-				packets[i].EndTime = packets[i+1].StartTime
-			} else {
-				packets[i].EndTime = packets[i].StartTime + defaultEndTimePadding
-			}
-		}
-	}
+	ApplyGapFillPolicy(packets, "", GapFillPolicy{})
 }
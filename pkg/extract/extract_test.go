@@ -1,7 +1,11 @@
 package extract
 
 import (
+	"bytes"
+	"math"
 	"testing"
+
+	matroska "github.com/luispater/matroska-go"
 )
 
 func TestRawSubtitlePacketType(t *testing.T) {
@@ -157,3 +161,177 @@ func TestApplyGapFill_MultipleMissingMiddle(t *testing.T) {
 		t.Errorf("packet[2].EndTime = %d, want 4000000000", packets[2].EndTime)
 	}
 }
+
+func TestCorrectTimestamps_None(t *testing.T) {
+	packets := []RawSubtitlePacket{{StartTime: 1_000_000_000, EndTime: 2_000_000_000}}
+	if c := CorrectTimestamps(packets); c != nil {
+		t.Errorf("CorrectTimestamps() = %+v, want nil", c)
+	}
+}
+
+func TestCorrectTimestamps_WholeTrack(t *testing.T) {
+	packets := []RawSubtitlePacket{
+		// The demuxer adds the real block duration to the bit-encoded start.
+		{StartTime: math.Float64bits(1e9), EndTime: math.Float64bits(1e9) + 1_500_000_000},
+		{StartTime: math.Float64bits(3e9), EndTime: math.Float64bits(3e9) + 1_000_000_000},
+	}
+	c := CorrectTimestamps(packets)
+	if c == nil || !c.WholeTrack || c.Packets != 2 || c.Uncorrected != 0 {
+		t.Fatalf("CorrectTimestamps() = %+v", c)
+	}
+	if c.FirstRaw != math.Float64bits(1e9) || c.FirstFixed != 1_000_000_000 {
+		t.Errorf("first = %d -> %d", c.FirstRaw, c.FirstFixed)
+	}
+	want := []RawSubtitlePacket{
+		{StartTime: 1_000_000_000, EndTime: 2_500_000_000},
+		{StartTime: 3_000_000_000, EndTime: 4_000_000_000},
+	}
+	for i := range want {
+		if packets[i].StartTime != want[i].StartTime || packets[i].EndTime != want[i].EndTime {
+			t.Errorf("packet[%d] = %d-%d, want %d-%d", i,
+				packets[i].StartTime, packets[i].EndTime, want[i].StartTime, want[i].EndTime)
+		}
+	}
+}
+
+func TestCorrectTimestamps_PerPacket(t *testing.T) {
+	packets := []RawSubtitlePacket{
+		{StartTime: 1_000_000_000, EndTime: 2_000_000_000},
+		{StartTime: math.Float64bits(5e9), EndTime: 0},
+		{StartTime: math.MaxUint64, EndTime: 0}, // NaN bits: not decodable
+	}
+	c := CorrectTimestamps(packets)
+	if c == nil || c.WholeTrack || c.Packets != 2 || c.Total != 3 || c.Uncorrected != 1 {
+		t.Fatalf("CorrectTimestamps() = %+v", c)
+	}
+	if packets[1].StartTime != 5_000_000_000 {
+		t.Errorf("packet[1].StartTime = %d, want 5000000000", packets[1].StartTime)
+	}
+	if packets[2].StartTime != math.MaxUint64 {
+		t.Errorf("undecodable StartTime changed to %d", packets[2].StartTime)
+	}
+	want := "timestamps of 2 of 3 packets were IEEE 754 bit-encoded; converted to nanoseconds " +
+		"(first 4752036887248502784 -> 0:00:05.00); 1 could not be decoded and were left as-is"
+	if got := c.String(); got != want {
+		t.Errorf("String() = %q\nwant       %q", got, want)
+	}
+}
+
+func TestApplyGapFillPolicy(t *testing.T) {
+	const s = uint64(1_000_000_000)
+	newPackets := func() []RawSubtitlePacket {
+		return []RawSubtitlePacket{
+			{StartTime: 1 * s, EndTime: 0, Data: []byte("Short"), Index: 0},
+			{StartTime: 2 * s, EndTime: 3 * s, Data: []byte("has duration"), Index: 1},
+			{StartTime: 600 * s, EndTime: 0, Data: []byte("<i>Thirty characters of text!!!!!</i>"), Index: 2},
+			{StartTime: 610 * s, EndTime: 0, Data: []byte("Last"), Index: 3},
+		}
+	}
+	tests := []struct {
+		name   string
+		policy GapFillPolicy
+		want   []uint64 // EndTime of packets 0, 2 and 3
+	}{
+		{"next-start", GapFillPolicy{}, []uint64{2 * s, 610 * s, 615 * s}},
+		{"next-start to segment end", GapFillPolicy{SegmentEnd: 700 * s}, []uint64{2 * s, 610 * s, 700 * s}},
+		{"fixed", GapFillPolicy{Mode: GapFillFixed, Duration: 3 * s}, []uint64{4 * s, 603 * s, 613 * s}},
+		{"capped", GapFillPolicy{Mode: GapFillCapped, Duration: 4 * s}, []uint64{2 * s, 604 * s, 614 * s}},
+		{"reading speed", GapFillPolicy{Mode: GapFillReadingSpeed, CPS: 10}, []uint64{2 * s, 603 * s, 611 * s}},
+		{"reading speed capped", GapFillPolicy{Mode: GapFillReadingSpeed, CPS: 10, Duration: 2 * s, SegmentEnd: 610*s + s/2}, []uint64{2 * s, 602 * s, 610*s + s/2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packets := newPackets()
+			filled := ApplyGapFillPolicy(packets, "S_TEXT/UTF8", tt.policy)
+
+			if len(filled) != 3 {
+				t.Fatalf("filled %d packets, want 3: %+v", len(filled), filled)
+			}
+			for i, idx := range []int{0, 2, 3} {
+				if packets[idx].EndTime != tt.want[i] {
+					t.Errorf("packet[%d].EndTime = %d, want %d", idx, packets[idx].EndTime, tt.want[i])
+				}
+				if f := filled[i]; f.PacketIndex != idx || f.End != tt.want[i] {
+					t.Errorf("filled[%d] = %+v", i, f)
+				}
+			}
+			if packets[1].EndTime != 3*s {
+				t.Errorf("packet with a duration was changed: %d", packets[1].EndTime)
+			}
+		})
+	}
+}
+
+func TestApplyGapFillPolicy_ASSTextLength(t *testing.T) {
+	packets := []RawSubtitlePacket{
+		{StartTime: 0, Data: []byte(`0,0,Default,,0,0,0,,{\i1}Twenty characters!!!{\i0}`)},
+	}
+	ApplyGapFillPolicy(packets, "S_TEXT/ASS", GapFillPolicy{Mode: GapFillReadingSpeed, CPS: 10})
+	if packets[0].EndTime != 2_000_000_000 {
+		t.Errorf("EndTime = %d, want 2000000000 (20 chars at 10 CPS)", packets[0].EndTime)
+	}
+}
+
+func TestParseGapFillMode(t *testing.T) {
+	for _, name := range []string{"next-start", "fixed", "capped", "reading-speed"} {
+		mode, err := ParseGapFillMode(name)
+		if err != nil || mode.String() != name {
+			t.Errorf("ParseGapFillMode(%q) = %v, %v", name, mode, err)
+		}
+	}
+	if _, err := ParseGapFillMode("forever"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+// ebmlElement encodes an EBML element with an 8-byte size.
+func ebmlElement(id []byte, body ...[]byte) []byte {
+	var data []byte
+	for _, b := range body {
+		data = append(data, b...)
+	}
+	size := uint64(len(data)) | 1<<56
+	out := append([]byte{}, id...)
+	for shift := 56; shift >= 0; shift -= 8 {
+		out = append(out, byte(size>>shift))
+	}
+	return append(out, data...)
+}
+
+func TestReadSubtitlePackets_DemuxerUnit(t *testing.T) {
+	// TimecodeScale 100000 (0.1 ms ticks); a block at cluster 1000 + 500
+	// ticks lasting 200 ticks.
+	file := append(
+		ebmlElement([]byte{0x1A, 0x45, 0xDF, 0xA3},
+			ebmlElement([]byte{0x42, 0x82}, []byte("matroska"))),
+		ebmlElement([]byte{0x18, 0x53, 0x80, 0x67},
+			ebmlElement([]byte{0x15, 0x49, 0xA9, 0x66},
+				ebmlElement([]byte{0x2A, 0xD7, 0xB1}, []byte{0x01, 0x86, 0xA0})),
+			ebmlElement([]byte{0x16, 0x54, 0xAE, 0x6B},
+				ebmlElement([]byte{0xAE},
+					ebmlElement([]byte{0xD7}, []byte{1}),
+					ebmlElement([]byte{0x83}, []byte{0x11}),
+					ebmlElement([]byte{0x86}, []byte("S_TEXT/UTF8")))),
+			ebmlElement([]byte{0x1F, 0x43, 0xB6, 0x75},
+				ebmlElement([]byte{0xE7}, []byte{0x03, 0xE8}),
+				ebmlElement([]byte{0xA0},
+					ebmlElement([]byte{0xA1}, []byte{0x81, 0x01, 0xF4, 0x00}, []byte("Hi")),
+					ebmlElement([]byte{0x9B}, []byte{0xC8}))))...)
+
+	demuxer, err := matroska.NewDemuxer(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, correction, err := ReadSubtitlePackets(demuxer, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The demuxer has already applied TimecodeScale: times are integer
+	// nanoseconds, not ticks, so CorrectTimestamps decodes no scale.
+	if len(packets) != 1 || correction != nil {
+		t.Fatalf("packets = %+v, correction = %+v", packets, correction)
+	}
+	if p := packets[0]; p.StartTime != 150_000_000 || p.EndTime != 170_000_000 || string(p.Data) != "Hi" {
+		t.Errorf("packet = %d-%d %q, want 150000000-170000000 \"Hi\"", p.StartTime, p.EndTime, p.Data)
+	}
+}
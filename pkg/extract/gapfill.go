@@ -0,0 +1,192 @@
+package extract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// GapFillMode selects how a missing EndTime (no BlockDuration) is filled.
+type GapFillMode int
+
+const (
+	// GapFillNextStart ends the packet where the next one starts; the last
+	// packet gets the policy's SegmentEnd, or 5 seconds. This is the
+	// behaviour of ApplyGapFill.
+	GapFillNextStart GapFillMode = iota
+
+	// GapFillFixed gives the packet a fixed duration, even if it overlaps
+	// the next packet.
+	GapFillFixed
+
+	// GapFillCapped ends the packet where the next one starts, but no later
+	// than a maximum duration after its start.
+	GapFillCapped
+
+	// GapFillReadingSpeed sizes the duration to the text length at a
+	// characters-per-second reading speed.
+	GapFillReadingSpeed
+)
+
+// gapFillModeNames maps CLI names to gap-fill modes.
+var gapFillModeNames = map[string]GapFillMode{
+	"next-start":    GapFillNextStart,
+	"fixed":         GapFillFixed,
+	"capped":        GapFillCapped,
+	"reading-speed": GapFillReadingSpeed,
+}
+
+// ParseGapFillMode parses "next-start", "fixed", "capped" or "reading-speed".
+func ParseGapFillMode(s string) (GapFillMode, error) {
+	mode, ok := gapFillModeNames[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("unknown gap-fill mode %q (available: next-start, fixed, capped, reading-speed)", s)
+	}
+	return mode, nil
+}
+
+// String returns the CLI name of the mode.
+func (m GapFillMode) String() string {
+	for name, mode := range gapFillModeNames {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("GapFillMode(%d)", int(m))
+}
+
+// defaultReadingSpeed is the reading speed, in characters per second, used by
+// GapFillReadingSpeed when the policy sets none.
+const defaultReadingSpeed = 15
+
+// defaultMinReadingDuration is the shortest duration GapFillReadingSpeed
+// gives a packet when the policy sets no MinDuration (1 second).
+const defaultMinReadingDuration uint64 = 1_000_000_000
+
+// GapFillPolicy controls how missing EndTime values are filled. The zero
+// value is GapFillNextStart with a 5 second end for the last packet.
+//
+// Capped and reading-speed durations never run past the next packet's start,
+// so a filled line does not stack on top of the following one; a fixed
+// duration is kept as given.
+type GapFillPolicy struct {
+	Mode GapFillMode
+
+	// Duration is the fixed duration for GapFillFixed and the maximum for
+	// GapFillCapped (both default to 5 seconds) and GapFillReadingSpeed (no
+	// maximum by default), in nanoseconds.
+	Duration uint64
+
+	// CPS is the reading speed for GapFillReadingSpeed in characters per
+	// second; zero means 15. MinDuration is its shortest duration in
+	// nanoseconds; zero means 1 second.
+	CPS         float64
+	MinDuration uint64
+
+	// SegmentEnd, when non-zero, is the end of the segment in nanoseconds
+	// (see mkvinfo.SegmentDuration). The last packet ends there under
+	// GapFillNextStart, and no packet is filled past it.
+	SegmentEnd uint64
+}
+
+// FilledPacket records a packet whose EndTime was filled by a gap-fill policy.
+type FilledPacket struct {
+	PacketIndex int    `json:"packet_index"` // RawSubtitlePacket.Index: position in the track as read
+	Start       uint64 `json:"start_ns"`
+	End         uint64 `json:"end_ns"` // the filled EndTime
+}
+
+// ApplyGapFillPolicy sorts packets by StartTime and fills every EndTime that
+// is not after its StartTime according to policy. codecID tells
+// GapFillReadingSpeed how to find the text in the packet data.
+//
+// Returns the filled packets in order.
+func ApplyGapFillPolicy(packets []RawSubtitlePacket, codecID string, policy GapFillPolicy) []FilledPacket {
+	if len(packets) == 0 {
+		return nil
+	}
+
+	// Sort by StartTime for correct gap-fill ordering
+	sort.SliceStable(packets, func(i, j int) bool {
+		return packets[i].StartTime < packets[j].StartTime
+	})
+
+	var filled []FilledPacket
+	for i := range packets {
+		pkt := &packets[i]
+		if pkt.EndTime > pkt.StartTime {
+			continue
+		}
+
+		hasNext := i+1 < len(packets)
+		var next uint64
+		if hasNext {
+			next = packets[i+1].StartTime
+		}
+		pkt.EndTime = policy.end(pkt.StartTime, next, hasNext, func() int {
+			return textLength(pkt.Data, codecID)
+		})
+		filled = append(filled, FilledPacket{PacketIndex: pkt.Index, Start: pkt.StartTime, End: pkt.EndTime})
+	}
+	return filled
+}
+
+// end computes the filled EndTime of a packet starting at start. next is the
+// next packet's start when hasNext; length returns the text length.
+func (p GapFillPolicy) end(start, next uint64, hasNext bool, length func() int) uint64 {
+	// Filled times stop at the segment end, and all but fixed durations
+	// also at the next packet's start.
+	limit, hasLimit := next, hasNext && p.Mode != GapFillFixed
+	if p.SegmentEnd > start && (!hasLimit || p.SegmentEnd < limit) {
+		limit, hasLimit = p.SegmentEnd, true
+	}
+
+	var d uint64
+	switch p.Mode {
+	case GapFillFixed, GapFillCapped:
+		d = p.Duration
+		if d == 0 {
+			d = defaultEndTimePadding
+		}
+	case GapFillReadingSpeed:
+		cps, minDuration := p.CPS, p.MinDuration
+		if cps <= 0 {
+			cps = defaultReadingSpeed
+		}
+		if minDuration == 0 {
+			minDuration = defaultMinReadingDuration
+		}
+		d = max(uint64(float64(length())/cps*1e9), minDuration)
+		if p.Duration > 0 {
+			d = min(d, p.Duration)
+		}
+	default:
+		if hasLimit {
+			return limit
+		}
+		return start + defaultEndTimePadding
+	}
+
+	if hasLimit && limit-start < d {
+		return limit
+	}
+	return start + d
+}
+
+// textLength counts the visible characters of a packet: the Text field of an
+// ASS/SSA block, or SRT text, without markup.
+func textLength(data []byte, codecID string) int {
+	text := string(data)
+	if codecID == "S_TEXT/ASS" || codecID == "S_TEXT/SSA" {
+		if _, _, remaining, err := subtitle.ParseASSBlockData(data); err == nil {
+			text = strings.SplitN(remaining, ",", 7)[6]
+		}
+	} else {
+		text = subtitle.ConvertSRTTagsToASS(text)
+	}
+	plain := subtitle.ParseASSText(text).PlainText()
+	return utf8.RuneCountInString(strings.Join(strings.Fields(plain), " "))
+}
@@ -0,0 +1,100 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"mkv-sub-extractor/pkg/assout"
+	"mkv-sub-extractor/pkg/mkvinfo"
+)
+
+// ExtractJoinedTrack extracts a track from a title split across several MKV
+// files (part1.mkv, part2.mkv, ...) and writes it as one file on the joined
+// timeline, named after the first part. mkvPaths lists the parts in playback
+// order; track is a track of the first.
+//
+// Each later part contributes its track matching track (see MatchTrack) and
+// is shifted by the summed segment Durations of the parts before it. ASS/SSA
+// headers are merged as by assout.WriteJoinedASS, renaming conflicting
+// styles. A part without a matching track, or without a Duration to place
+// the parts after it, is an error. opts.Range and opts.JoinParts are ignored.
+func ExtractJoinedTrack(mkvPaths []string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, opts Options) (*Result, error) {
+	if len(mkvPaths) == 0 {
+		return nil, fmt.Errorf("no parts to join")
+	}
+	opts.Range, opts.JoinParts = TimeRange{}, nil
+
+	var parts []*trackEvents
+	var res Result
+	var offset time.Duration
+	readOrder := 0
+	for i, path := range mkvPaths {
+		info, err := mkvinfo.GetMKVInfo(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		srcTrack := track
+		if i > 0 {
+			var ok bool
+			if srcTrack, ok = MatchTrack(info.Tracks, track); !ok {
+				return nil, fmt.Errorf("%s: no %s %s subtitle track to join", filepath.Base(path), track.Language, track.FormatType)
+			}
+		}
+
+		o := opts
+		o.Chinese = opts.Chinese.sameAs(track, srcTrack)
+		o.Encoding = opts.Encoding.sameAs(track, srcTrack)
+		te, err := readTrackEvents(path, srcTrack, o)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		for j := range te.events {
+			te.events[j].Start += uint64(offset)
+			te.events[j].End += uint64(offset)
+			te.events[j].ReadOrder += readOrder
+		}
+		readOrder += len(te.events)
+		opts.Retime.ApplyEvents(te.events)
+
+		res.merge(te.result)
+		parts = append(parts, te)
+
+		if i+1 < len(mkvPaths) && info.Info.Duration <= 0 {
+			return nil, fmt.Errorf("%s: segment has no Duration to join the next part after", filepath.Base(path))
+		}
+		offset += info.Info.Duration
+	}
+
+	outputPath := outputPathFor(mkvPaths[0], opts.Chinese.named(track), outputDir, existingPaths, nil)
+	if err := writeJoined(parts, outputPath, opts); err != nil {
+		return nil, err
+	}
+	res.OutputPath = outputPath
+	return &res, nil
+}
+
+// writeJoined writes the parts of a track as one file at outputPath. ASS/SSA
+// parts have their headers merged; SRT parts share the generated header of
+// the first.
+func writeJoined(parts []*trackEvents, outputPath string, opts Options) error {
+	first := parts[0]
+	switch first.track.CodecID {
+	case "S_TEXT/ASS", "S_TEXT/SSA":
+		joined := make([]assout.JoinPart, len(parts))
+		for i, te := range parts {
+			joined[i] = assout.JoinPart{CodecPrivate: te.codecPrivate, Events: te.events}
+		}
+		return createOutput(outputPath, func(w io.Writer) error {
+			return assout.WriteJoinedASS(w, joined, first.assOptions(opts))
+		})
+	default:
+		all := *first
+		all.events = nil
+		for _, te := range parts {
+			all.events = append(all.events, te.events...)
+		}
+		return all.write(outputPath, opts)
+	}
+}
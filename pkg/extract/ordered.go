@@ -0,0 +1,218 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// MatchTrack returns the track among tracks that carries the same subtitles
+// as want in another file: same language and codec, preferring the same
+// track name. Track numbers and indices are not compared, as they differ
+// between files.
+func MatchTrack(tracks []mkvinfo.SubtitleTrack, want mkvinfo.SubtitleTrack) (mkvinfo.SubtitleTrack, bool) {
+	var match mkvinfo.SubtitleTrack
+	found := false
+	for _, t := range tracks {
+		if t.Language != want.Language || t.CodecID != want.CodecID {
+			continue
+		}
+		if t.Name == want.Name {
+			return t, true
+		}
+		if !found {
+			match, found = t, true
+		}
+	}
+	return match, found
+}
+
+// segmentFile is an MKV file taking part in an ordered edition.
+type segmentFile struct {
+	path string
+	info *mkvinfo.MKVInfo
+}
+
+// orderedTimeline resolves the chapters of an ordered edition to the files
+// they play from.
+type orderedTimeline struct {
+	main    segmentFile
+	mainUID []byte
+	linked  map[string]string // hex SegmentUID -> path, once the directory was scanned
+	files   map[string]*segmentFile
+}
+
+// ExtractOrderedChapters is like ExtractTrack but follows the ordered
+// chapters of the file's default edition, as a player does: chapters play
+// in the order listed, each from its own time span, and chapters with a
+// SegmentUID play from the linked MKV file with that SegmentUID in the same
+// directory (typically a shared opening or ending). The output holds every
+// chapter's events shifted to its place on that timeline.
+//
+// Events of a linked file come from its track matching track (see
+// MatchTrack); a linked file without one contributes no events. Linked
+// files that cannot be found are skipped, as players skip them, and listed
+// in Result.MissingSegments. The ASS header is track's, or a linked file's
+// when no chapter plays from the main file. Files without an ordered
+// default edition are extracted as by ExtractTrack.
+func ExtractOrderedChapters(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, opts Options) (*Result, error) {
+	editions, err := mkvinfo.ReadChapters(mkvPath)
+	if err != nil {
+		return nil, err
+	}
+	ed := mkvinfo.DefaultEdition(editions)
+	if ed == nil || !ed.Ordered {
+		return extractTrackToASS(mkvPath, track, outputDir, existingPaths, opts)
+	}
+
+	uid, err := mkvinfo.ReadSegmentUID(mkvPath)
+	if err != nil {
+		return nil, err
+	}
+	tl := &orderedTimeline{
+		main:    segmentFile{path: mkvPath},
+		mainUID: uid,
+		files:   make(map[string]*segmentFile),
+	}
+
+	var events []subtitle.SubtitleEvent
+	var header *trackEvents // supplies the ASS header, preferably from the main file
+	headerIsMain := false
+	var res Result
+	var offset uint64
+	for _, ch := range ed.PlaybackChapters() {
+		file, err := tl.file(ch.SegmentUID)
+		if err != nil {
+			return nil, err
+		}
+		if file == nil {
+			if uid := hex.EncodeToString(ch.SegmentUID); !slices.Contains(res.MissingSegments, uid) {
+				res.MissingSegments = append(res.MissingSegments, uid)
+			}
+			continue
+		}
+
+		end := ch.End
+		if end <= ch.Start {
+			if err := file.load(); err != nil {
+				return nil, err
+			}
+			end = uint64(file.info.Info.Duration)
+		}
+		if end <= ch.Start {
+			continue
+		}
+
+		srcTrack, ok := track, true
+		if file != &tl.main {
+			if err := file.load(); err != nil {
+				return nil, err
+			}
+			srcTrack, ok = MatchTrack(file.info.Tracks, track)
+		}
+		if ok {
+			o := opts
+			o.Range = clipRange(ch.Start, end)
+			o.Chinese = opts.Chinese.sameAs(track, srcTrack)
+			o.Encoding = opts.Encoding.sameAs(track, srcTrack)
+			te, err := readTrackEvents(file.path, srcTrack, o)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", filepath.Base(file.path), err)
+			}
+			rebaseEvents(o.Range, te)
+			for i := range te.events {
+				te.events[i].Start += offset
+				te.events[i].End += offset
+			}
+
+			res.merge(te.result)
+			if header == nil || (file == &tl.main && !headerIsMain) {
+				header, headerIsMain = te, file == &tl.main
+			}
+			events = append(events, te.events...)
+		}
+		offset += end - ch.Start
+	}
+	if header == nil {
+		return nil, fmt.Errorf("ordered chapters: no chapter has a matching subtitle track")
+	}
+
+	header.track, header.events = track, events
+	opts.Retime.ApplyEvents(header.events)
+	outputPath := outputPathFor(mkvPath, opts.Chinese.named(track), outputDir, existingPaths, nil)
+	if err := header.write(outputPath, opts); err != nil {
+		return nil, err
+	}
+	res.OutputPath = outputPath
+	return &res, nil
+}
+
+// file returns the file a chapter with segmentUID plays from, or nil if it
+// is a linked file that cannot be found.
+func (tl *orderedTimeline) file(segmentUID []byte) (*segmentFile, error) {
+	if segmentUID == nil || bytes.Equal(segmentUID, tl.mainUID) {
+		return &tl.main, nil
+	}
+	if tl.linked == nil {
+		linked, err := linkedSegments(filepath.Dir(tl.main.path), tl.main.path)
+		if err != nil {
+			return nil, err
+		}
+		tl.linked = linked
+	}
+	path, ok := tl.linked[hex.EncodeToString(segmentUID)]
+	if !ok {
+		return nil, nil
+	}
+	if f, ok := tl.files[path]; ok {
+		return f, nil
+	}
+	f := &segmentFile{path: path}
+	tl.files[path] = f
+	return f, nil
+}
+
+// load reads the file's track list and duration, once.
+func (f *segmentFile) load() error {
+	if f.info != nil {
+		return nil
+	}
+	info, err := mkvinfo.GetMKVInfo(f.path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(f.path), err)
+	}
+	f.info = info
+	return nil
+}
+
+// linkedSegments maps the hex SegmentUIDs of the MKV files in dir, other
+// than self, to their paths. Files that cannot be read are left out.
+func linkedSegments(dir, self string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("find linked segments: %w", err)
+	}
+	linked := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".mkv") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if filepath.Clean(path) == filepath.Clean(self) {
+			continue
+		}
+		uid, err := mkvinfo.ReadSegmentUID(path)
+		if err != nil || uid == nil {
+			continue
+		}
+		linked[hex.EncodeToString(uid)] = path
+	}
+	return linked, nil
+}
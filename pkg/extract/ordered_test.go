@@ -0,0 +1,55 @@
+package extract
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+)
+
+func TestMatchTrack(t *testing.T) {
+	want := mkvinfo.SubtitleTrack{Number: 3, Language: "eng", CodecID: "S_TEXT/ASS", Name: "Full"}
+	tracks := []mkvinfo.SubtitleTrack{
+		{Number: 2, Language: "eng", CodecID: "S_TEXT/UTF8", Name: "Full"},
+		{Number: 3, Language: "eng", CodecID: "S_TEXT/ASS", Name: "Signs"},
+		{Number: 4, Language: "eng", CodecID: "S_TEXT/ASS", Name: "Full"},
+	}
+	if got, ok := MatchTrack(tracks, want); !ok || got.Number != 4 {
+		t.Errorf("MatchTrack() = track %d, %v; want track 4", got.Number, ok)
+	}
+	if got, ok := MatchTrack(tracks[:2], want); !ok || got.Number != 3 {
+		t.Errorf("MatchTrack() without a name match = track %d, %v; want track 3", got.Number, ok)
+	}
+	want.Language = "jpn"
+	if _, ok := MatchTrack(tracks, want); ok {
+		t.Error("MatchTrack() matched another language")
+	}
+}
+
+func TestLinkedSegments(t *testing.T) {
+	dir := t.TempDir()
+	mkv := func(name string, uid []byte) string {
+		info := element([]byte{0x15, 0x49, 0xA9, 0x66}, element([]byte{0x73, 0xA4}, uid))
+		data := append(element([]byte{0x1A, 0x45, 0xDF, 0xA3}), element([]byte{0x18, 0x53, 0x80, 0x67}, info)...)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	main := mkv("ep01.mkv", []byte{1})
+	op := mkv("OP.MKV", []byte{0xAB, 0xCD})
+	mkv("notes.txt", []byte{2})
+
+	linked, err := linkedSegments(dir, main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{hex.EncodeToString([]byte{0xAB, 0xCD}): op}
+	if !reflect.DeepEqual(linked, want) {
+		t.Errorf("linkedSegments() = %v, want %v", linked, want)
+	}
+}
@@ -0,0 +1,137 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"mkv-sub-extractor/pkg/corpus"
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/output"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// PairsOptions controls ExtractPairs.
+type PairsOptions struct {
+	Format  corpus.Format
+	Context bool                  // add source, episode and chapter columns
+	Align   subtitle.AlignOptions // how events are paired by time overlap
+}
+
+// ExtractPairs extracts two tracks of an MKV file, the same dialogue in two
+// languages, pairs their events by time overlap (see subtitle.Align) and
+// writes the pairs as CSV or TSV (see corpus.Write), one row per pair found
+// in both tracks. Text is reduced to the spoken words: ASS override tags,
+// drawings and SRT markup are removed, and events left without text, such
+// as drawn signs, are skipped. The file is named after both languages (see
+// output.GeneratePairsOutputPath).
+//
+// With opts.Pairs.Context, the chapter column holds the title of the
+// default edition's chapter each pair starts in (at its time in the file,
+// before opts.Range rebases it), and the episode column is guessed from the
+// file name (see corpus.EpisodeFromPath).
+func ExtractPairs(mkvPath string, a, b mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, opts Options) (*Result, error) {
+	var po PairsOptions
+	if opts.Pairs != nil {
+		po = *opts.Pairs
+	}
+
+	var tracks [2]*trackEvents
+	var res Result
+	for i, track := range []mkvinfo.SubtitleTrack{a, b} {
+		te, err := readTrackEvents(mkvPath, track, opts)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: %w", track.Number, err)
+		}
+		tracks[i] = te
+		res.merge(te.result)
+	}
+	offset := rebaseEvents(opts.Range, tracks[:]...)
+	var events [2][]subtitle.SubtitleEvent
+	for i, te := range tracks {
+		events[i] = spokenEvents(te.events, te.track.CodecID == "S_TEXT/UTF8")
+	}
+
+	var chapters []mkvinfo.Chapter
+	if po.Context {
+		editions, err := mkvinfo.ReadChapters(mkvPath)
+		if err != nil {
+			return nil, err
+		}
+		if ed := mkvinfo.DefaultEdition(editions); ed != nil {
+			chapters = ed.ListedChapters()
+		}
+	}
+
+	var rows []corpus.Row
+	for _, p := range subtitle.Align(events[0], events[1], po.Align) {
+		if !p.Matched() {
+			continue
+		}
+		row := corpus.Row{
+			Start: opts.Retime.Apply(p.Start),
+			End:   opts.Retime.Apply(p.End),
+			TextA: joinText(p.A),
+			TextB: joinText(p.B),
+		}
+		if po.Context {
+			row.Source = filepath.Base(mkvPath)
+			row.Episode = corpus.EpisodeFromPath(mkvPath)
+			row.Chapter = chapterAt(chapters, p.Start+offset, a.EffectiveLanguage())
+		}
+		rows = append(rows, row)
+	}
+
+	if outputDir == "" {
+		outputDir = filepath.Dir(mkvPath)
+	}
+	videoForNaming := filepath.Join(outputDir, filepath.Base(mkvPath))
+	a, b = opts.Chinese.named(a), opts.Chinese.named(b)
+	outputPath := output.GeneratePairsOutputPath(videoForNaming, a, b, po.Format.Ext(), existingPaths)
+	err := createOutput(outputPath, func(w io.Writer) error {
+		return corpus.Write(w, rows, corpus.Options{Format: po.Format, LangA: a.EffectiveLanguage(), LangB: b.EffectiveLanguage(), Context: po.Context})
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.OutputPath = outputPath
+	return &res, nil
+}
+
+// spokenEvents returns the events with their text reduced to the spoken
+// words (see subtitle.SpokenText), leaving out those without any. SRT text is
+// converted to ASS first so its markup is removed too.
+func spokenEvents(events []subtitle.SubtitleEvent, srt bool) []subtitle.SubtitleEvent {
+	var spoken []subtitle.SubtitleEvent
+	for _, ev := range events {
+		text := ev.Text
+		if srt {
+			text = subtitle.ConvertSRTTagsToASS(text)
+		}
+		if ev.Text = subtitle.SpokenText(text); ev.Text != "" {
+			spoken = append(spoken, ev)
+		}
+	}
+	return spoken
+}
+
+// joinText joins the text of the events of one side of a pair.
+func joinText(events []subtitle.SubtitleEvent) string {
+	texts := make([]string, len(events))
+	for i, ev := range events {
+		texts[i] = ev.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// chapterAt returns the title, in lang where possible, of the chapter
+// containing t, or "" if none does.
+func chapterAt(chapters []mkvinfo.Chapter, t uint64, lang string) string {
+	for _, c := range chapters {
+		if c.SegmentUID == nil && t >= c.Start && (c.End == 0 || t < c.End) {
+			return c.Title(lang)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package extract
+
+import (
+	"testing"
+
+	"mkv-sub-extractor/pkg/mkvinfo"
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+func TestSpokenEvents(t *testing.T) {
+	events := []subtitle.SubtitleEvent{
+		{Start: 0, Text: `{\an8}Hello,\Nworld`},
+		{Start: 1, Text: `{\p1}m 0 0 l 100 0 100 100{\p0}`},
+		{Start: 2, Text: `{\i1}Bye{\i0}`},
+	}
+	got := spokenEvents(events, false)
+	if len(got) != 2 || got[0].Text != "Hello, world" || got[1].Text != "Bye" {
+		t.Errorf("spokenEvents(ASS) = %+v", got)
+	}
+
+	srt := []subtitle.SubtitleEvent{{Text: "<i>Hi</i>\nthere"}, {Text: "<b></b>"}}
+	got = spokenEvents(srt, true)
+	if len(got) != 1 || got[0].Text != "Hi there" {
+		t.Errorf("spokenEvents(SRT) = %+v", got)
+	}
+	if srt[0].Text != "<i>Hi</i>\nthere" {
+		t.Errorf("spokenEvents() modified its input: %q", srt[0].Text)
+	}
+}
+
+func TestChapterAt(t *testing.T) {
+	title := func(s, lang string) []mkvinfo.ChapterDisplay {
+		return []mkvinfo.ChapterDisplay{{String: s, Languages: []string{lang}}}
+	}
+	chapters := []mkvinfo.Chapter{
+		{Start: 0, End: 100, Displays: append(title("Prologue", "eng"), title("序章", "jpn")...)},
+		{Start: 100, End: 200, SegmentUID: []byte{1}, Displays: title("OP", "eng")},
+		{Start: 200, Displays: title("Part A", "eng")},
+	}
+	tests := []struct {
+		t    uint64
+		lang string
+		want string
+	}{
+		{50, "jpn", "序章"},
+		{50, "fre", "Prologue"},
+		{150, "eng", ""}, // plays from another segment
+		{500, "eng", "Part A"},
+	}
+	for _, tt := range tests {
+		if got := chapterAt(chapters, tt.t, tt.lang); got != tt.want {
+			t.Errorf("chapterAt(%d, %s) = %q, want %q", tt.t, tt.lang, got, tt.want)
+		}
+	}
+}
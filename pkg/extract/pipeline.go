@@ -7,9 +7,9 @@ package extract
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
 
 	matroska "github.com/luispater/matroska-go"
 
@@ -17,26 +17,181 @@ import (
 	"mkv-sub-extractor/pkg/mkvinfo"
 	"mkv-sub-extractor/pkg/output"
 	"mkv-sub-extractor/pkg/subtitle"
+	"mkv-sub-extractor/pkg/vfr"
 )
 
+// Options controls optional extraction behaviour. The zero value reproduces
+// the default output of ExtractTrackToASS.
+type Options struct {
+	// StyleTemplate replaces the generated header for SRT-to-ASS conversion.
+	// Nil uses assout.DefaultStyleTemplate. ASS/SSA tracks keep their own styles.
+	StyleTemplate *assout.StyleTemplate
+
+	// LanguageFonts enables language-aware fonts for SRT-to-ASS conversion,
+	// keyed by the track language. Nil keeps the template's font.
+	LanguageFonts assout.LanguageFonts
+
+	// KeepPlayRes keeps the style template's PlayRes for SRT-to-ASS
+	// conversion. By default the header follows the video track's resolution
+	// (see assout.VideoResolution), scaling fonts and margins to match.
+	KeepPlayRes bool
+
+	// ResampleASS resamples ASS/SSA tracks to the video track's resolution
+	// (see assout.Resample). By default their headers are copied unchanged.
+	ResampleASS bool
+
+	// ResampleTo resamples ASS/SSA tracks to this resolution instead of the
+	// video's. Non-zero implies ResampleASS.
+	ResampleTo assout.Resolution
+
+	// Aspect selects how resampling handles an aspect ratio change.
+	Aspect assout.AspectMode
+
+	// Lenient repairs or skips malformed ASS/SSA packets instead of failing
+	// the whole track, reporting each one in Result.Diagnostics. Short block
+	// data is kept as Default-style text; a bad ReadOrder or Layer is
+	// defaulted; empty blocks are dropped.
+	Lenient bool
+
+	// GapFill is the policy for packets without a BlockDuration. The zero
+	// value ends each at the next packet's start (see GapFillPolicy).
+	GapFill GapFillPolicy
+
+	// GapFillToSegmentEnd sets GapFill.SegmentEnd from the file's segment
+	// Duration, so the last packet can last until the end of the video.
+	GapFillToSegmentEnd bool
+
+	// Range extracts only the events overlapping a time range, seeking past
+	// the clusters before it. The zero value extracts the whole track.
+	Range TimeRange
+
+	// Retime transforms event times, and the times of animated override
+	// tags, before writing (see subtitle.Retime). Range is applied to the
+	// original times. The zero value keeps times unchanged.
+	Retime subtitle.Retime
+
+	// SnapToFrames moves event start and end times onto the frame
+	// boundaries of the video track, read from its block timestamps so
+	// variable frame rate video is handled (see vfr.Timecodes.Snap).
+	// Snapping happens before Retime.
+	SnapToFrames bool
+
+	// Timing pads, links and snaps event times to keyframes as a timing
+	// post-processor (see vfr.PostProcess), using the video track's
+	// keyframes. It runs before SnapToFrames and Retime. The zero value
+	// leaves times unchanged.
+	Timing vfr.TimingOptions
+
+	// SplitChapters asks callers to extract with ExtractTrackChapters, one
+	// file per chapter, instead of ExtractTrack. ChapterRanges groups the
+	// chapters into files; nil gives each chapter its own.
+	SplitChapters bool
+	ChapterRanges []ChapterRange
+
+	// OrderedChapters follows the ordered chapters of the default edition,
+	// including linked segment files (see ExtractOrderedChapters).
+	OrderedChapters bool
+
+	// Bilingual asks callers to extract the selected tracks in pairs with
+	// ExtractBilingual, one dual-language file per pair, instead of
+	// ExtractTrack. BilingualLayout places and styles the two tracks; its
+	// Template and PlayRes come from StyleTemplate and KeepPlayRes, and fonts
+	// it leaves empty come from LanguageFonts when that is set.
+	Bilingual       bool
+	BilingualLayout assout.BilingualOptions
+
+	// Pairs asks callers to export the selected tracks in pairs with
+	// ExtractPairs, as aligned sentence pairs in CSV or TSV, instead of
+	// ExtractTrack. Nil extracts subtitles as usual.
+	Pairs *PairsOptions
+
+	// Chinese converts Chinese tracks between Simplified and Traditional
+	// characters (see ChineseOptions). The zero value converts none.
+	Chinese ChineseOptions
+
+	// Encoding selects the encoding of text tracks that are not valid UTF-8
+	// (see EncodingOptions). The zero value detects it.
+	Encoding EncodingOptions
+
+	// JoinParts lists the files holding the later parts of a title split
+	// across several MKV files, in playback order. ExtractTrack then joins
+	// the track with its counterparts in these files (see ExtractJoinedTrack).
+	JoinParts []string
+
+	part *ChapterPart // set by ExtractTrackChapters to name the output
+}
+
+// Result describes the outcome of extracting a single subtitle track.
+type Result struct {
+	OutputPath  string       // path of the written ASS file
+	Diagnostics []Diagnostic // malformed packets repaired or skipped (lenient mode only)
+
+	// Timestamps reports IEEE 754 bit-encoded packet timestamps that were
+	// converted back to nanoseconds; nil if the track's timestamps were sane.
+	Timestamps *TimestampCorrection
+	// Filled lists the packets whose missing end times were filled by the
+	// gap-fill policy.
+	Filled []FilledPacket
+	// MissingSegments lists the hex SegmentUIDs of linked files that ordered
+	// chapters refer to but that were not found (see ExtractOrderedChapters).
+	MissingSegments []string
+	// Transcoded reports text decoded from a legacy encoding; nil if the
+	// track was UTF-8.
+	Transcoded *Transcoding
+}
+
+// merge adds the diagnostics, filled packets, timestamp correction and
+// transcoding of another track (or part of one) written to the same file.
+func (r *Result) merge(o Result) {
+	r.Diagnostics = append(r.Diagnostics, o.Diagnostics...)
+	r.Filled = append(r.Filled, o.Filled...)
+	if r.Timestamps == nil {
+		r.Timestamps = o.Timestamps
+	}
+	if r.Transcoded == nil {
+		r.Transcoded = o.Transcoded
+	}
+}
+
 // ExtractTrackToASS extracts a single subtitle track from an MKV file and writes
 // it as an ASS output file. This is the primary public API for Phase 3 (CLI).
 //
 // For ASS/SSA tracks, the original styles from CodecPrivate are preserved (passthrough mode).
-// For SRT tracks, a default ASS header with Microsoft YaHei font is generated.
+// For SRT tracks, a default ASS header with Microsoft YaHei font is generated,
+// its PlayRes matching the video track's resolution.
 //
 // If outputDir is empty, the output file is written to the same directory as the MKV file.
 // Returns the path to the created ASS file.
 func ExtractTrackToASS(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string) (string, error) {
 	existingPaths := make(map[string]bool)
-	return extractTrackToASS(mkvPath, track, outputDir, existingPaths)
+	res, err := extractTrackToASS(mkvPath, track, outputDir, existingPaths, Options{})
+	if err != nil {
+		return "", err
+	}
+	return res.OutputPath, nil
 }
 
 // ExtractTrackToASSShared is like ExtractTrackToASS but accepts a shared existingPaths
 // map for collision-aware output naming across multiple tracks in the same batch.
 // Callers extracting multiple tracks should create one map and pass it to all calls.
 func ExtractTrackToASSShared(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool) (string, error) {
-	return extractTrackToASS(mkvPath, track, outputDir, existingPaths)
+	res, err := extractTrackToASS(mkvPath, track, outputDir, existingPaths, Options{})
+	if err != nil {
+		return "", err
+	}
+	return res.OutputPath, nil
+}
+
+// ExtractTrack is like ExtractTrackToASSShared but applies opts and returns a
+// Result describing the extraction.
+func ExtractTrack(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, opts Options) (*Result, error) {
+	if len(opts.JoinParts) > 0 {
+		return ExtractJoinedTrack(append([]string{mkvPath}, opts.JoinParts...), track, outputDir, existingPaths, opts)
+	}
+	if opts.OrderedChapters {
+		return ExtractOrderedChapters(mkvPath, track, outputDir, existingPaths, opts)
+	}
+	return extractTrackToASS(mkvPath, track, outputDir, existingPaths, opts)
 }
 
 // ExtractTracksToASS extracts multiple subtitle tracks from an MKV file, writing
@@ -51,13 +206,15 @@ func ExtractTracksToASS(mkvPath string, tracks []mkvinfo.SubtitleTrack, outputDi
 
 	existingPaths := make(map[string]bool)
 	var outputPaths []string
+	var opts Options
+	opts.Chinese.TagSharedNames(tracks)
 
 	for _, track := range tracks {
-		outPath, err := extractTrackToASS(mkvPath, track, outputDir, existingPaths)
+		res, err := extractTrackToASS(mkvPath, track, outputDir, existingPaths, opts)
 		if err != nil {
 			return outputPaths, fmt.Errorf("track %d (%s): %w", track.Number, track.FormatType, err)
 		}
-		outputPaths = append(outputPaths, outPath)
+		outputPaths = append(outputPaths, res.OutputPath)
 	}
 
 	return outputPaths, nil
@@ -65,25 +222,58 @@ func ExtractTracksToASS(mkvPath string, tracks []mkvinfo.SubtitleTrack, outputDi
 
 // extractTrackToASS is the internal implementation shared by both single and batch extraction.
 // It accepts a shared existingPaths map for collision-aware output naming.
-func extractTrackToASS(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool) (string, error) {
+func extractTrackToASS(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, opts Options) (*Result, error) {
+	te, err := readTrackEvents(mkvPath, track, opts)
+	if err != nil {
+		return nil, err
+	}
+	rebaseEvents(opts.Range, te)
+	opts.Retime.ApplyEvents(te.events)
+
+	outputPath := outputPathFor(mkvPath, opts.Chinese.named(track), outputDir, existingPaths, opts.part)
+	if err := te.write(outputPath, opts); err != nil {
+		return nil, err
+	}
+
+	res := te.result
+	res.OutputPath = outputPath
+	return &res, nil
+}
+
+// trackEvents is a subtitle track read from one file and converted to
+// events, with what writing it needs from the file.
+type trackEvents struct {
+	track        mkvinfo.SubtitleTrack
+	codecPrivate []byte            // ASS/SSA header
+	videoRes     assout.Resolution // the file's video resolution, for PlayRes
+	events       []subtitle.SubtitleEvent
+	result       Result // everything but OutputPath
+}
+
+// readTrackEvents reads track from the MKV file at mkvPath and converts it to
+// events, applying every option that depends on the file: gap-fill, time
+// range, timing post-processing and frame snapping. Rebasing (see
+// rebaseEvents), Retime and the output options are left to the caller.
+func readTrackEvents(mkvPath string, track mkvinfo.SubtitleTrack, opts Options) (*trackEvents, error) {
 	// 1. Open MKV file and create demuxer
 	file, err := os.Open(mkvPath)
 	if err != nil {
-		return "", fmt.Errorf("open MKV file: %w", err)
+		return nil, fmt.Errorf("open MKV file: %w", err)
 	}
 	defer file.Close()
 
 	demuxer, err := matroska.NewDemuxer(file)
 	if err != nil {
-		return "", fmt.Errorf("create demuxer: %w", err)
+		return nil, fmt.Errorf("create demuxer: %w", err)
 	}
 	defer demuxer.Close()
 
-	// 2. Get track info for CodecPrivate
-	var codecPrivate []byte
+	// 2. Get track info for CodecPrivate, and the video track for PlayRes and frame snapping
+	te := &trackEvents{track: track}
+	var videoTrack uint8
 	numTracks, err := demuxer.GetNumTracks()
 	if err != nil {
-		return "", fmt.Errorf("get track count: %w", err)
+		return nil, fmt.Errorf("get track count: %w", err)
 	}
 
 	found := false
@@ -92,116 +282,197 @@ func extractTrackToASS(mkvPath string, track mkvinfo.SubtitleTrack, outputDir st
 		if err != nil {
 			continue
 		}
+		if info.Type == matroska.TypeVideo && videoTrack == 0 {
+			videoTrack = info.Number
+			te.videoRes = assout.VideoResolution(
+				int(info.Video.PixelWidth), int(info.Video.PixelHeight),
+				int(info.Video.DisplayWidth), int(info.Video.DisplayHeight))
+		}
 		if info.Number == track.Number {
-			codecPrivate = info.CodecPrivate
+			te.codecPrivate = info.CodecPrivate
 			found = true
-			break
 		}
 	}
 	if !found {
-		return "", fmt.Errorf("track number %d not found in MKV file", track.Number)
+		return nil, fmt.Errorf("track number %d not found in MKV file", track.Number)
 	}
 
 	// 3. Extract raw packets
-	packets, warning, err := ExtractSubtitlePackets(demuxer, track.Number)
+	seekToRange(demuxer, track.Number, opts.Range)
+	reader := trackReader{track: track.Number, stopAt: opts.Range.To}
+	if opts.SnapToFrames && opts.Timing.Keyframes == nil {
+		if videoTrack == 0 {
+			return nil, fmt.Errorf("snap to frames: no video track")
+		}
+		reader.video = videoTrack
+	}
+	read, err := reader.read(demuxer)
 	if err != nil {
-		return "", fmt.Errorf("extract packets: %w", err)
+		return nil, fmt.Errorf("extract packets: %w", err)
+	}
+	packets := read.packets
+	te.result.Timestamps = read.correction
+
+	// Decode text muxed in a legacy encoding first: the reading-speed gap
+	// fill counts its characters.
+	te.result.Transcoded, err = decodeText(track, packets, &te.codecPrivate, opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keyframe snapping needs every frame's keyframe flag, which the
+	// demuxer does not report; the frame times come from the same read.
+	var keyframes []int
+	if opts.Timing.Keyframes != nil {
+		frames, err := ReadVideoFrames(mkvPath)
+		if err != nil {
+			return nil, fmt.Errorf("snap to keyframes: %w", err)
+		}
+		read.frames, keyframes = FrameTimes(frames), KeyframeIndices(frames)
+	}
+
+	// 4. Fill missing end times
+	policy := opts.GapFill
+	if opts.GapFillToSegmentEnd {
+		segInfo, err := demuxer.GetFileInfo()
+		if err != nil {
+			return nil, fmt.Errorf("get file info: %w", err)
+		}
+		policy.SegmentEnd = uint64(mkvinfo.SegmentDuration(segInfo.Duration, segInfo.TimecodeScale))
 	}
-	_ = warning // Caller can check via logging if needed
+	te.result.Filled = ApplyGapFillPolicy(packets, track.CodecID, policy)
 
-	// 4. Gap-fill is already applied by ExtractSubtitlePackets
+	// 5. Keep only the requested time range. Rebasing is left to the
+	// caller (see rebaseEvents), once the events have been timed against
+	// this file's frames.
+	inFile := opts.Range
+	inFile.Rebase = false
+	packets = ApplyTimeRange(packets, inFile)
 
-	// 5. Convert raw packets to SubtitleEvents based on codec
-	events, err := packetsToEvents(packets, track.CodecID)
+	// 6. Convert raw packets to SubtitleEvents based on codec
+	te.events, te.result.Diagnostics, err = convertPackets(packets, track.CodecID, opts.Lenient)
 	if err != nil {
-		return "", fmt.Errorf("convert packets to events: %w", err)
+		return nil, fmt.Errorf("convert packets to events: %w", err)
 	}
+	te.convertChinese(opts.Chinese)
+
+	// 7. Post-process timing and snap to this file's frames
+	var tc *vfr.Timecodes
+	if opts.SnapToFrames || keyframes != nil {
+		if tc, err = vfr.New(read.frames); err != nil {
+			return nil, fmt.Errorf("video timecodes: %w", err)
+		}
+	}
+	vfr.PostProcess(te.events, opts.Timing, tc, keyframes)
+	if opts.SnapToFrames {
+		for i := range te.events {
+			te.events[i].Start, te.events[i].End = tc.Snap(te.events[i].Start, te.events[i].End)
+		}
+	}
+
+	return te, nil
+}
 
-	// 6. Determine output path
+// outputPathFor determines the output path of a track, or of the part of it
+// covering a chapter when part is non-nil.
+func outputPathFor(mkvPath string, track mkvinfo.SubtitleTrack, outputDir string, existingPaths map[string]bool, part *ChapterPart) string {
 	if outputDir == "" {
 		outputDir = filepath.Dir(mkvPath)
 	}
 	videoForNaming := filepath.Join(outputDir, filepath.Base(mkvPath))
-	outputPath := output.GenerateOutputPath(videoForNaming, track, existingPaths)
+	if part != nil {
+		return output.GenerateChapterOutputPath(videoForNaming, part.Label, part.Title, track, existingPaths)
+	}
+	return output.GenerateOutputPath(videoForNaming, track, existingPaths)
+}
+
+// write writes the events as an ASS file at outputPath, removing it again if
+// writing fails.
+func (te *trackEvents) write(outputPath string, opts Options) error {
+	return createOutput(outputPath, func(w io.Writer) error {
+		codecID := te.track.CodecID
+		switch {
+		case codecID == "S_TEXT/ASS" || codecID == "S_TEXT/SSA":
+			return assout.WriteASSPassthroughWithOptions(w, te.codecPrivate, codecID, te.events, te.assOptions(opts))
+		case codecID == "S_TEXT/UTF8":
+			srtOpts := assout.SRTOptions{
+				Template: opts.StyleTemplate,
+				Fonts:    opts.LanguageFonts,
+				Language: te.track.EffectiveLanguage(),
+			}
+			if !opts.KeepPlayRes {
+				srtOpts.PlayRes = te.videoRes
+			}
+			return assout.WriteSRTAsASSWithOptions(w, te.events, srtOpts)
+		default:
+			return fmt.Errorf("unsupported codec ID: %s", codecID)
+		}
+	})
+}
+
+// assOptions returns the output options for an ASS/SSA track.
+func (te *trackEvents) assOptions(opts Options) assout.ASSOptions {
+	assOpts := assout.ASSOptions{Aspect: opts.Aspect}
+	switch {
+	case !opts.ResampleTo.IsZero():
+		assOpts.PlayRes = opts.ResampleTo
+	case opts.ResampleASS:
+		assOpts.PlayRes = te.videoRes
+	}
+	return assOpts
+}
 
-	// 7. Write ASS output
+// createOutput creates the file at outputPath and writes it with fn,
+// removing it again if writing fails.
+func createOutput(outputPath string, fn func(w io.Writer) error) error {
 	outFile, err := os.Create(outputPath)
 	if err != nil {
-		return "", fmt.Errorf("create output file: %w", err)
+		return fmt.Errorf("create output file: %w", err)
 	}
 	defer outFile.Close()
 
-	switch {
-	case track.CodecID == "S_TEXT/ASS" || track.CodecID == "S_TEXT/SSA":
-		err = assout.WriteASSPassthrough(outFile, codecPrivate, track.CodecID, events)
-	case track.CodecID == "S_TEXT/UTF8":
-		err = assout.WriteSRTAsASS(outFile, events)
-	default:
-		return "", fmt.Errorf("unsupported codec ID: %s", track.CodecID)
-	}
-	if err != nil {
+	if err := fn(outFile); err != nil {
 		// Clean up partial output file on write error
 		outFile.Close()
 		os.Remove(outputPath)
-		return "", fmt.Errorf("write ASS output: %w", err)
+		return fmt.Errorf("write ASS output: %w", err)
 	}
-
-	return outputPath, nil
+	return nil
 }
 
-// packetsToEvents converts raw subtitle packets to SubtitleEvents based on codec type.
+// packetsToEvents converts raw subtitle packets to SubtitleEvents based on codec
+// type, failing on the first malformed packet.
 func packetsToEvents(packets []RawSubtitlePacket, codecID string) ([]subtitle.SubtitleEvent, error) {
+	events, _, err := convertPackets(packets, codecID, false)
+	return events, err
+}
+
+// convertPackets is packetsToEvents with an optional lenient mode, in which
+// malformed packets are repaired or skipped and reported as diagnostics.
+func convertPackets(packets []RawSubtitlePacket, codecID string, lenient bool) ([]subtitle.SubtitleEvent, []Diagnostic, error) {
 	events := make([]subtitle.SubtitleEvent, 0, len(packets))
+	conv := &packetConverter{lenient: lenient}
 
 	switch {
 	case codecID == "S_TEXT/ASS" || codecID == "S_TEXT/SSA":
-		for i, pkt := range packets {
-			readOrder, layer, remaining, err := subtitle.ParseASSBlockData(pkt.Data)
+		for _, pkt := range packets {
+			ev, ok, err := conv.assEvent(pkt)
 			if err != nil {
-				return nil, fmt.Errorf("packet %d: parse ASS block data: %w", i, err)
+				return nil, nil, err
 			}
-
-			// Split remaining into at most 7 parts: Style, Name, MarginL, MarginR, MarginV, Effect, Text
-			// remaining = "Style,Name,MarginL,MarginR,MarginV,Effect,Text"
-			parts := strings.SplitN(remaining, ",", 7)
-			if len(parts) < 7 {
-				return nil, fmt.Errorf("packet %d: expected 7 fields in remaining %q, got %d", i, remaining, len(parts))
+			if ok {
+				events = append(events, ev)
 			}
-
-			events = append(events, subtitle.SubtitleEvent{
-				Start:     pkt.StartTime,
-				End:       pkt.EndTime,
-				Layer:     layer,
-				Style:     parts[0],
-				Name:      parts[1],
-				MarginL:   parts[2],
-				MarginR:   parts[3],
-				MarginV:   parts[4],
-				Effect:    parts[5],
-				Text:      parts[6],
-				ReadOrder: readOrder,
-			})
 		}
 
 	case codecID == "S_TEXT/UTF8":
 		for _, pkt := range packets {
-			events = append(events, subtitle.SubtitleEvent{
-				Start:   pkt.StartTime,
-				End:     pkt.EndTime,
-				Layer:   0,
-				Style:   "Default",
-				Name:    "",
-				MarginL: "0",
-				MarginR: "0",
-				MarginV: "0",
-				Effect:  "",
-				Text:    string(pkt.Data),
-			})
+			events = append(events, textEvent(pkt, 0))
 		}
 
 	default:
-		return nil, fmt.Errorf("unsupported codec ID: %s", codecID)
+		return nil, nil, fmt.Errorf("unsupported codec ID: %s", codecID)
 	}
 
-	return events, nil
+	return events, conv.diagnostics, nil
 }
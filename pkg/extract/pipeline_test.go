@@ -3,6 +3,7 @@ package extract
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"mkv-sub-extractor/pkg/mkvinfo"
@@ -274,3 +275,63 @@ func TestPacketsToEvents_ReturnsSubtitleEvents(t *testing.T) {
 	// Type assertion at compile time
 	var _ []subtitle.SubtitleEvent = events
 }
+
+func TestConvertPackets_Lenient(t *testing.T) {
+	// Packets are reported by their position in the track as read, here
+	// after ten packets dropped by a time range.
+	packets := []RawSubtitlePacket{
+		{StartTime: 1_000_000_000, EndTime: 2_000_000_000, Data: []byte("0,0,Default,,0,0,0,,Good"), Index: 10},
+		{StartTime: 2_000_000_000, EndTime: 3_000_000_000, Data: []byte("damaged text"), Index: 11},
+		{StartTime: 3_000_000_000, EndTime: 4_000_000_000, Data: []byte("x1,y,Sign,,0,0,0,,Bad numbers"), Index: 12},
+		{StartTime: 4_000_000_000, EndTime: 5_000_000_000, Data: nil, Index: 13},
+		{StartTime: 5_000_000_000, EndTime: 6_000_000_000, Data: []byte(",z,Default,,0,0,0,,Empty ReadOrder"), Index: 14},
+	}
+
+	// Strict mode still fails on the first bad packet, named as in reports.
+	if _, _, err := convertPackets(packets, "S_TEXT/ASS", false); err == nil || !strings.Contains(err.Error(), "packet 11:") {
+		t.Fatalf("strict mode: error = %v, want packet 11", err)
+	}
+
+	events, diags, err := convertPackets(packets, "S_TEXT/ASS", true)
+	if err != nil {
+		t.Fatalf("lenient mode: unexpected error: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+	if ev := events[1]; ev.Style != "Default" || ev.Text != "damaged text" || ev.ReadOrder != 11 {
+		t.Errorf("short block event = %+v, want Default-style text", ev)
+	}
+	if ev := events[2]; ev.Style != "Sign" || ev.Text != "Bad numbers" || ev.ReadOrder != 12 || ev.Layer != 0 {
+		t.Errorf("repaired event = %+v", ev)
+	}
+	if ev := events[3]; ev.ReadOrder != 0 || ev.Layer != 0 {
+		t.Errorf("empty ReadOrder event = %+v, want ReadOrder 0", ev)
+	}
+
+	if len(diags) != 4 {
+		t.Fatalf("expected 4 diagnostics, got %d: %v", len(diags), diags)
+	}
+	want := []struct {
+		index  int
+		action string
+	}{{1, ActionRepaired}, {2, ActionRepaired}, {3, ActionSkipped}, {4, ActionRepaired}}
+	for i, w := range want {
+		d := diags[i]
+		if d.PacketIndex != 10+w.index || d.Action != w.action || d.Timestamp != packets[w.index].StartTime {
+			t.Errorf("diagnostic %d = %+v, want packet %d %s", i, d, w.index, w.action)
+		}
+	}
+	if got := diags[1].Reason; got != `invalid ReadOrder "x1", invalid Layer "y"` {
+		t.Errorf("reason = %q", got)
+	}
+	if got := diags[3].Reason; got != `invalid Layer "z"` {
+		t.Errorf("reason = %q, want only the Layer", got)
+	}
+	if string(diags[0].Data) != "damaged text" {
+		t.Errorf("raw data = %q", diags[0].Data)
+	}
+	if got := diags[0].String(); got != `packet 11 at 0:00:02.00: expected 9 fields, got 1; kept as text (repaired): "damaged text"` {
+		t.Errorf("String() = %s", got)
+	}
+}
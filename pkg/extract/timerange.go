@@ -0,0 +1,156 @@
+package extract
+
+import (
+	"math"
+
+	matroska "github.com/luispater/matroska-go"
+)
+
+// seekLookback is how far before TimeRange.From extraction seeks when the
+// track has no cues saying which of its blocks are still showing at From, so
+// that events starting shortly before the range are still read (30 seconds).
+const seekLookback uint64 = 30_000_000_000
+
+// TimeRange selects the part of a track to extract. The zero value selects
+// the whole track.
+type TimeRange struct {
+	// From and To bound the range in nanoseconds; events overlapping
+	// [From, To) are kept. Zero To means the end of the track.
+	From, To uint64
+
+	// Clamp cuts kept events at the range edges, so none starts before From
+	// or ends after To.
+	Clamp bool
+
+	// Rebase shifts kept events so the first of them starts at zero.
+	Rebase bool
+
+	// NoSeek reads the file from the beginning instead of seeking to From
+	// using the cues, for files whose cues are missing or wrong, or whose
+	// events start more than seekLookback before From without cues saying
+	// so.
+	NoSeek bool
+
+	// clip rebases on From instead of the first kept event, timing the
+	// output from the start of the range as for a clip cut at From, such as
+	// a chapter. Times before From become zero.
+	clip bool
+}
+
+// clipRange returns the range of a clip cut from from to to: clamped and
+// rebased on from.
+func clipRange(from, to uint64) TimeRange {
+	return TimeRange{From: from, To: to, Clamp: true, Rebase: true, clip: true}
+}
+
+// IsZero reports whether r selects the whole track.
+func (r TimeRange) IsZero() bool {
+	return r.From == 0 && r.To == 0
+}
+
+// contains reports whether an event from start to end overlaps the range.
+func (r TimeRange) contains(start, end uint64) bool {
+	return (start >= r.From || end > r.From) && (r.To == 0 || start < r.To)
+}
+
+// ApplyTimeRange returns the packets overlapping r, clamped and rebased as r
+// asks. Packets should already be gap-filled, so their end times are known.
+func ApplyTimeRange(packets []RawSubtitlePacket, r TimeRange) []RawSubtitlePacket {
+	if r.IsZero() {
+		return packets
+	}
+	rebaseOn := r.Rebase
+	r.Rebase = false
+
+	kept := packets[:0:0]
+	for _, pkt := range packets {
+		if !r.contains(pkt.StartTime, pkt.EndTime) {
+			continue
+		}
+		if r.Clamp {
+			pkt.StartTime = max(pkt.StartTime, r.From)
+			if r.To != 0 {
+				pkt.EndTime = min(pkt.EndTime, r.To)
+			}
+		}
+		kept = append(kept, pkt)
+	}
+
+	if rebaseOn {
+		origin := r.From
+		if !r.clip {
+			origin = math.MaxUint64
+			for _, pkt := range kept {
+				origin = min(origin, pkt.StartTime)
+			}
+		}
+		for i := range kept {
+			kept[i].StartTime = rebase(kept[i].StartTime, origin)
+			kept[i].EndTime = rebase(kept[i].EndTime, origin)
+		}
+	}
+	return kept
+}
+
+// rebaseEvents rebases the events of tracks as r asks, all by the same
+// offset so that tracks shown together stay in sync: From for a clip, else
+// the start of the earliest event. It returns the offset, zero when r does
+// not rebase.
+func rebaseEvents(r TimeRange, tracks ...*trackEvents) uint64 {
+	if !r.Rebase || r.IsZero() {
+		return 0
+	}
+	origin := r.From
+	if !r.clip {
+		origin = math.MaxUint64
+		for _, te := range tracks {
+			for _, ev := range te.events {
+				origin = min(origin, ev.Start)
+			}
+		}
+		if origin == math.MaxUint64 {
+			return 0
+		}
+	}
+	for _, te := range tracks {
+		for i := range te.events {
+			te.events[i].Start = rebase(te.events[i].Start, origin)
+			te.events[i].End = rebase(te.events[i].End, origin)
+		}
+	}
+	return origin
+}
+
+// rebase returns t - from, or zero if t is before from.
+func rebase(t, from uint64) uint64 {
+	if t < from {
+		return 0
+	}
+	return t - from
+}
+
+// seekToRange positions the demuxer near r.From using the file's cues, so the
+// clusters before the range are not read. Subtitle cues written with a
+// CueDuration (as mkvmerge does) locate blocks still showing at From; other
+// tracks' cues are used with seekLookback. Without cues the demuxer stays at
+// the start of the file.
+func seekToRange(demuxer *matroska.Demuxer, trackNumber uint8, r TimeRange) {
+	if r.From == 0 || r.NoSeek {
+		return
+	}
+	cues := demuxer.GetCues()
+	if len(cues) == 0 {
+		return
+	}
+
+	target := rebase(r.From, seekLookback)
+	for _, cue := range cues {
+		if cue.Track == trackNumber && cue.Duration > 0 &&
+			cue.Time < r.From && cue.Time+cue.Duration > r.From {
+			target = min(target, cue.Time)
+		}
+	}
+	if target > 0 {
+		demuxer.Seek(target, 0)
+	}
+}
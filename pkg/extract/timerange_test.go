@@ -0,0 +1,73 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+func TestApplyTimeRange(t *testing.T) {
+	const s = uint64(1_000_000_000)
+	packets := []RawSubtitlePacket{
+		{StartTime: 1 * s, EndTime: 2 * s, Data: []byte("before")},
+		{StartTime: 9 * s, EndTime: 11 * s, Data: []byte("across From")},
+		{StartTime: 12 * s, EndTime: 13 * s, Data: []byte("inside")},
+		{StartTime: 19 * s, EndTime: 21 * s, Data: []byte("across To")},
+		{StartTime: 20 * s, EndTime: 22 * s, Data: []byte("at To")},
+	}
+	times := func(got []RawSubtitlePacket) [][2]uint64 {
+		var out [][2]uint64
+		for _, p := range got {
+			out = append(out, [2]uint64{p.StartTime, p.EndTime})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		r    TimeRange
+		want [][2]uint64
+	}{
+		{"whole track", TimeRange{}, [][2]uint64{{1 * s, 2 * s}, {9 * s, 11 * s}, {12 * s, 13 * s}, {19 * s, 21 * s}, {20 * s, 22 * s}}},
+		{"overlap", TimeRange{From: 10 * s, To: 20 * s}, [][2]uint64{{9 * s, 11 * s}, {12 * s, 13 * s}, {19 * s, 21 * s}}},
+		{"open end", TimeRange{From: 12 * s}, [][2]uint64{{12 * s, 13 * s}, {19 * s, 21 * s}, {20 * s, 22 * s}}},
+		{"clamp", TimeRange{From: 10 * s, To: 20 * s, Clamp: true}, [][2]uint64{{10 * s, 11 * s}, {12 * s, 13 * s}, {19 * s, 20 * s}}},
+		{"rebase", TimeRange{From: 10 * s, To: 20 * s, Rebase: true}, [][2]uint64{{0, 2 * s}, {3 * s, 4 * s}, {10 * s, 12 * s}}},
+		{"rebase after a gap", TimeRange{From: 11 * s, To: 20 * s, Rebase: true}, [][2]uint64{{0, 1 * s}, {7 * s, 9 * s}}},
+		{"clamp and rebase", TimeRange{From: 10 * s, To: 20 * s, Clamp: true, Rebase: true}, [][2]uint64{{0, 1 * s}, {2 * s, 3 * s}, {9 * s, 10 * s}}},
+		{"clip", clipRange(11*s, 20*s), [][2]uint64{{1 * s, 2 * s}, {8 * s, 9 * s}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append([]RawSubtitlePacket(nil), packets...)
+			if got := times(ApplyTimeRange(in, tt.r)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(in, packets) {
+				t.Error("input packets were modified")
+			}
+		})
+	}
+}
+
+func TestRebaseEvents(t *testing.T) {
+	const s = uint64(1_000_000_000)
+	a := &trackEvents{events: []subtitle.SubtitleEvent{{Start: 13 * s, End: 14 * s}}}
+	b := &trackEvents{events: []subtitle.SubtitleEvent{{Start: 12 * s, End: 15 * s}}}
+
+	// Tracks shown together share the offset of the earliest event.
+	if got := rebaseEvents(TimeRange{From: 10 * s, Rebase: true}, a, b); got != 12*s {
+		t.Errorf("rebaseEvents() = %v, want %v", got, 12*s)
+	}
+	if ev := a.events[0]; ev.Start != 1*s || ev.End != 2*s {
+		t.Errorf("first track event = %v-%v, want 1s-2s", ev.Start, ev.End)
+	}
+	if ev := b.events[0]; ev.Start != 0 || ev.End != 3*s {
+		t.Errorf("second track event = %v-%v, want 0-3s", ev.Start, ev.End)
+	}
+
+	if got := rebaseEvents(TimeRange{From: 10 * s}, a); got != 0 || a.events[0].Start != 1*s {
+		t.Errorf("rebaseEvents() without Rebase = %v, moved events to %v", got, a.events[0].Start)
+	}
+}
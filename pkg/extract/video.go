@@ -0,0 +1,203 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"mkv-sub-extractor/pkg/ebml"
+)
+
+// VideoFrame is one frame of a video track, as stored in the container.
+type VideoFrame struct {
+	Time     uint64 // presentation timestamp, nanoseconds
+	Keyframe bool   // the block can be decoded on its own
+}
+
+// defaultTimecodeScale is the Matroska default TimecodeScale (1 ms).
+const defaultTimecodeScale uint64 = 1_000_000
+
+// blockHeaderSize covers a block header with the longest track number
+// coding: track vint (up to 8 bytes), 2-byte relative timestamp and flags.
+const blockHeaderSize = 11
+
+// segmentSiblings are the IDs that can follow a Segment of unknown size.
+var segmentSiblings = map[uint32]bool{ebml.IDEBML: true, ebml.IDSegment: true}
+
+// clusterSiblings are the top-level IDs that can follow a Cluster of unknown
+// size.
+var clusterSiblings = map[uint32]bool{
+	ebml.IDCluster: true, ebml.IDCues: true, ebml.IDTags: true, ebml.IDChapters: true,
+	ebml.IDSeekHead: true, ebml.IDInfo: true, ebml.IDTracks: true,
+	ebml.IDEBML: true, ebml.IDSegment: true,
+}
+
+// ReadVideoFrames returns the frames of the first video track of the MKV file
+// at mkvPath in presentation order, from the container alone: block
+// timestamps and keyframe flags, without decoding any video.
+//
+// A SimpleBlock is a keyframe when its keyframe flag is set; a BlockGroup is
+// one unless it has a ReferenceBlock. matroska-go reports every BlockGroup as
+// a keyframe, so the blocks are read here directly. Laced blocks count as one
+// frame.
+func ReadVideoFrames(mkvPath string) ([]VideoFrame, error) {
+	f, err := os.Open(mkvPath)
+	if err != nil {
+		return nil, fmt.Errorf("open MKV file: %w", err)
+	}
+	defer f.Close()
+
+	frames, err := readVideoFrames(f)
+	if err != nil {
+		return nil, fmt.Errorf("read video frames: %w", err)
+	}
+	return frames, nil
+}
+
+// videoScanner collects video frames while walking a Segment.
+type videoScanner struct {
+	r      *ebml.Reader
+	scale  uint64
+	track  uint64 // first video track number, once Tracks has been read
+	frames []VideoFrame
+}
+
+// readVideoFrames reads the video frames of the first Segment in rs.
+func readVideoFrames(rs io.ReadSeeker) ([]VideoFrame, error) {
+	s := &videoScanner{r: ebml.NewReader(rs), scale: defaultTimecodeScale}
+
+	seg, err := s.r.Segment()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.r.Children(seg, segmentSiblings, s.segmentChild); err != nil {
+		return nil, err
+	}
+
+	if s.track == 0 {
+		return nil, fmt.Errorf("no video track")
+	}
+	sort.SliceStable(s.frames, func(i, j int) bool { return s.frames[i].Time < s.frames[j].Time })
+	return s.frames, nil
+}
+
+// segmentChild handles a top-level element of the Segment.
+func (s *videoScanner) segmentChild(e ebml.Element) error {
+	switch e.ID {
+	case ebml.IDInfo:
+		return s.r.Children(e, nil, func(c ebml.Element) error {
+			if c.ID != ebml.IDTimecodeScale {
+				return nil
+			}
+			v, err := s.r.Uint(c)
+			if err == nil && v > 0 {
+				s.scale = v
+			}
+			return err
+		})
+	case ebml.IDTracks:
+		return s.r.Children(e, nil, s.trackEntry)
+	case ebml.IDCluster:
+		return s.cluster(e)
+	}
+	if e.Size == ebml.UnknownSize {
+		return fmt.Errorf("element 0x%X has unknown size", e.ID)
+	}
+	return nil
+}
+
+// trackEntry records the number of the first video TrackEntry.
+func (s *videoScanner) trackEntry(e ebml.Element) error {
+	if e.ID != ebml.IDTrackEntry || s.track != 0 {
+		return nil
+	}
+	var number, kind uint64
+	err := s.r.Children(e, nil, func(c ebml.Element) error {
+		var err error
+		switch c.ID {
+		case ebml.IDTrackNumber:
+			number, err = s.r.Uint(c)
+		case ebml.IDTrackType:
+			kind, err = s.r.Uint(c)
+		}
+		return err
+	})
+	if kind == 1 { // video
+		s.track = number
+	}
+	return err
+}
+
+// cluster collects the video frames of a Cluster.
+func (s *videoScanner) cluster(e ebml.Element) error {
+	var base uint64
+	return s.r.Children(e, clusterSiblings, func(c ebml.Element) error {
+		switch c.ID {
+		case ebml.IDClusterTime:
+			v, err := s.r.Uint(c)
+			base = v
+			return err
+		case ebml.IDSimpleBlock:
+			return s.block(c, base, true)
+		case ebml.IDBlockGroup:
+			var block ebml.Element
+			referenced := false
+			err := s.r.Children(c, nil, func(g ebml.Element) error {
+				switch g.ID {
+				case ebml.IDBlock:
+					block = g
+				case ebml.IDReferenceBlock:
+					referenced = true
+				}
+				return nil
+			})
+			if err != nil || block.ID == 0 {
+				return err
+			}
+			return s.block(block, base, !referenced)
+		}
+		return nil
+	})
+}
+
+// block records the frame in a SimpleBlock or Block if it belongs to the
+// video track. A SimpleBlock's keyframe flag overrides key.
+func (s *videoScanner) block(e ebml.Element, base uint64, key bool) error {
+	head, err := s.r.Head(e, blockHeaderSize)
+	if err != nil {
+		return err
+	}
+	track, n := ebml.Vint(head)
+	if n == 0 || len(head) < n+3 || track != s.track {
+		return nil
+	}
+	rel := int64(int16(uint16(head[n])<<8 | uint16(head[n+1])))
+	if e.ID == ebml.IDSimpleBlock {
+		key = head[n+2]&0x80 != 0
+	}
+
+	t := max(int64(base)+rel, 0)
+	s.frames = append(s.frames, VideoFrame{Time: uint64(t) * s.scale, Keyframe: key})
+	return nil
+}
+
+// FrameTimes returns the timestamps of frames.
+func FrameTimes(frames []VideoFrame) []uint64 {
+	times := make([]uint64, len(frames))
+	for i, f := range frames {
+		times[i] = f.Time
+	}
+	return times
+}
+
+// KeyframeIndices returns the indices of the keyframes among frames.
+func KeyframeIndices(frames []VideoFrame) []int {
+	var keys []int
+	for i, f := range frames {
+		if f.Keyframe {
+			keys = append(keys, i)
+		}
+	}
+	return keys
+}
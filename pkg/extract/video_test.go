@@ -0,0 +1,64 @@
+package extract
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// element encodes an EBML element with a one-byte size (or unknown size when
+// size is -1).
+func element(id []byte, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	out := append([]byte(nil), id...)
+	return append(append(out, 0x80|byte(len(body))), body...)
+}
+
+// block encodes a block header for track 1 at relative time rel (ms).
+func block(rel int16, flags byte) []byte {
+	return []byte{0x81, byte(uint16(rel) >> 8), byte(rel), flags, 0xDE, 0xAD}
+}
+
+func TestReadVideoFrames(t *testing.T) {
+	tracks := element([]byte{0x16, 0x54, 0xAE, 0x6B},
+		element([]byte{0xAE}, element([]byte{0xD7}, []byte{2}), element([]byte{0x83}, []byte{0x11})),
+		element([]byte{0xAE}, element([]byte{0xD7}, []byte{1}), element([]byte{0x83}, []byte{1})),
+	)
+	cluster := element([]byte{0x1F, 0x43, 0xB6, 0x75},
+		element([]byte{0xE7}, []byte{0x03, 0xE8}), // 1000 ms
+		element([]byte{0xA3}, block(0, 0x80)),
+		element([]byte{0xA3}, block(80, 0x00)),                     // decode order: B-frame after P
+		element([]byte{0xA3}, []byte{0x82, 0, 40, 0x80}),           // another track
+		element([]byte{0xA0}, element([]byte{0xA1}, block(40, 0))), // BlockGroup keyframe
+		element([]byte{0xA0},
+			element([]byte{0xA1}, block(120, 0)),
+			element([]byte{0xFB}, []byte{0xD8}), // ReferenceBlock
+		),
+	)
+	segment := append([]byte{0x18, 0x53, 0x80, 0x67, 0xFF}, append(tracks, cluster...)...)
+	data := append(element([]byte{0x1A, 0x45, 0xDF, 0xA3}), segment...)
+
+	frames, err := readVideoFrames(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []VideoFrame{
+		{1000_000_000, true},
+		{1040_000_000, true},
+		{1080_000_000, false},
+		{1120_000_000, false},
+	}
+	if !reflect.DeepEqual(frames, want) {
+		t.Errorf("frames = %v, want %v", frames, want)
+	}
+	if got := KeyframeIndices(frames); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("KeyframeIndices() = %v", got)
+	}
+}
+
+func TestReadVideoFrames_NoVideo(t *testing.T) {
+	data := append(element([]byte{0x1A, 0x45, 0xDF, 0xA3}), element([]byte{0x18, 0x53, 0x80, 0x67})...)
+	if _, err := readVideoFrames(bytes.NewReader(data)); err == nil {
+		t.Error("expected error for a file without a video track")
+	}
+}
@@ -0,0 +1,130 @@
+// Package langid identifies the language of subtitle text, for tracks whose
+// language is not tagged. Each line is first assigned its script; languages
+// that are the only one of their script here (Chinese, Japanese, Korean,
+// Greek, Thai) follow from it, and the others are told apart by comparing the
+// text's trigrams with the language profiles of whatlanggo, which are
+// compiled into the binary.
+package langid
+
+import (
+	"strings"
+
+	"github.com/abadojack/whatlanggo"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// MinLines is the number of lines of text needed to detect a language.
+const MinLines = 5
+
+// scriptLanguages maps the scripts written by one language to its ISO 639-2
+// code.
+var scriptLanguages = map[subtitle.Script]string{
+	subtitle.ScriptHan:      "chi",
+	subtitle.ScriptJapanese: "jpn",
+	subtitle.ScriptHangul:   "kor",
+	subtitle.ScriptGreek:    "gre",
+	subtitle.ScriptThai:     "tha",
+}
+
+// scriptOrder lists the scripts in the order ties between them are resolved.
+var scriptOrder = []subtitle.Script{
+	subtitle.ScriptLatin,
+	subtitle.ScriptHan,
+	subtitle.ScriptJapanese,
+	subtitle.ScriptHangul,
+	subtitle.ScriptArabic,
+	subtitle.ScriptHebrew,
+	subtitle.ScriptCyrillic,
+	subtitle.ScriptGreek,
+	subtitle.ScriptThai,
+}
+
+// Detect returns the language of lines of subtitle text as an ISO 639-2 code,
+// such as "eng" or "ger", and the confidence of the detection, from 0 to 1.
+// The language is that of the lines in the most common script (see
+// subtitle.DetectScript), and the confidence is the share of those lines
+// times the confidence of the trigram comparison, if one was needed: a
+// bilingual track or a language close to another scores lower. It returns
+// "" and 0 when fewer than MinLines lines have letters or no language
+// matches.
+func Detect(lines []string) (string, float64) {
+	byScript := make(map[subtitle.Script][]string)
+	total := 0
+	for _, line := range lines {
+		script := subtitle.DetectScript(line)
+		if script == subtitle.ScriptUnknown {
+			continue
+		}
+		byScript[script] = append(byScript[script], line)
+		total++
+	}
+	if total < MinLines {
+		return "", 0
+	}
+
+	var script subtitle.Script
+	for _, s := range scriptOrder {
+		if len(byScript[s]) > len(byScript[script]) {
+			script = s
+		}
+	}
+	share := float64(len(byScript[script])) / float64(total)
+
+	if code, ok := scriptLanguages[script]; ok {
+		return code, share
+	}
+	info := whatlanggo.Detect(strings.Join(byScript[script], "\n"))
+	if info.Lang < 0 || info.Confidence == 0 {
+		return "", 0
+	}
+	return matroskaCode(info.Lang), share * info.Confidence
+}
+
+// macrolanguages maps the individual languages whatlanggo detects to the
+// macrolanguage ISO 639-2 names them by.
+var macrolanguages = map[string]string{
+	"arb": "ara", // Standard Arabic
+	"azj": "aze", // North Azerbaijani
+	"cmn": "zho", // Mandarin Chinese
+	"pes": "fas", // Iranian Persian
+	"ydd": "yid", // Eastern Yiddish
+}
+
+// bibliographic maps ISO 639-2/T codes to the ISO 639-2/B codes Matroska
+// files use, where they differ.
+var bibliographic = map[string]string{
+	"bod": "tib",
+	"ces": "cze",
+	"cym": "wel",
+	"deu": "ger",
+	"ell": "gre",
+	"eus": "baq",
+	"fas": "per",
+	"fra": "fre",
+	"hye": "arm",
+	"isl": "ice",
+	"kat": "geo",
+	"mkd": "mac",
+	"mri": "mao",
+	"msa": "may",
+	"mya": "bur",
+	"nld": "dut",
+	"ron": "rum",
+	"slk": "slo",
+	"sqi": "alb",
+	"zho": "chi",
+}
+
+// matroskaCode returns the ISO 639-2 code of lang as a Matroska file would
+// tag it, such as "ger" for German.
+func matroskaCode(lang whatlanggo.Lang) string {
+	code := lang.Iso6393()
+	if macro, ok := macrolanguages[code]; ok {
+		code = macro
+	}
+	if b, ok := bibliographic[code]; ok {
+		code = b
+	}
+	return code
+}
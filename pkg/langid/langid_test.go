@@ -0,0 +1,79 @@
+package langid
+
+import (
+	"testing"
+
+	"github.com/abadojack/whatlanggo"
+)
+
+func TestDetect(t *testing.T) {
+	english := []string{
+		"Where have you been all this time?",
+		"I told you not to come back here.",
+		"We need to leave before the sun goes down.",
+		"Nobody knows what happened that night.",
+		"Give me the key and I will open the door.",
+		"She said she would be waiting at the station.",
+	}
+	german := []string{
+		"Wo bist du die ganze Zeit gewesen?",
+		"Ich habe dir gesagt, dass du nicht zurückkommen sollst.",
+		"Wir müssen gehen, bevor die Sonne untergeht.",
+		"Niemand weiß, was in dieser Nacht passiert ist.",
+		"Gib mir den Schlüssel und ich öffne die Tür.",
+		"Sie sagte, sie würde am Bahnhof warten.",
+	}
+	japanese := []string{
+		"今までどこにいたの？",
+		"ここに戻ってくるなと言ったはずだ。",
+		"日が沈む前に出発しないと。",
+		"あの夜何が起きたのか誰も知らない。",
+		"鍵をくれ、ドアを開けるから。",
+	}
+	chinese := []string{"你去哪里了？", "我跟你说过别回来。", "天黑之前我们得走。", "没人知道那天晚上发生了什么。", "把钥匙给我。"}
+
+	tests := []struct {
+		name     string
+		lines    []string
+		wantCode string
+		minConf  float64
+		maxConf  float64
+	}{
+		{"English", english, "eng", 0.9, 1},
+		{"German", german, "ger", 0.9, 1},
+		{"Japanese", japanese, "jpn", 1, 1},
+		{"Chinese", chinese, "chi", 1, 1},
+		{"markup and signs ignored", append([]string{`{\an8}`, "♪", "123"}, japanese...), "jpn", 1, 1},
+		{"bilingual", append(append([]string{}, chinese...), english[:3]...), "chi", 0.6, 0.7},
+		{"too few lines", english[:MinLines-1], "", 0, 0},
+		{"no letters", []string{"...", "♪♪", "1", "2", "3", "4"}, "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, conf := Detect(tt.lines)
+			if code != tt.wantCode || conf < tt.minConf || conf > tt.maxConf {
+				t.Errorf("Detect() = %q, %.2f, want %q in [%.2f, %.2f]", code, conf, tt.wantCode, tt.minConf, tt.maxConf)
+			}
+		})
+	}
+}
+
+func TestMatroskaCode(t *testing.T) {
+	tests := []struct {
+		lang whatlanggo.Lang
+		want string
+	}{
+		{whatlanggo.Eng, "eng"},
+		{whatlanggo.Deu, "ger"},
+		{whatlanggo.Fra, "fre"},
+		{whatlanggo.Cmn, "chi"},
+		{whatlanggo.Arb, "ara"},
+		{whatlanggo.Pes, "per"},
+		{whatlanggo.Spa, "spa"},
+	}
+	for _, tt := range tests {
+		if got := matroskaCode(tt.lang); got != tt.want {
+			t.Errorf("matroskaCode(%v) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package mkvinfo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"mkv-sub-extractor/pkg/ebml"
+)
+
+// Edition is a Matroska EditionEntry: one set of chapters for the segment.
+type Edition struct {
+	UID      uint64
+	Default  bool // EditionFlagDefault
+	Ordered  bool // EditionFlagOrdered: chapters give the playback order
+	Hidden   bool // EditionFlagHidden
+	Chapters []Chapter
+}
+
+// Chapter is a ChapterAtom.
+type Chapter struct {
+	UID   uint64
+	Start uint64 // nanoseconds
+	End   uint64 // nanoseconds; 0 if the chapter has no ChapterTimeEnd
+
+	Enabled bool // ChapterFlagEnabled
+	Hidden  bool // ChapterFlagHidden
+
+	// SegmentUID names the segment the chapter plays from, in ordered
+	// editions; nil for this segment.
+	SegmentUID []byte
+
+	Displays []ChapterDisplay
+	Children []Chapter // nested ChapterAtoms
+}
+
+// ChapterDisplay is a chapter title in one or more languages.
+type ChapterDisplay struct {
+	String    string
+	Languages []string // ChapLanguage codes (ISO 639-2); "eng" when none is given
+}
+
+// Title returns the chapter's title in lang (an ISO 639-2 code), falling
+// back to its first title, or "" if it has none.
+func (c Chapter) Title(lang string) string {
+	for _, d := range c.Displays {
+		for _, l := range d.Languages {
+			if l == lang {
+				return d.String
+			}
+		}
+	}
+	if len(c.Displays) > 0 {
+		return c.Displays[0].String
+	}
+	return ""
+}
+
+// Visible reports whether a player lists the chapter.
+func (c Chapter) Visible() bool {
+	return c.Enabled && !c.Hidden
+}
+
+// DefaultEdition returns the edition a player picks: the first flagged as
+// default, else the first. It returns nil when there are no editions.
+func DefaultEdition(editions []Edition) *Edition {
+	for i := range editions {
+		if editions[i].Default {
+			return &editions[i]
+		}
+	}
+	if len(editions) > 0 {
+		return &editions[0]
+	}
+	return nil
+}
+
+// ListedChapters returns the edition's visible top-level chapters in start
+// order, with each missing End set to the next chapter's Start (the last
+// keeps End 0, meaning the end of the segment).
+func (e Edition) ListedChapters() []Chapter {
+	var chapters []Chapter
+	for _, c := range e.Chapters {
+		if c.Visible() {
+			chapters = append(chapters, c)
+		}
+	}
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	for i := range chapters {
+		if chapters[i].End <= chapters[i].Start && i+1 < len(chapters) {
+			chapters[i].End = chapters[i+1].Start
+		}
+	}
+	return chapters
+}
+
+// PlaybackChapters returns the edition's enabled top-level chapters in the
+// order they are stored, which for an ordered edition is playback order.
+// Hidden chapters still play; they are only left out of menus.
+func (e Edition) PlaybackChapters() []Chapter {
+	var chapters []Chapter
+	for _, c := range e.Chapters {
+		if c.Enabled {
+			chapters = append(chapters, c)
+		}
+	}
+	return chapters
+}
+
+// ReadSegmentUID returns the SegmentUID of the MKV file at path, which
+// ordered chapters in other files use to refer to it; nil if it has none.
+func ReadSegmentUID(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %w", err)
+	}
+	defer f.Close()
+
+	uid, err := readSegmentUID(f)
+	if err != nil {
+		return nil, fmt.Errorf("read segment UID: %w", err)
+	}
+	return uid, nil
+}
+
+// readSegmentUID reads the SegmentUID from the Info of the first Segment in
+// rs, which precedes the first Cluster.
+func readSegmentUID(rs io.ReadSeeker) ([]byte, error) {
+	r := ebml.NewReader(rs)
+	seg, err := r.Segment()
+	if err != nil {
+		return nil, err
+	}
+
+	var uid []byte
+	err = r.Children(seg, segmentSiblings, func(e ebml.Element) error {
+		switch {
+		case e.ID == ebml.IDInfo:
+			err := r.Children(e, nil, func(c ebml.Element) error {
+				var err error
+				if c.ID == ebml.IDSegmentUID {
+					uid, err = r.Data(c)
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			return errStopWalk
+		case e.ID == ebml.IDCluster, e.Size == ebml.UnknownSize:
+			return errStopWalk
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return nil, err
+	}
+	return uid, nil
+}
+
+// ReadChapters returns the chapter editions of the MKV file at path; nil if
+// it has no Chapters element. matroska-go flattens the editions away, so the
+// element is read here directly, found by walking the top-level elements up
+// to the first Cluster or through the SeekHead.
+func ReadChapters(path string) ([]Edition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %w", err)
+	}
+	defer f.Close()
+
+	editions, err := readChapters(f)
+	if err != nil {
+		return nil, fmt.Errorf("read chapters: %w", err)
+	}
+	return editions, nil
+}
+
+// errStopWalk ends a walk of the top-level elements early.
+var errStopWalk = errors.New("stop walk")
+
+// segmentSiblings are the IDs that can follow a Segment of unknown size.
+var segmentSiblings = map[uint32]bool{ebml.IDEBML: true, ebml.IDSegment: true}
+
+// readChapters reads the Chapters element of the first Segment in rs.
+func readChapters(rs io.ReadSeeker) ([]Edition, error) {
+	r := ebml.NewReader(rs)
+	seg, err := r.Segment()
+	if err != nil {
+		return nil, err
+	}
+
+	var editions []Edition
+	found := false
+	seekPos := int64(-1) // Chapters offset from the SeekHead, relative to the Segment payload
+
+	err = r.Children(seg, segmentSiblings, func(e ebml.Element) error {
+		switch e.ID {
+		case ebml.IDChapters:
+			found = true
+			var err error
+			editions, err = readEditions(r, e)
+			if err != nil {
+				return err
+			}
+			return errStopWalk
+		case ebml.IDSeekHead:
+			pos, err := seekPosition(r, e, ebml.IDChapters)
+			if pos >= 0 {
+				seekPos = pos
+			}
+			return err
+		case ebml.IDCluster:
+			return errStopWalk
+		}
+		if e.Size == ebml.UnknownSize {
+			return errStopWalk
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return nil, err
+	}
+	if found || seekPos < 0 {
+		return editions, nil
+	}
+
+	if err := r.SeekTo(seg.Offset + seekPos); err != nil {
+		return nil, err
+	}
+	e, err := r.Next()
+	if err != nil {
+		return nil, err
+	}
+	if e.ID != ebml.IDChapters {
+		return nil, fmt.Errorf("SeekHead points to element 0x%X, not Chapters", e.ID)
+	}
+	return readEditions(r, e)
+}
+
+// seekPosition returns the SeekPosition listed for id in a SeekHead, or -1.
+func seekPosition(r *ebml.Reader, head ebml.Element, id uint32) (int64, error) {
+	pos := int64(-1)
+	err := r.Children(head, nil, func(seek ebml.Element) error {
+		if seek.ID != ebml.IDSeek {
+			return nil
+		}
+		var seekID, seekPos uint64
+		hasPos := false
+		err := r.Children(seek, nil, func(c ebml.Element) error {
+			var err error
+			switch c.ID {
+			case ebml.IDSeekID:
+				seekID, err = r.Uint(c)
+			case ebml.IDSeekPosition:
+				seekPos, err = r.Uint(c)
+				hasPos = true
+			}
+			return err
+		})
+		if err == nil && hasPos && seekID == uint64(id) && pos < 0 {
+			pos = int64(seekPos)
+		}
+		return err
+	})
+	return pos, err
+}
+
+// readEditions reads the EditionEntries of a Chapters element.
+func readEditions(r *ebml.Reader, chapters ebml.Element) ([]Edition, error) {
+	var editions []Edition
+	err := r.Children(chapters, nil, func(e ebml.Element) error {
+		if e.ID != ebml.IDEditionEntry {
+			return nil
+		}
+		var ed Edition
+		err := r.Children(e, nil, func(c ebml.Element) error {
+			var v uint64
+			var err error
+			switch c.ID {
+			case ebml.IDEditionUID:
+				ed.UID, err = r.Uint(c)
+			case ebml.IDEditionFlagDefault:
+				v, err = r.Uint(c)
+				ed.Default = v != 0
+			case ebml.IDEditionFlagOrdered:
+				v, err = r.Uint(c)
+				ed.Ordered = v != 0
+			case ebml.IDEditionFlagHidden:
+				v, err = r.Uint(c)
+				ed.Hidden = v != 0
+			case ebml.IDChapterAtom:
+				var ch Chapter
+				ch, err = readChapterAtom(r, c)
+				ed.Chapters = append(ed.Chapters, ch)
+			}
+			return err
+		})
+		editions = append(editions, ed)
+		return err
+	})
+	return editions, err
+}
+
+// readChapterAtom reads a ChapterAtom and its nested atoms.
+func readChapterAtom(r *ebml.Reader, atom ebml.Element) (Chapter, error) {
+	ch := Chapter{Enabled: true}
+	err := r.Children(atom, nil, func(c ebml.Element) error {
+		var v uint64
+		var err error
+		switch c.ID {
+		case ebml.IDChapterUID:
+			ch.UID, err = r.Uint(c)
+		case ebml.IDChapterTimeStart:
+			ch.Start, err = r.Uint(c)
+		case ebml.IDChapterTimeEnd:
+			ch.End, err = r.Uint(c)
+		case ebml.IDChapterFlagEnabled:
+			v, err = r.Uint(c)
+			ch.Enabled = v != 0
+		case ebml.IDChapterFlagHidden:
+			v, err = r.Uint(c)
+			ch.Hidden = v != 0
+		case ebml.IDChapterSegmentUID:
+			ch.SegmentUID, err = r.Data(c)
+		case ebml.IDChapterDisplay:
+			var d ChapterDisplay
+			d, err = readChapterDisplay(r, c)
+			ch.Displays = append(ch.Displays, d)
+		case ebml.IDChapterAtom:
+			var child Chapter
+			child, err = readChapterAtom(r, c)
+			ch.Children = append(ch.Children, child)
+		}
+		return err
+	})
+	return ch, err
+}
+
+// readChapterDisplay reads a ChapterDisplay.
+func readChapterDisplay(r *ebml.Reader, display ebml.Element) (ChapterDisplay, error) {
+	var d ChapterDisplay
+	err := r.Children(display, nil, func(c ebml.Element) error {
+		var err error
+		switch c.ID {
+		case ebml.IDChapString:
+			d.String, err = r.String(c)
+		case ebml.IDChapLanguage:
+			var lang string
+			lang, err = r.String(c)
+			d.Languages = append(d.Languages, lang)
+		}
+		return err
+	})
+	if len(d.Languages) == 0 {
+		d.Languages = []string{"eng"}
+	}
+	return d, err
+}
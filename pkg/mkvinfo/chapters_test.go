@@ -0,0 +1,152 @@
+package mkvinfo
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// element encodes an EBML element with a one-byte size.
+func element(id []byte, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	out := append([]byte(nil), id...)
+	return append(append(out, 0x80|byte(len(body))), body...)
+}
+
+// chapterDisplay encodes a ChapterDisplay with a title and languages.
+func chapterDisplay(title string, langs ...string) []byte {
+	fields := [][]byte{element([]byte{0x85}, []byte(title))}
+	for _, l := range langs {
+		fields = append(fields, element([]byte{0x43, 0x7C}, []byte(l)))
+	}
+	return element([]byte{0x80}, fields...)
+}
+
+// testChapters returns a Chapters element with an ordinary edition and a
+// default ordered edition.
+func testChapters() []byte {
+	return element([]byte{0x10, 0x43, 0xA7, 0x70},
+		element([]byte{0x45, 0xB9},
+			element([]byte{0x45, 0xBC}, []byte{1}),
+			element([]byte{0xB6},
+				element([]byte{0x91}, []byte{0}),
+				chapterDisplay("Episode 1", "eng"),
+				chapterDisplay("第1話", "jpn"),
+			),
+			element([]byte{0xB6},
+				element([]byte{0x91}, []byte{0x05, 0x00}),
+				element([]byte{0x98}, []byte{1}), // hidden
+			),
+			element([]byte{0xB6},
+				element([]byte{0x91}, []byte{0x10, 0x00}),
+				element([]byte{0x92}, []byte{0x20, 0x00}),
+				chapterDisplay("Episode 2"),
+			),
+		),
+		element([]byte{0x45, 0xB9},
+			element([]byte{0x45, 0xDB}, []byte{1}),
+			element([]byte{0x45, 0xDD}, []byte{1}),
+			element([]byte{0xB6},
+				element([]byte{0x6E, 0x67}, []byte{0xAA, 0xBB}),
+				element([]byte{0x45, 0x98}, []byte{0}), // disabled
+			),
+		),
+	)
+}
+
+func TestReadChapters(t *testing.T) {
+	segment := append([]byte{0x18, 0x53, 0x80, 0x67, 0xFF}, testChapters()...)
+	data := append(element([]byte{0x1A, 0x45, 0xDF, 0xA3}), segment...)
+
+	editions, err := readChapters(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(editions) != 2 {
+		t.Fatalf("got %d editions, want 2", len(editions))
+	}
+
+	ed := editions[0]
+	if ed.UID != 1 || ed.Default || ed.Ordered || len(ed.Chapters) != 3 {
+		t.Fatalf("edition 0 = %+v", ed)
+	}
+	if got := ed.Chapters[0].Title("jpn"); got != "第1話" {
+		t.Errorf("Title(jpn) = %q", got)
+	}
+	if got := ed.Chapters[0].Title("chi"); got != "Episode 1" {
+		t.Errorf("Title(chi) = %q, want the first title", got)
+	}
+	if got := ed.Chapters[2].Displays[0].Languages; !reflect.DeepEqual(got, []string{"eng"}) {
+		t.Errorf("default ChapLanguage = %v", got)
+	}
+
+	listed := ed.ListedChapters()
+	if len(listed) != 2 || listed[0].End != 0x1000 || listed[1].End != 0x2000 {
+		t.Errorf("ListedChapters() = %+v", listed)
+	}
+
+	def := DefaultEdition(editions)
+	if def != &editions[1] || !def.Ordered {
+		t.Fatalf("DefaultEdition() = %+v", def)
+	}
+	if c := def.Chapters[0]; c.Visible() || !bytes.Equal(c.SegmentUID, []byte{0xAA, 0xBB}) {
+		t.Errorf("ordered chapter = %+v", c)
+	}
+}
+
+func TestReadChapters_SeekHead(t *testing.T) {
+	// The Chapters element follows a Cluster; the SeekHead points to it.
+	cluster := element([]byte{0x1F, 0x43, 0xB6, 0x75}, element([]byte{0xE7}, []byte{0}))
+	seekHead := element([]byte{0x11, 0x4D, 0x9B, 0x74},
+		element([]byte{0x4D, 0xBB},
+			element([]byte{0x53, 0xAB}, []byte{0x10, 0x43, 0xA7, 0x70}),
+			element([]byte{0x53, 0xAC}, []byte{0}), // patched below
+		),
+	)
+	seekHead[len(seekHead)-1] = byte(len(seekHead) + len(cluster))
+
+	segment := append([]byte{0x18, 0x53, 0x80, 0x67, 0xFF}, seekHead...)
+	segment = append(append(segment, cluster...), testChapters()...)
+	data := append(element([]byte{0x1A, 0x45, 0xDF, 0xA3}), segment...)
+
+	editions, err := readChapters(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(editions) != 2 {
+		t.Errorf("got %d editions, want 2", len(editions))
+	}
+}
+
+func TestReadChapters_None(t *testing.T) {
+	data := append(element([]byte{0x1A, 0x45, 0xDF, 0xA3}), element([]byte{0x18, 0x53, 0x80, 0x67})...)
+	editions, err := readChapters(bytes.NewReader(data))
+	if err != nil || editions != nil {
+		t.Errorf("readChapters() = %v, %v; want no editions", editions, err)
+	}
+	if DefaultEdition(editions) != nil {
+		t.Error("DefaultEdition(nil) != nil")
+	}
+}
+
+func TestReadSegmentUID(t *testing.T) {
+	info := element([]byte{0x15, 0x49, 0xA9, 0x66},
+		element([]byte{0x2A, 0xD7, 0xB1}, []byte{0x0F, 0x42, 0x40}),
+		element([]byte{0x73, 0xA4}, []byte{0xAA, 0xBB, 0xCC}),
+	)
+	segment := append([]byte{0x18, 0x53, 0x80, 0x67, 0xFF}, append(testChapters(), info...)...)
+	data := append(element([]byte{0x1A, 0x45, 0xDF, 0xA3}), segment...)
+
+	uid, err := readSegmentUID(bytes.NewReader(data))
+	if err != nil || !bytes.Equal(uid, []byte{0xAA, 0xBB, 0xCC}) {
+		t.Errorf("readSegmentUID() = %x, %v", uid, err)
+	}
+
+	editions, _ := readChapters(bytes.NewReader(data))
+	if got := editions[0].PlaybackChapters(); len(got) != 3 {
+		t.Errorf("PlaybackChapters() kept %d chapters, want 3 (hidden ones play)", len(got))
+	}
+	if got := editions[1].PlaybackChapters(); len(got) != 0 {
+		t.Errorf("PlaybackChapters() kept %d disabled chapters", len(got))
+	}
+}
@@ -24,29 +24,53 @@ func ShouldShowDefault(tracks []SubtitleTrack) bool {
 // Format:
 //
 //	File: {FileName}
-//	Size: {size} | Duration: {duration} | Subtitle tracks: {count}
+//	Size: {size} | Duration: {duration} | Video: {w}x{h} | Subtitle tracks: {count}
+//
+// The video part is omitted when the file has no video track. Anamorphic
+// video also shows the display size, e.g. "Video: 720x480 (display 853x480)".
 func FormatFileInfoHeader(info FileInfo) string {
 	size := FormatFileSize(info.FileSize)
 	dur := FormatDuration(info.Duration)
-	return fmt.Sprintf("File: %s\nSize: %s | Duration: %s | Subtitle tracks: %d",
-		info.FileName, size, dur, info.SubtitleCount)
+
+	video := ""
+	if v := info.Video; !v.IsZero() {
+		video = fmt.Sprintf(" | Video: %dx%d", v.PixelWidth, v.PixelHeight)
+		if v.IsAnamorphic() {
+			video += fmt.Sprintf(" (display %dx%d)", v.DisplayWidth, v.DisplayHeight)
+		}
+	}
+
+	return fmt.Sprintf("File: %s\nSize: %s | Duration: %s%s | Subtitle tracks: %d",
+		info.FileName, size, dur, video, info.SubtitleCount)
 }
 
 // FormatTrackLine returns a single formatted line for a subtitle track.
 //
 // For text tracks:
 //
-//	[{Index}] {LanguageName} ({FormatType}) "Name" CodecID [default] [forced]
+//	[{Index}] {LanguageName} [{Detected}] [{Chinese}] ({FormatType}) "Name" CodecID [default] [forced]
+//
+// where {Detected} marks a language detected rather than tagged (see
+// DetectedLanguage.Label), such as "detected 92%", and {Chinese} is the
+// detected written form of a Chinese track (see ChineseText.Label), such as
+// "Simplified".
 //
 // For image tracks, appends: -- not extractable (image subtitle)
 //
-// Parts are space-separated. Optional parts (name, flags) are omitted when empty/false.
+// Parts are space-separated. Optional parts (detection, written form, name,
+// flags) are omitted when empty/false.
 func FormatTrackLine(t SubtitleTrack, showDefault bool) string {
 	var parts []string
 
 	// Index and language
 	parts = append(parts, fmt.Sprintf("[%d]", t.Index))
 	parts = append(parts, t.LanguageName)
+	if label := t.Detected.Label(); label != "" {
+		parts = append(parts, fmt.Sprintf("[%s]", label))
+	}
+	if label := t.Chinese.Label(); label != "" {
+		parts = append(parts, fmt.Sprintf("[%s]", label))
+	}
 	parts = append(parts, fmt.Sprintf("(%s)", t.FormatType))
 
 	// Track name (only if non-empty, wrapped in double quotes)
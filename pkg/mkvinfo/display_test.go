@@ -66,6 +66,39 @@ func TestFormatTrackLine_NoTrackName(t *testing.T) {
 	}
 }
 
+func TestFormatTrackLine_ChineseText(t *testing.T) {
+	track := SubtitleTrack{
+		Index:        3,
+		LanguageName: "中文",
+		FormatType:   "ASS",
+		CodecID:      "S_TEXT/ASS",
+		IsText:       true,
+		Chinese:      ChineseText{Script: "Hant", Second: "jpn"},
+	}
+	got := FormatTrackLine(track, false)
+	want := `[3] 中文 [Traditional+Japanese] (ASS) S_TEXT/ASS`
+	if got != want {
+		t.Errorf("FormatTrackLine() =\n  %q\nwant:\n  %q", got, want)
+	}
+}
+
+func TestFormatTrackLine_DetectedLanguage(t *testing.T) {
+	track := SubtitleTrack{
+		Index:        2,
+		Language:     "und",
+		LanguageName: "English",
+		FormatType:   "SRT",
+		CodecID:      "S_TEXT/UTF8",
+		IsText:       true,
+		Detected:     DetectedLanguage{Code: "eng", Confidence: 0.923},
+	}
+	got := FormatTrackLine(track, false)
+	want := `[2] English [detected 92%] (SRT) S_TEXT/UTF8`
+	if got != want {
+		t.Errorf("FormatTrackLine() =\n  %q\nwant:\n  %q", got, want)
+	}
+}
+
 func TestFormatTrackLine_NoFlags(t *testing.T) {
 	track := SubtitleTrack{
 		Index:        3,
@@ -280,3 +313,21 @@ func TestShouldShowDefault_SingleNonDefault(t *testing.T) {
 		t.Error("ShouldShowDefault() should return true when single track is not default")
 	}
 }
+
+func TestFormatFileInfoHeader_Video(t *testing.T) {
+	info := FileInfo{FileName: "movie.mkv", SubtitleCount: 1}
+
+	if got := FormatFileInfoHeader(info); strings.Contains(got, "Video:") {
+		t.Errorf("header without video track should omit video, got: %q", got)
+	}
+
+	info.Video = VideoInfo{PixelWidth: 1920, PixelHeight: 1080, DisplayWidth: 1920, DisplayHeight: 1080}
+	if got := FormatFileInfoHeader(info); !strings.Contains(got, "| Video: 1920x1080 |") {
+		t.Errorf("header should contain video size, got: %q", got)
+	}
+
+	info.Video = VideoInfo{PixelWidth: 720, PixelHeight: 480, DisplayWidth: 853, DisplayHeight: 480}
+	if got := FormatFileInfoHeader(info); !strings.Contains(got, "Video: 720x480 (display 853x480)") {
+		t.Errorf("header should show anamorphic display size, got: %q", got)
+	}
+}
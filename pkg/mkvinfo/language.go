@@ -1,6 +1,9 @@
 package mkvinfo
 
 import (
+	"fmt"
+	"strings"
+
 	"golang.org/x/text/language"
 	"golang.org/x/text/language/display"
 )
@@ -11,7 +14,7 @@ import (
 // Fallback chain: native-script name -> English name -> raw code -> "Unknown".
 // Empty string and "und" (undetermined) return "Unknown".
 func ResolveLanguageName(code string) string {
-	if code == "" || code == "und" {
+	if IsUntagged(code) {
 		return "Unknown"
 	}
 
@@ -34,3 +37,103 @@ func ResolveLanguageName(code string) string {
 
 	return code
 }
+
+// IsUntagged reports whether code leaves a track's language unknown: it is
+// empty or "und" (undetermined).
+func IsUntagged(code string) bool {
+	return code == "" || code == "und"
+}
+
+// SameLanguage reports whether two language codes name the same language,
+// whatever their form: "chi", "zho" and "zh" are all Chinese. Unparseable
+// codes are compared as they are, ignoring case.
+func SameLanguage(a, b string) bool {
+	baseA, errA := language.ParseBase(a)
+	baseB, errB := language.ParseBase(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	canonA, _ := language.Make(baseA.String()).Base()
+	canonB, _ := language.Make(baseB.String()).Base()
+	return canonA == canonB
+}
+
+// DetectedLanguage is the language of an untagged track, as detected from
+// its text.
+type DetectedLanguage struct {
+	Code       string  // ISO 639-2 code, e.g. "eng"
+	Confidence float64 // from 0 to 1
+}
+
+// IsZero reports whether no language was detected.
+func (d DetectedLanguage) IsZero() bool {
+	return d.Code == ""
+}
+
+// Label returns a short note for track listings telling the language was
+// detected rather than tagged, such as "detected 92%"; "" when nothing was
+// detected.
+func (d DetectedLanguage) Label() string {
+	if d.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("detected %.0f%%", d.Confidence*100)
+}
+
+// ChineseText is the written form of a Chinese subtitle track, as detected
+// from its text: the script of its Chinese lines and, for a bilingual track,
+// the other language.
+type ChineseText struct {
+	Script string // ISO 15924: "Hans" (Simplified) or "Hant" (Traditional); "" if unknown
+	Second string // ISO 639-2 code of a bilingual track's other language, "jpn" or "eng"; "" if none
+}
+
+// IsZero reports whether nothing was detected.
+func (c ChineseText) IsZero() bool {
+	return c.Script == "" && c.Second == ""
+}
+
+// Tag returns the BCP 47 tag of the Chinese lines ("zh-Hans", "zh-Hant", or
+// "zh" when the script is unknown), followed by "+" and the other language
+// for a bilingual track: for example "zh-Hans+jpn". It returns "" when
+// nothing was detected.
+func (c ChineseText) Tag() string {
+	if c.IsZero() {
+		return ""
+	}
+	tag := "zh"
+	if c.Script != "" {
+		tag += "-" + c.Script
+	}
+	if c.Second != "" {
+		tag += "+" + c.Second
+	}
+	return tag
+}
+
+// Label returns a short description for track listings, such as
+// "Simplified", "Traditional" or "Simplified+Japanese"; "" when nothing was
+// detected.
+func (c ChineseText) Label() string {
+	var parts []string
+	switch c.Script {
+	case "Hans":
+		parts = append(parts, "Simplified")
+	case "Hant":
+		parts = append(parts, "Traditional")
+	case "":
+		if c.Second != "" {
+			parts = append(parts, "Chinese")
+		}
+	}
+	if c.Second != "" {
+		name := c.Second
+		if tag, err := language.ParseBase(c.Second); err == nil {
+			if n := display.English.Languages().Name(language.Make(tag.String())); n != "" {
+				name = n
+			}
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, "+")
+}
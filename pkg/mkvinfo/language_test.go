@@ -54,3 +54,63 @@ func TestResolveLanguageName_BibliographicCodes(t *testing.T) {
 	}
 	t.Logf("ger -> %q", gerName)
 }
+
+func TestChineseText(t *testing.T) {
+	tests := []struct {
+		c          ChineseText
+		tag, label string
+	}{
+		{ChineseText{}, "", ""},
+		{ChineseText{Script: "Hans"}, "zh-Hans", "Simplified"},
+		{ChineseText{Script: "Hant"}, "zh-Hant", "Traditional"},
+		{ChineseText{Script: "Hans", Second: "jpn"}, "zh-Hans+jpn", "Simplified+Japanese"},
+		{ChineseText{Second: "eng"}, "zh+eng", "Chinese+English"},
+	}
+	for _, tt := range tests {
+		if got := tt.c.Tag(); got != tt.tag {
+			t.Errorf("%+v.Tag() = %q, want %q", tt.c, got, tt.tag)
+		}
+		if got := tt.c.Label(); got != tt.label {
+			t.Errorf("%+v.Label() = %q, want %q", tt.c, got, tt.label)
+		}
+	}
+}
+
+func TestSameLanguage(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"chi", "zho", true},
+		{"chi", "zh", true},
+		{"ger", "de", true},
+		{"eng", "ENG", true},
+		{"eng", "jpn", false},
+		{"eng", "", false},
+		{"xx-1", "XX-1", true},
+	}
+	for _, tt := range tests {
+		if got := SameLanguage(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameLanguage(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSubtitleTrack_EffectiveLanguage(t *testing.T) {
+	detected := DetectedLanguage{Code: "eng", Confidence: 0.9}
+	tests := []struct {
+		name  string
+		track SubtitleTrack
+		want  string
+	}{
+		{"tagged", SubtitleTrack{Language: "jpn", Detected: detected}, "jpn"},
+		{"und detected", SubtitleTrack{Language: "und", Detected: detected}, "eng"},
+		{"empty detected", SubtitleTrack{Detected: detected}, "eng"},
+		{"und not detected", SubtitleTrack{Language: "und"}, "und"},
+	}
+	for _, tt := range tests {
+		if got := tt.track.EffectiveLanguage(); got != tt.want {
+			t.Errorf("%s: EffectiveLanguage() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
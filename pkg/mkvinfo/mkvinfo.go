@@ -35,17 +35,7 @@ func GetMKVInfo(path string) (*MKVInfo, error) {
 		return nil, fmt.Errorf("cannot read file info: %w", err)
 	}
 
-	// Matroska Duration is a float element, but matroska-go reads it via ReadUInt(),
-	// returning the raw IEEE 754 bits as uint64. Reinterpret as float, then scale.
-	// The element may be 4-byte (float32) or 8-byte (float64); uint64 values
-	// fitting in 32 bits are float32 (any real float64 has upper bits set).
-	var durationTicks float64
-	if segInfo.Duration <= math.MaxUint32 {
-		durationTicks = float64(math.Float32frombits(uint32(segInfo.Duration)))
-	} else {
-		durationTicks = math.Float64frombits(segInfo.Duration)
-	}
-	duration := time.Duration(durationTicks * float64(segInfo.TimecodeScale))
+	duration := SegmentDuration(segInfo.Duration, segInfo.TimecodeScale)
 
 	numTracks, err := demuxer.GetNumTracks()
 	if err != nil {
@@ -53,6 +43,7 @@ func GetMKVInfo(path string) (*MKVInfo, error) {
 	}
 
 	var tracks []SubtitleTrack
+	var video VideoInfo
 	displayIndex := 1
 
 	for i := uint(0); i < numTracks; i++ {
@@ -61,6 +52,17 @@ func GetMKVInfo(path string) (*MKVInfo, error) {
 			continue // Skip tracks that can't be read
 		}
 
+		// Remember the first video track's dimensions for PlayRes decisions
+		if info.Type == matroska.TypeVideo && video.IsZero() {
+			video = VideoInfo{
+				PixelWidth:    int(info.Video.PixelWidth),
+				PixelHeight:   int(info.Video.PixelHeight),
+				DisplayWidth:  int(info.Video.DisplayWidth),
+				DisplayHeight: int(info.Video.DisplayHeight),
+			}
+			continue
+		}
+
 		// Filter to subtitle tracks only (Type == 17)
 		if info.Type != matroska.TypeSubtitle {
 			continue
@@ -102,9 +104,26 @@ func GetMKVInfo(path string) (*MKVInfo, error) {
 			Duration:      duration,
 			SubtitleCount: subtitleCount,
 			TextSubCount:  textSubCount,
+			Video:         video,
 		},
 		Tracks: tracks,
 	}
 
 	return result, nil
 }
+
+// SegmentDuration converts the segment Duration reported by matroska-go to a
+// time.Duration. Matroska Duration is a float element, but matroska-go reads
+// it via ReadUInt(), returning the raw IEEE 754 bits as uint64. Reinterpret as
+// float, then scale by timecodeScale (nanoseconds per tick).
+func SegmentDuration(raw, timecodeScale uint64) time.Duration {
+	// The element may be 4-byte (float32) or 8-byte (float64); uint64 values
+	// fitting in 32 bits are float32 (any real float64 has upper bits set).
+	var durationTicks float64
+	if raw <= math.MaxUint32 {
+		durationTicks = float64(math.Float32frombits(uint32(raw)))
+	} else {
+		durationTicks = math.Float64frombits(raw)
+	}
+	return time.Duration(durationTicks * float64(timecodeScale))
+}
@@ -9,6 +9,34 @@ type FileInfo struct {
 	Duration      time.Duration // from SegmentInfo.Duration nanoseconds
 	SubtitleCount int           // total subtitle tracks including image-based
 	TextSubCount  int           // extractable text subtitle tracks only
+	Video         VideoInfo     // first video track; zero if the file has none
+}
+
+// VideoInfo holds the frame dimensions of a video track.
+//
+// DisplayWidth/DisplayHeight give the intended display size. They equal the
+// pixel size unless the video is anamorphic (e.g. a 720x480 DVD shown at
+// 16:9), and may be in other units than pixels, so only their ratio is
+// meaningful.
+type VideoInfo struct {
+	PixelWidth    int
+	PixelHeight   int
+	DisplayWidth  int
+	DisplayHeight int
+}
+
+// IsZero reports whether no video track was found.
+func (v VideoInfo) IsZero() bool {
+	return v.PixelWidth == 0 || v.PixelHeight == 0
+}
+
+// IsAnamorphic reports whether the display aspect ratio differs from the
+// pixel aspect ratio.
+func (v VideoInfo) IsAnamorphic() bool {
+	if v.IsZero() || v.DisplayWidth == 0 || v.DisplayHeight == 0 {
+		return false
+	}
+	return v.PixelWidth*v.DisplayHeight != v.PixelHeight*v.DisplayWidth
 }
 
 // SubtitleTrack holds metadata for a single subtitle track.
@@ -24,6 +52,24 @@ type SubtitleTrack struct {
 	IsForced      bool   // FlagForced
 	IsText        bool   // true for S_TEXT/* codecs
 	IsExtractable bool   // true if text-based and extractable
+
+	// Detected is the language of an untagged track (Language "" or "und"),
+	// detected from its text; zero when not detected.
+	Detected DetectedLanguage
+
+	// Chinese is the written form of a Chinese track, detected from its
+	// text; zero when not detected.
+	Chinese ChineseText
+}
+
+// EffectiveLanguage returns the track's language: its tagged ISO 639-2 code,
+// or the detected one when the track is untagged and its language was
+// detected, else Language as it is.
+func (t SubtitleTrack) EffectiveLanguage() string {
+	if IsUntagged(t.Language) && t.Detected.Code != "" {
+		return t.Detected.Code
+	}
+	return t.Language
 }
 
 // MKVInfo bundles FileInfo and subtitle tracks returned from the main parsing function.
@@ -17,34 +17,51 @@ var reMultiUnderscore = regexp.MustCompile(`_+`)
 
 // GenerateOutputPath generates the output ASS file path for a subtitle track.
 //
-// Format: {video_basename}.{lang_code}.ass with ISO 639-2 three-letter codes.
+// Format: {video_basename}.{lang_code}.ass with ISO 639-2 three-letter codes
+// (the detected language of an untagged track, when known).
 //
 // Collision handling:
 //  1. If path is unique, return it
-//  2. If track has a non-empty Name, try {basename}.{lang}.{sanitized_name}.ass
-//  3. Otherwise, try sequence numbers: {basename}.{lang}.2.ass, .3.ass, etc.
+//  2. If track is Chinese with a detected written form, try its BCP 47 tag
+//     (see mkvinfo.ChineseText.Tag): {basename}.zh-Hant.ass, or
+//     {basename}.zh-Hans+jpn.ass for a bilingual track. The track named
+//     first keeps its plain name; callers naming a set of tracks mark every
+//     track of a shared name up front (see extract.ChineseOptions.TagSharedNames)
+//  3. If track has a non-empty Name, try {basename}.{lang}.{sanitized_name}.ass
+//  4. Otherwise, try sequence numbers: {basename}.{lang}.2.ass, .3.ass, etc.
 //
 // The returned path is automatically added to existingPaths.
 func GenerateOutputPath(videoPath string, track mkvinfo.SubtitleTrack, existingPaths map[string]bool) string {
+	return generatePath(videoPath, track, "ass", existingPaths)
+}
+
+// generatePath implements GenerateOutputPath for files with extension ext.
+func generatePath(videoPath string, track mkvinfo.SubtitleTrack, ext string, existingPaths map[string]bool) string {
 	dir := filepath.Dir(videoPath)
 	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
 
-	lang := track.Language
-	if lang == "" || lang == "und" {
-		lang = "und"
-	}
+	lang := languageCode(track)
 
 	// Try basic path first
-	candidate := filepath.Join(dir, fmt.Sprintf("%s.%s.ass", base, lang))
+	candidate := filepath.Join(dir, fmt.Sprintf("%s.%s.%s", base, lang, ext))
 	if !existingPaths[candidate] {
 		existingPaths[candidate] = true
 		return candidate
 	}
 
+	// Collision: try with the detected Chinese written form
+	if tag := track.Chinese.Tag(); tag != "" {
+		candidate = filepath.Join(dir, fmt.Sprintf("%s.%s.%s", base, tag, ext))
+		if !existingPaths[candidate] {
+			existingPaths[candidate] = true
+			return candidate
+		}
+	}
+
 	// Collision: try with sanitized track name
 	if track.Name != "" {
 		sanitized := sanitizeFileName(track.Name)
-		candidate = filepath.Join(dir, fmt.Sprintf("%s.%s.%s.ass", base, lang, sanitized))
+		candidate = filepath.Join(dir, fmt.Sprintf("%s.%s.%s.%s", base, lang, sanitized, ext))
 		if !existingPaths[candidate] {
 			existingPaths[candidate] = true
 			return candidate
@@ -53,7 +70,7 @@ func GenerateOutputPath(videoPath string, track mkvinfo.SubtitleTrack, existingP
 
 	// Fallback: sequence numbers starting at 2
 	for n := 2; ; n++ {
-		candidate = filepath.Join(dir, fmt.Sprintf("%s.%s.%d.ass", base, lang, n))
+		candidate = filepath.Join(dir, fmt.Sprintf("%s.%s.%d.%s", base, lang, n, ext))
 		if !existingPaths[candidate] {
 			existingPaths[candidate] = true
 			return candidate
@@ -61,6 +78,81 @@ func GenerateOutputPath(videoPath string, track mkvinfo.SubtitleTrack, existingP
 	}
 }
 
+// GenerateChapterOutputPath is GenerateOutputPath for the part of a track
+// covering one chapter or chapter range, labelled by its chapter number (or
+// range, e.g. "01-03") and the chapter title:
+// {video_basename}.{label}.{sanitized_title}.{lang_code}.ass, or without the
+// title when it is empty. For example chapter 2 "Episode 2" of "season.mkv"
+// becomes "season.02.Episode 2.jpn.ass".
+func GenerateChapterOutputPath(videoPath, label, title string, track mkvinfo.SubtitleTrack, existingPaths map[string]bool) string {
+	base := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + "." + label
+	if title != "" {
+		base += "." + sanitizeFileName(title)
+	}
+	return GenerateOutputPath(base+filepath.Ext(videoPath), track, existingPaths)
+}
+
+// GenerateBilingualOutputPath is GenerateOutputPath for a file holding two
+// tracks: {video_basename}.{lang1}+{lang2}.ass, with the primary track's
+// language first. For example Chinese and English tracks of "ep01.mkv" become
+// "ep01.chi+eng.ass". Collisions are resolved as in GenerateOutputPath,
+// using the primary track's name.
+func GenerateBilingualOutputPath(videoPath string, primary, secondary mkvinfo.SubtitleTrack, existingPaths map[string]bool) string {
+	return generatePath(videoPath, pairTrack(primary, secondary), "ass", existingPaths)
+}
+
+// GeneratePairsOutputPath is GenerateBilingualOutputPath for sentence pairs
+// exported from two tracks, with extension ext ("csv" or "tsv"): for example
+// "ep01.chi+eng.tsv".
+func GeneratePairsOutputPath(videoPath string, a, b mkvinfo.SubtitleTrack, ext string, existingPaths map[string]bool) string {
+	return generatePath(videoPath, pairTrack(a, b), ext, existingPaths)
+}
+
+// pairTrack returns the track naming a file made from two tracks: the
+// first's, with both languages joined by "+".
+func pairTrack(a, b mkvinfo.SubtitleTrack) mkvinfo.SubtitleTrack {
+	track := a
+	track.Language = languageCode(a) + "+" + languageCode(b)
+	track.Chinese = mkvinfo.ChineseText{}
+	return track
+}
+
+// languageCode returns the language part of a track's file names: its
+// language, tagged or detected (see mkvinfo.SubtitleTrack.EffectiveLanguage),
+// or "und".
+func languageCode(track mkvinfo.SubtitleTrack) string {
+	if lang := track.EffectiveLanguage(); lang != "" {
+		return lang
+	}
+	return "und"
+}
+
+// ResampledPath returns the output path for a standalone resampled script:
+// {script_basename}.{WxH}.ass in outputDir, or next to the script when
+// outputDir is empty. For example "ep01.ass" resampled to 1920x1080 becomes
+// "ep01.1920x1080.ass".
+func ResampledPath(scriptPath, outputDir, resolution string) string {
+	dir := outputDir
+	if dir == "" {
+		dir = filepath.Dir(scriptPath)
+	}
+	base := strings.TrimSuffix(filepath.Base(scriptPath), filepath.Ext(scriptPath))
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.ass", base, resolution))
+}
+
+// SidecarPath returns the path of a file derived from the video, such as its
+// timecodes or keyframes: {video_basename}.{suffix} in outputDir, or next to
+// the video when outputDir is empty. For example "ep01.mkv" with suffix
+// "keyframes.txt" becomes "ep01.keyframes.txt".
+func SidecarPath(videoPath, outputDir, suffix string) string {
+	dir := outputDir
+	if dir == "" {
+		dir = filepath.Dir(videoPath)
+	}
+	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	return filepath.Join(dir, base+"."+suffix)
+}
+
 // sanitizeFileName makes a track name safe for use in filenames.
 //
 // Replaces characters not in [a-zA-Z0-9_\-. ] with underscore,
@@ -40,6 +40,17 @@ func TestGenerateOutputPath_UndLanguage(t *testing.T) {
 	}
 }
 
+func TestGenerateOutputPath_DetectedLanguage(t *testing.T) {
+	existing := make(map[string]bool)
+	track := mkvinfo.SubtitleTrack{Language: "und", Detected: mkvinfo.DetectedLanguage{Code: "eng", Confidence: 0.9}}
+
+	result := GenerateOutputPath("movie.mkv", track, existing)
+	expected := "movie.eng.ass"
+	if result != expected {
+		t.Errorf("got %q, want %q", result, expected)
+	}
+}
+
 func TestGenerateOutputPath_WithDirectory(t *testing.T) {
 	existing := make(map[string]bool)
 	track := mkvinfo.SubtitleTrack{Language: "chi"}
@@ -105,6 +116,31 @@ func TestGenerateOutputPath_TripleCollision(t *testing.T) {
 	}
 }
 
+func TestGenerateOutputPath_ChineseText(t *testing.T) {
+	existing := make(map[string]bool)
+	tracks := []mkvinfo.SubtitleTrack{
+		{Language: "chi", Chinese: mkvinfo.ChineseText{Script: "Hans"}},
+		{Language: "chi", Chinese: mkvinfo.ChineseText{Script: "Hant"}},
+		{Language: "chi", Chinese: mkvinfo.ChineseText{Script: "Hans", Second: "jpn"}},
+		{Language: "chi"},
+	}
+	// Named one at a time, the first track takes the plain name before the
+	// collision is known; extract.ChineseOptions.TagSharedNames names every
+	// such track by its form up front.
+	want := []string{"ep01.chi.ass", "ep01.zh-Hant.ass", "ep01.zh-Hans+jpn.ass", "ep01.chi.2.ass"}
+	for i, track := range tracks {
+		if got := GenerateOutputPath("ep01.mkv", track, existing); got != want[i] {
+			t.Errorf("track %d: got %q, want %q", i, got, want[i])
+		}
+	}
+
+	// A single Chinese track keeps its language code.
+	single := mkvinfo.SubtitleTrack{Language: "chi", Chinese: mkvinfo.ChineseText{Script: "Hant"}}
+	if got := GenerateOutputPath("video.mkv", single, make(map[string]bool)); got != "video.chi.ass" {
+		t.Errorf("single Chinese track: got %q, want %q", got, "video.chi.ass")
+	}
+}
+
 func TestGenerateOutputPath_MarksPathInExisting(t *testing.T) {
 	existing := make(map[string]bool)
 	track := mkvinfo.SubtitleTrack{Language: "jpn"}
@@ -166,3 +202,68 @@ func TestSanitizeFileName_AllowedChars(t *testing.T) {
 		t.Errorf("got %q, want %q", result, "Track-1 v2.0")
 	}
 }
+
+func TestGenerateChapterOutputPath(t *testing.T) {
+	existing := make(map[string]bool)
+	track := mkvinfo.SubtitleTrack{Language: "jpn"}
+
+	got := GenerateChapterOutputPath("season.mkv", "02", "Episode 2: Rain", track, existing)
+	if want := "season.02.Episode 2_ Rain.jpn.ass"; got != want {
+		t.Errorf("GenerateChapterOutputPath() = %q, want %q", got, want)
+	}
+	got = GenerateChapterOutputPath("season.mkv", "03-04", "", track, existing)
+	if want := "season.03-04.jpn.ass"; got != want {
+		t.Errorf("GenerateChapterOutputPath() without title = %q, want %q", got, want)
+	}
+	got = GenerateChapterOutputPath("season.mkv", "03-04", "", track, existing)
+	if want := "season.03-04.jpn.2.ass"; got != want {
+		t.Errorf("GenerateChapterOutputPath() collision = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateBilingualOutputPath(t *testing.T) {
+	existing := make(map[string]bool)
+	chi := mkvinfo.SubtitleTrack{Language: "chi", Name: "Simplified"}
+	eng := mkvinfo.SubtitleTrack{Language: "eng"}
+
+	got := GenerateBilingualOutputPath("ep01.mkv", chi, eng, existing)
+	if want := "ep01.chi+eng.ass"; got != want {
+		t.Errorf("GenerateBilingualOutputPath() = %q, want %q", got, want)
+	}
+	got = GenerateBilingualOutputPath("ep01.mkv", chi, eng, existing)
+	if want := "ep01.chi+eng.Simplified.ass"; got != want {
+		t.Errorf("GenerateBilingualOutputPath() collision = %q, want %q", got, want)
+	}
+	got = GenerateBilingualOutputPath("ep01.mkv", eng, mkvinfo.SubtitleTrack{}, existing)
+	if want := "ep01.eng+und.ass"; got != want {
+		t.Errorf("GenerateBilingualOutputPath() untagged = %q, want %q", got, want)
+	}
+}
+
+func TestResampledPath(t *testing.T) {
+	got := ResampledPath(filepath.Join("subs", "ep01.ssa"), "", "1920x1080")
+	want := filepath.Join("subs", "ep01.1920x1080.ass")
+	if got != want {
+		t.Errorf("ResampledPath() = %q, want %q", got, want)
+	}
+
+	got = ResampledPath(filepath.Join("subs", "ep01.ass"), "out", "1280x720")
+	want = filepath.Join("out", "ep01.1280x720.ass")
+	if got != want {
+		t.Errorf("ResampledPath() with output dir = %q, want %q", got, want)
+	}
+}
+
+func TestSidecarPath(t *testing.T) {
+	got := SidecarPath(filepath.Join("videos", "ep01.mkv"), "", "keyframes.txt")
+	want := filepath.Join("videos", "ep01.keyframes.txt")
+	if got != want {
+		t.Errorf("SidecarPath() = %q, want %q", got, want)
+	}
+
+	got = SidecarPath(filepath.Join("videos", "ep01.mkv"), "out", "timecodes.txt")
+	want = filepath.Join("out", "ep01.timecodes.txt")
+	if got != want {
+		t.Errorf("SidecarPath() with output dir = %q, want %q", got, want)
+	}
+}
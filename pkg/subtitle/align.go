@@ -0,0 +1,228 @@
+package subtitle
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultMinOverlap is the overlap, as a fraction of the shorter event, that
+// Align requires when AlignOptions.MinOverlap is zero.
+const DefaultMinOverlap = 0.5
+
+// AlignOptions controls Align. The zero value aligns events that overlap for
+// at least half of the shorter one, with no timing tolerance.
+type AlignOptions struct {
+	// Tolerance is the timing difference, in nanoseconds, forgiven between
+	// the two lists: it is added to every overlap, so events up to Tolerance
+	// apart still count as overlapping.
+	Tolerance uint64
+
+	// MinOverlap is the fraction (0-1] of the shorter of two events that
+	// must overlap for them to align. Zero means DefaultMinOverlap.
+	MinOverlap float64
+}
+
+// AlignedPair is a group of events from the first list aligned with a group
+// from the second, each in start order: one event on each side, one split
+// into several (one-to-many), several merged into one (many-to-one), or a
+// line without counterpart, whose other side is empty.
+type AlignedPair struct {
+	A, B       []SubtitleEvent
+	Start, End uint64 // span of all the group's events, nanoseconds
+}
+
+// Matched reports whether both sides of the pair have events; an unmatched
+// pair is a line missing in the other language.
+func (p AlignedPair) Matched() bool {
+	return len(p.A) > 0 && len(p.B) > 0
+}
+
+// Align pairs the events of a and b, typically the same dialogue in two
+// languages, by time overlap. Two events can be linked when their overlap
+// (plus opts.Tolerance) covers at least opts.MinOverlap of the shorter one.
+// Links are taken best first, by overlap relative to the longer event, and
+// only while the linked events stay one-to-one, one-to-many or many-to-one:
+// one event on one side, each event of the other lying inside it for at
+// least opts.MinOverlap of its length. A line split in two in one language
+// thus pairs with both halves, while a long sign overlapping many lines
+// does not pull them into one pair. Every event appears in exactly one pair.
+// Pairs are returned in start order.
+func Align(a, b []SubtitleEvent, opts AlignOptions) []AlignedPair {
+	minOverlap := opts.MinOverlap
+	if minOverlap <= 0 {
+		minOverlap = DefaultMinOverlap
+	}
+
+	sa, sb := sortedByStart(a), sortedByStart(b)
+	var links []alignLink
+	for i, ea := range sa {
+		for j, eb := range sb {
+			if eb.Start > ea.End+opts.Tolerance {
+				break
+			}
+			if aligned(ea, eb, opts.Tolerance, minOverlap) {
+				links = append(links, alignLink{i, j, overlapScore(ea, eb, opts.Tolerance)})
+			}
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].score > links[j].score })
+
+	groups := newUnionFind(len(sa) + len(sb))
+	members := make(map[int][]int) // group root -> nodes, for groups of two or more
+	nodes := func(root int) []int {
+		if m, ok := members[root]; ok {
+			return m
+		}
+		return []int{root}
+	}
+	for _, l := range links {
+		ra, rb := groups.find(l.a), groups.find(len(sa)+l.b)
+		if ra == rb {
+			continue
+		}
+		group := append(append([]int{}, nodes(ra)...), nodes(rb)...)
+		var ga, gb []SubtitleEvent
+		for _, node := range group {
+			if node < len(sa) {
+				ga = append(ga, sa[node])
+			} else {
+				gb = append(gb, sb[node-len(sa)])
+			}
+		}
+		if !groupAligned(ga, gb, opts.Tolerance, minOverlap) {
+			continue
+		}
+		groups.union(ra, rb)
+		delete(members, ra)
+		delete(members, rb)
+		members[groups.find(ra)] = group
+	}
+
+	index := make(map[int]int) // group root -> pair index
+	var pairs []AlignedPair
+	for node := range len(sa) + len(sb) {
+		root := groups.find(node)
+		n, ok := index[root]
+		if !ok {
+			n = len(pairs)
+			index[root] = n
+			pairs = append(pairs, AlignedPair{Start: math.MaxUint64})
+		}
+		p := &pairs[n]
+		var ev SubtitleEvent
+		if node < len(sa) {
+			ev = sa[node]
+			p.A = append(p.A, ev)
+		} else {
+			ev = sb[node-len(sa)]
+			p.B = append(p.B, ev)
+		}
+		p.Start, p.End = min(p.Start, ev.Start), max(p.End, ev.End)
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Start < pairs[j].Start })
+	return pairs
+}
+
+// alignLink links event a of the first list with event b of the second.
+type alignLink struct {
+	a, b  int
+	score float64 // see overlapScore
+}
+
+// aligned reports whether two events overlap enough to align.
+func aligned(a, b SubtitleEvent, tolerance uint64, minOverlap float64) bool {
+	if duration(a) > duration(b) {
+		a, b = b, a
+	}
+	return inside(a, b, tolerance, minOverlap)
+}
+
+// inside reports whether the overlap of part with whole (plus tolerance)
+// covers at least minOverlap of part.
+func inside(part, whole SubtitleEvent, tolerance uint64, minOverlap float64) bool {
+	overlap := overlapOf(part, whole, tolerance)
+	if overlap <= 0 {
+		return false
+	}
+	return float64(min(uint64(overlap), duration(part))) >= minOverlap*float64(duration(part))
+}
+
+// groupAligned reports whether events a and b may form one AlignedPair: one
+// side has a single event, and every event of the other lies inside it.
+func groupAligned(a, b []SubtitleEvent, tolerance uint64, minOverlap float64) bool {
+	allInside := func(parts []SubtitleEvent, whole SubtitleEvent) bool {
+		for _, part := range parts {
+			if !inside(part, whole, tolerance, minOverlap) {
+				return false
+			}
+		}
+		return true
+	}
+	return len(a) == 1 && allInside(b, a[0]) || len(b) == 1 && allInside(a, b[0])
+}
+
+// overlapScore rates how well two aligned events match: their overlap (plus
+// tolerance, at most the shorter event) as a fraction of the longer event.
+func overlapScore(a, b SubtitleEvent, tolerance uint64) float64 {
+	longer := max(duration(a), duration(b))
+	if longer == 0 {
+		return 1
+	}
+	overlap := min(uint64(max(overlapOf(a, b, tolerance), 0)), duration(a), duration(b))
+	return float64(overlap) / float64(longer)
+}
+
+// overlapOf returns the time a and b overlap plus tolerance, negative for
+// events further apart.
+func overlapOf(a, b SubtitleEvent, tolerance uint64) int64 {
+	return int64(min(a.End, b.End)) - int64(max(a.Start, b.Start)) + int64(tolerance)
+}
+
+// duration returns an event's length, zero for an event ending before it starts.
+func duration(ev SubtitleEvent) uint64 {
+	if ev.End <= ev.Start {
+		return 0
+	}
+	return ev.End - ev.Start
+}
+
+// sortedByStart returns a copy of events sorted by start time, then ReadOrder.
+func sortedByStart(events []SubtitleEvent) []SubtitleEvent {
+	sorted := make([]SubtitleEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Start != sorted[j].Start {
+			return sorted[i].Start < sorted[j].Start
+		}
+		return sorted[i].ReadOrder < sorted[j].ReadOrder
+	})
+	return sorted
+}
+
+// unionFind is a disjoint-set forest over nodes 0..n-1.
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	u := make(unionFind, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+// find returns the root of node's set.
+func (u unionFind) find(node int) int {
+	for u[node] != node {
+		u[node] = u[u[node]]
+		node = u[node]
+	}
+	return node
+}
+
+// union merges the sets of x and y.
+func (u unionFind) union(x, y int) {
+	if rx, ry := u.find(x), u.find(y); rx != ry {
+		u[max(rx, ry)] = min(rx, ry)
+	}
+}
@@ -0,0 +1,122 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// alignEvents builds events from "START-END TEXT" specs in tenths of a second.
+func alignEvents(specs ...string) []SubtitleEvent {
+	const tenth = uint64(100 * time.Millisecond)
+	var events []SubtitleEvent
+	for _, spec := range specs {
+		var start, end uint64
+		span, text, _ := strings.Cut(spec, " ")
+		s, e, _ := strings.Cut(span, "-")
+		for _, c := range s {
+			start = start*10 + uint64(c-'0')
+		}
+		for _, c := range e {
+			end = end*10 + uint64(c-'0')
+		}
+		events = append(events, SubtitleEvent{Start: start * tenth, End: end * tenth, Text: text})
+	}
+	return events
+}
+
+// pairTexts renders pairs as "a1+a2=b1" for comparison.
+func pairTexts(pairs []AlignedPair) string {
+	var out []string
+	for _, p := range pairs {
+		var a, b []string
+		for _, ev := range p.A {
+			a = append(a, ev.Text)
+		}
+		for _, ev := range p.B {
+			b = append(b, ev.Text)
+		}
+		out = append(out, strings.Join(a, "+")+"="+strings.Join(b, "+"))
+	}
+	return strings.Join(out, " ")
+}
+
+func TestAlign(t *testing.T) {
+	zh := alignEvents("10-30 你好", "30-60 我们走吧", "70-90 等等", "120-140 再见")
+	en := alignEvents(
+		"11-29 Hello",
+		"30-44 Let's", // split: both halves lie inside 我们走吧
+		"45-60 go",
+		"70-79 Wait,",
+		"100-110 (music)",
+		"121-139 Bye",
+	)
+
+	got := pairTexts(Align(zh, en, AlignOptions{}))
+	want := "你好=Hello 我们走吧=Let's+go 等等=Wait, =(music) 再见=Bye"
+	if got != want {
+		t.Errorf("Align() = %s\nwant       %s", got, want)
+	}
+
+	// Merge: two lines of one list covered by one line of the other.
+	got = pairTexts(Align(en[1:3], zh[1:2], AlignOptions{}))
+	if want := "Let's+go=我们走吧"; got != want {
+		t.Errorf("Align() merge = %s, want %s", got, want)
+	}
+
+	pairs := Align(zh, en, AlignOptions{})
+	if p := pairs[1]; p.Start != 3*uint64(time.Second) || p.End != 6*uint64(time.Second) || !p.Matched() {
+		t.Errorf("pair 1 = %+v", p)
+	}
+	if pairs[3].Matched() {
+		t.Errorf("pair 3 = %+v, want unmatched", pairs[3])
+	}
+}
+
+func TestAlign_LongEvent(t *testing.T) {
+	// A 60s sign overlaps ten dialogue lines that pair with their own
+	// translations; it must not merge them into one pair.
+	specs := make([]string, 10)
+	for i := range specs {
+		specs[i] = fmt.Sprintf("%d-%d line%d", 100+60*i, 150+60*i, i)
+	}
+	a := append(alignEvents("100-700 SIGN"), alignEvents(specs...)...)
+	b := alignEvents(specs...)
+
+	pairs := Align(a, b, AlignOptions{})
+	if len(pairs) != 11 {
+		t.Fatalf("Align() = %s, want 11 pairs", pairTexts(pairs))
+	}
+	for _, p := range pairs {
+		if len(p.A) > 1 || len(p.B) > 1 {
+			t.Errorf("pair %s, want one-to-one", pairTexts([]AlignedPair{p}))
+		}
+		if p.A[0].Text == "SIGN" && p.Matched() {
+			t.Errorf("sign paired as %s, want unmatched", pairTexts([]AlignedPair{p}))
+		}
+	}
+
+	// Alone, the sign takes the lines lying inside it (one-to-many).
+	if got, want := pairTexts(Align(a[:1], b[:3], AlignOptions{})), "SIGN=line0+line1+line2"; got != want {
+		t.Errorf("Align(sign alone) = %s, want %s", got, want)
+	}
+}
+
+func TestAlign_Tolerance(t *testing.T) {
+	// The second list runs 0.3s late: 1.5s lines overlap by 1.2s (80%).
+	a := alignEvents("10-25 one", "30-45 two")
+	b := alignEvents("13-28 uno", "33-48 dos")
+	if got, want := pairTexts(Align(a, b, AlignOptions{MinOverlap: 0.9})), "one= =uno two= =dos"; got != want {
+		t.Errorf("Align(MinOverlap 0.9) = %s, want %s", got, want)
+	}
+	opts := AlignOptions{MinOverlap: 0.9, Tolerance: uint64(300 * time.Millisecond)}
+	if got, want := pairTexts(Align(a, b, opts)), "one=uno two=dos"; got != want {
+		t.Errorf("Align(tolerance 0.3s) = %s, want %s", got, want)
+	}
+
+	// Lines that only touch do not align.
+	if got, want := pairTexts(Align(alignEvents("0-10 a"), alignEvents("10-20 b"), AlignOptions{})), "a= =b"; got != want {
+		t.Errorf("Align(touching) = %s, want %s", got, want)
+	}
+}
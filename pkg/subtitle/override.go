@@ -0,0 +1,508 @@
+package subtitle
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ASSText is ASS dialogue text parsed into nodes: TextRun, Break, Drawing and
+// OverrideBlock values in the order they appear. String re-serialises it; text
+// that was not modified is written back exactly as parsed.
+type ASSText []Node
+
+// Node is one piece of parsed dialogue text.
+type Node interface {
+	String() string
+	node()
+}
+
+// TextRun is literal text between override blocks and line breaks.
+type TextRun struct {
+	Text string
+}
+
+// BreakKind identifies the ASS escape a Break stands for.
+type BreakKind int
+
+const (
+	HardBreak BreakKind = iota // \N: always breaks the line
+	SoftBreak                  // \n: breaks only with WrapStyle 2, a space otherwise
+	HardSpace                  // \h: non-breaking space
+)
+
+// Break is a \N, \n or \h escape.
+type Break struct {
+	Kind BreakKind
+}
+
+// breakKinds maps the letter after a backslash to the escape it forms.
+var breakKinds = map[byte]BreakKind{'N': HardBreak, 'n': SoftBreak, 'h': HardSpace}
+
+// Drawing is text rendered as vector drawing commands while \p is non-zero.
+type Drawing struct {
+	Commands string
+}
+
+// OverrideBlock is one {...} block: override tags and any comment text.
+type OverrideBlock struct {
+	Items []BlockItem
+}
+
+// BlockItem is a Tag or a Comment inside an override block.
+type BlockItem interface {
+	String() string
+	blockItem()
+}
+
+// Comment is text inside an override block that is not part of a tag, such
+// as translator notes ("{TL note: ...}").
+type Comment struct {
+	Text string
+}
+
+func (TextRun) node()       {}
+func (Break) node()         {}
+func (Drawing) node()       {}
+func (OverrideBlock) node() {}
+func (Tag) blockItem()      {}
+func (Comment) blockItem()  {}
+
+func (r TextRun) String() string { return r.Text }
+func (d Drawing) String() string { return d.Commands }
+func (c Comment) String() string { return c.Text }
+
+func (b Break) String() string {
+	switch b.Kind {
+	case SoftBreak:
+		return `\n`
+	case HardSpace:
+		return `\h`
+	default:
+		return `\N`
+	}
+}
+
+func (b OverrideBlock) String() string {
+	var s strings.Builder
+	s.WriteByte('{')
+	for _, item := range b.Items {
+		s.WriteString(item.String())
+	}
+	s.WriteByte('}')
+	return s.String()
+}
+
+// String serialises the text.
+func (t ASSText) String() string {
+	var s strings.Builder
+	for _, n := range t {
+		s.WriteString(n.String())
+	}
+	return s.String()
+}
+
+// PlainText returns the visible text: override blocks and drawings are
+// dropped, \N becomes a newline, \n a space and \h a non-breaking space.
+func (t ASSText) PlainText() string {
+	var s strings.Builder
+	for _, n := range t {
+		switch n := n.(type) {
+		case TextRun:
+			s.WriteString(n.Text)
+		case Break:
+			switch n.Kind {
+			case HardBreak:
+				s.WriteByte('\n')
+			case SoftBreak:
+				s.WriteByte(' ')
+			case HardSpace:
+				s.WriteString("\u00A0")
+			}
+		}
+	}
+	return s.String()
+}
+
+// SpokenText returns the spoken words of ASS dialogue text on one line, for
+// export: override blocks, comments and drawings are dropped, and line
+// breaks and runs of spaces become single spaces.
+func SpokenText(text string) string {
+	return strings.Join(strings.Fields(ParseASSText(text).PlainText()), " ")
+}
+
+// Tags returns every tag in the text, including those nested in \t, in order.
+func (t ASSText) Tags() []Tag {
+	var tags []Tag
+	var walk func(items []BlockItem)
+	walk = func(items []BlockItem) {
+		for _, item := range items {
+			if tag, ok := item.(Tag); ok {
+				tags = append(tags, tag)
+				walk(tag.Nested)
+			}
+		}
+	}
+	for _, n := range t {
+		if b, ok := n.(OverrideBlock); ok {
+			walk(b.Items)
+		}
+	}
+	return tags
+}
+
+// ParseASSText parses ASS dialogue text. It never fails: an unterminated "{"
+// is kept as literal text, and tags it does not know parse as TagUnknown.
+func ParseASSText(text string) ASSText {
+	var nodes ASSText
+	drawing := false
+
+	addText := func(s string) {
+		if s == "" {
+			return
+		}
+		if drawing {
+			// Drawing commands contain no escapes.
+			nodes = append(nodes, Drawing{Commands: s})
+			return
+		}
+		start := 0
+		for i := 0; i+1 < len(s); i++ {
+			kind, ok := breakKinds[s[i+1]]
+			if s[i] != '\\' || !ok {
+				continue
+			}
+			if i > start {
+				nodes = append(nodes, TextRun{Text: s[start:i]})
+			}
+			nodes = append(nodes, Break{Kind: kind})
+			start = i + 2
+			i++
+		}
+		if start < len(s) {
+			nodes = append(nodes, TextRun{Text: s[start:]})
+		}
+	}
+
+	for text != "" {
+		open := strings.IndexByte(text, '{')
+		end := -1
+		if open >= 0 {
+			end = strings.IndexByte(text[open:], '}')
+		}
+		if open < 0 || end < 0 {
+			addText(text)
+			break
+		}
+		addText(text[:open])
+
+		block := OverrideBlock{Items: parseBlockItems(text[open+1 : open+end])}
+		for _, tag := range block.Items {
+			if tag, ok := tag.(Tag); ok && tag.Kind == TagDrawing {
+				n, _ := tag.Int()
+				drawing = n > 0
+			}
+		}
+		nodes = append(nodes, block)
+		text = text[open+end+1:]
+	}
+	return nodes
+}
+
+// parseBlockItems splits the inside of an override block into tags and comments.
+func parseBlockItems(s string) []BlockItem {
+	var items []BlockItem
+	for s != "" {
+		slash := strings.IndexByte(s, '\\')
+		if slash < 0 {
+			items = append(items, Comment{Text: s})
+			break
+		}
+		if slash > 0 {
+			items = append(items, Comment{Text: s[:slash]})
+		}
+		s = s[slash+1:]
+		n := tagEnd(s)
+		items = append(items, parseTag(s[:n]))
+		s = s[n:]
+	}
+	return items
+}
+
+// tagEnd returns the length of the tag at the start of s: up to the next
+// backslash outside parentheses.
+func tagEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case '\\':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// TagKind identifies an override tag independently of its spelling (\c and
+// \1c are both TagColour1, \fr and \frz both TagRotateZ).
+type TagKind int
+
+// Override tag kinds.
+const (
+	TagUnknown TagKind = iota
+	TagBold
+	TagItalic
+	TagUnderline
+	TagStrikeOut
+	TagBorder
+	TagBorderX
+	TagBorderY
+	TagShadow
+	TagShadowX
+	TagShadowY
+	TagBlurEdges
+	TagBlur
+	TagFontName
+	TagFontSize
+	TagFontScaleX
+	TagFontScaleY
+	TagSpacing
+	TagRotateX
+	TagRotateY
+	TagRotateZ
+	TagShearX
+	TagShearY
+	TagEncoding
+	TagColour1
+	TagColour2
+	TagColour3
+	TagColour4
+	TagAlpha
+	TagAlpha1
+	TagAlpha2
+	TagAlpha3
+	TagAlpha4
+	TagAlignment
+	TagLegacyAlignment
+	TagKaraoke
+	TagKaraokeFill
+	TagKaraokeOutline
+	TagKaraokeTime
+	TagWrapStyle
+	TagReset
+	TagPos
+	TagMove
+	TagOrigin
+	TagFade
+	TagFadeComplex
+	TagTransform
+	TagClip
+	TagInverseClip
+	TagDrawing
+	TagBaselineOffset
+)
+
+// tagKinds maps tag names to kinds. Names are matched case-sensitively, as
+// renderers do (\K is karaoke fill, \k plain karaoke).
+var tagKinds = map[string]TagKind{
+	"b": TagBold, "i": TagItalic, "u": TagUnderline, "s": TagStrikeOut,
+	"bord": TagBorder, "xbord": TagBorderX, "ybord": TagBorderY,
+	"shad": TagShadow, "xshad": TagShadowX, "yshad": TagShadowY,
+	"be": TagBlurEdges, "blur": TagBlur,
+	"fn": TagFontName, "fs": TagFontSize, "fscx": TagFontScaleX, "fscy": TagFontScaleY, "fsp": TagSpacing,
+	"frx": TagRotateX, "fry": TagRotateY, "frz": TagRotateZ, "fr": TagRotateZ,
+	"fax": TagShearX, "fay": TagShearY, "fe": TagEncoding,
+	"c": TagColour1, "1c": TagColour1, "2c": TagColour2, "3c": TagColour3, "4c": TagColour4,
+	"alpha": TagAlpha, "1a": TagAlpha1, "2a": TagAlpha2, "3a": TagAlpha3, "4a": TagAlpha4,
+	"an": TagAlignment, "a": TagLegacyAlignment,
+	"k": TagKaraoke, "K": TagKaraokeFill, "kf": TagKaraokeFill, "ko": TagKaraokeOutline, "kt": TagKaraokeTime,
+	"q": TagWrapStyle, "r": TagReset,
+	"pos": TagPos, "move": TagMove, "org": TagOrigin,
+	"fad": TagFade, "fade": TagFadeComplex, "t": TagTransform,
+	"clip": TagClip, "iclip": TagInverseClip,
+	"p": TagDrawing, "pbo": TagBaselineOffset,
+}
+
+// tagNames lists the keys of tagKinds longest first, so that prefixes ("\fs"
+// of "\fscx", "\p" of "\pos") do not match early.
+var tagNames = func() []string {
+	names := make([]string, 0, len(tagKinds))
+	for name := range tagKinds {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return names
+}()
+
+// Tag is one override tag.
+type Tag struct {
+	Kind TagKind
+	Name string // as written, without the backslash: "fs", "1c", "pos"
+
+	// Args holds the arguments. A simple tag has one ("" when omitted, which
+	// resets the value to the style's); a parenthesised tag has one per
+	// comma-separated value. For \t these are the times and acceleration
+	// before the animated tags, which are in Nested.
+	Args   []string
+	Paren  bool
+	Nested []BlockItem
+
+	raw, canon string // the tag as parsed and its re-serialisation, to detect edits
+}
+
+// NewTag returns a tag built from its name and arguments. Tags that take
+// several values (\pos, \move, \clip, \fad, ...) are written in parentheses.
+func NewTag(name string, args ...string) Tag {
+	kind := tagKinds[name]
+	paren := len(args) > 1
+	switch kind {
+	case TagPos, TagMove, TagOrigin, TagFade, TagFadeComplex, TagTransform, TagClip, TagInverseClip:
+		paren = true
+	}
+	return Tag{Kind: kind, Name: name, Args: args, Paren: paren}
+}
+
+// parseTag parses a tag without its leading backslash.
+func parseTag(s string) Tag {
+	tag := Tag{Name: s}
+	for _, name := range tagNames {
+		if strings.HasPrefix(s, name) {
+			tag.Kind, tag.Name = tagKinds[name], name
+			break
+		}
+	}
+	if tag.Kind == TagUnknown {
+		// Unknown tags are kept whole, as their argument syntax is unknown.
+		tag.raw, tag.canon = `\`+s, tag.format()
+		return tag
+	}
+
+	arg := s[len(tag.Name):]
+	if inner, ok := strings.CutPrefix(arg, "("); ok {
+		tag.Paren = true
+		inner = strings.TrimSuffix(inner, ")") // renderers tolerate a missing ")"
+		if tag.Kind == TagTransform {
+			if slash := strings.IndexByte(inner, '\\'); slash >= 0 {
+				tag.Nested = parseBlockItems(inner[slash:])
+				inner = strings.TrimSuffix(strings.TrimRight(inner[:slash], " "), ",")
+			}
+		}
+		if inner != "" {
+			tag.Args = splitArgs(inner)
+		}
+	} else {
+		tag.Args = []string{arg}
+	}
+	tag.raw, tag.canon = `\`+s, tag.format()
+	return tag
+}
+
+// splitArgs splits parenthesised arguments at commas. A vector clip's drawing
+// contains no commas, so a plain split keeps it in one argument.
+func splitArgs(s string) []string {
+	return strings.Split(s, ",")
+}
+
+// String renders the tag with its backslash, verbatim if it was not modified.
+func (t Tag) String() string {
+	out := t.format()
+	if t.raw != "" && out == t.canon {
+		return t.raw
+	}
+	return out
+}
+
+// format serialises the tag from its fields.
+func (t Tag) format() string {
+	if t.Kind == TagUnknown {
+		return `\` + t.Name + strings.Join(t.Args, "")
+	}
+	if !t.Paren {
+		return `\` + t.Name + strings.Join(t.Args, "")
+	}
+
+	var s strings.Builder
+	s.WriteString(`\` + t.Name + "(")
+	s.WriteString(strings.Join(t.Args, ","))
+	if len(t.Nested) > 0 {
+		if len(t.Args) > 0 {
+			s.WriteByte(',')
+		}
+		for _, item := range t.Nested {
+			s.WriteString(item.String())
+		}
+	}
+	s.WriteByte(')')
+	return s.String()
+}
+
+// Arg returns the i-th argument with surrounding spaces removed, or "".
+func (t Tag) Arg(i int) string {
+	if i < 0 || i >= len(t.Args) {
+		return ""
+	}
+	return strings.TrimSpace(t.Args[i])
+}
+
+// IsReset reports whether the tag has no argument, which resets the value to
+// the style's (\c, \fn, \bord, \r).
+func (t Tag) IsReset() bool {
+	return !t.Paren && t.Arg(0) == ""
+}
+
+// Float returns the first argument as a number.
+func (t Tag) Float() (float64, bool) {
+	v, err := strconv.ParseFloat(t.Arg(0), 64)
+	return v, err == nil
+}
+
+// Int returns the first argument as an integer.
+func (t Tag) Int() (int, bool) {
+	v, err := strconv.Atoi(t.Arg(0))
+	return v, err == nil
+}
+
+// Numbers returns all arguments as numbers; ok is false if any is not numeric.
+func (t Tag) Numbers() (nums []float64, ok bool) {
+	for i := range t.Args {
+		v, err := strconv.ParseFloat(t.Arg(i), 64)
+		if err != nil {
+			return nil, false
+		}
+		nums = append(nums, v)
+	}
+	return nums, true
+}
+
+// Colour returns the value of a colour tag (&HBBGGRR&) as 0xBBGGRR.
+func (t Tag) Colour() (uint32, bool) {
+	return parseASSHex(t.Arg(0))
+}
+
+// Alpha returns the value of an alpha tag (&HAA&), 0 being opaque.
+func (t Tag) Alpha() (uint8, bool) {
+	v, ok := parseASSHex(t.Arg(0))
+	return uint8(v), ok && v <= 0xFF
+}
+
+// parseASSHex reads an "&H...&" hexadecimal value. Like renderers it accepts
+// a missing "&H" prefix or trailing "&".
+func parseASSHex(s string) (uint32, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "&"), "H")
+	s = strings.TrimPrefix(s, "h")
+	s = strings.TrimSuffix(s, "&")
+	v, err := strconv.ParseUint(s, 16, 32)
+	return uint32(v), err == nil && s != ""
+}
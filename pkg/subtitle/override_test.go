@@ -0,0 +1,129 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseASSText_RoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain text",
+		`{\b1}bold{\b0} and {\i1\c&H0000FF&}red{\c\i0}`,
+		`Line one\NLine two\nsoft\hspace`,
+		`{\pos( 10 , 20 )\an8}top`,
+		`{\move(0,0,100,50,0,1000)\fad(200,300)}x`,
+		`{\t(0,500,0.5,\fs40\c&HFF&)\t(\frz30)}spin`,
+		`{\clip(2,m 0 0 l 4 0 4 4)\iclip(1,2,3,4)}x`,
+		`{\p1}m 0 0 l 10 0 10 10{\p0}text`,
+		`{TL note: pun\k20}ka{\kf30}ra{\K10\ko5}o`,
+		`{\fnArial Bold\fs+2\r}x`,
+		`{\pos(1,2}unclosed paren`,
+		`{unclosed brace`,
+		`{}empty block`,
+		`{\xyz12\blur0.5\be1}unknown tag`,
+		`back\slash \\N`,
+	}
+	for _, in := range inputs {
+		if got := ParseASSText(in).String(); got != in {
+			t.Errorf("round trip of %q gave %q", in, got)
+		}
+	}
+}
+
+func TestParseASSText_Nodes(t *testing.T) {
+	got := ParseASSText(`{note\b1}Hi\Nthere{\p2}m 0 0{\p0}`)
+
+	if len(got) != 7 {
+		t.Fatalf("got %d nodes, want 7: %#v", len(got), got)
+	}
+	block, ok := got[0].(OverrideBlock)
+	if !ok || len(block.Items) != 2 {
+		t.Fatalf("node 0 = %#v, want block with comment and tag", got[0])
+	}
+	if c, ok := block.Items[0].(Comment); !ok || c.Text != "note" {
+		t.Errorf("block item 0 = %#v, want comment", block.Items[0])
+	}
+	if tag, ok := block.Items[1].(Tag); !ok || tag.Kind != TagBold || tag.Arg(0) != "1" {
+		t.Errorf("block item 1 = %#v, want \\b1", block.Items[1])
+	}
+	if got[1] != (TextRun{Text: "Hi"}) || got[2] != (Break{Kind: HardBreak}) || got[3] != (TextRun{Text: "there"}) {
+		t.Errorf("text nodes = %#v", got[1:4])
+	}
+	if got[5] != (Drawing{Commands: "m 0 0"}) {
+		t.Errorf("node 5 = %#v, want drawing", got[5])
+	}
+}
+
+func TestParseASSText_Tags(t *testing.T) {
+	tags := ParseASSText(`{\1c&HFF8000&\alpha&H80&\pos(320,240)\fs+2\kf50\r}x{\t(0,1000,\frz90\3c&H00&)}`).Tags()
+
+	var kinds []TagKind
+	for _, tag := range tags {
+		kinds = append(kinds, tag.Kind)
+	}
+	want := []TagKind{TagColour1, TagAlpha, TagPos, TagFontSize, TagKaraokeFill, TagReset, TagTransform, TagRotateZ, TagColour3}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("kinds = %v, want %v", kinds, want)
+	}
+
+	if c, ok := tags[0].Colour(); !ok || c != 0xFF8000 {
+		t.Errorf("Colour() = %X, %v", c, ok)
+	}
+	if a, ok := tags[1].Alpha(); !ok || a != 0x80 {
+		t.Errorf("Alpha() = %X, %v", a, ok)
+	}
+	if nums, ok := tags[2].Numbers(); !ok || !reflect.DeepEqual(nums, []float64{320, 240}) {
+		t.Errorf("Numbers() = %v, %v", nums, ok)
+	}
+	if tags[3].Arg(0) != "+2" {
+		t.Errorf("\\fs arg = %q", tags[3].Arg(0))
+	}
+	if k, ok := tags[4].Int(); !ok || k != 50 {
+		t.Errorf("\\kf = %d, %v", k, ok)
+	}
+	if !tags[5].IsReset() {
+		t.Error("\\r should be a reset")
+	}
+	if nums, ok := tags[6].Numbers(); !ok || !reflect.DeepEqual(nums, []float64{0, 1000}) || len(tags[6].Nested) != 2 {
+		t.Errorf("\\t args = %v, nested %d", nums, len(tags[6].Nested))
+	}
+}
+
+func TestASSText_Edit(t *testing.T) {
+	text := ParseASSText(`{\pos( 10 , 20 )\b1}Hi{\t(0,500,\fs40)}`)
+
+	block := text[0].(OverrideBlock)
+	pos := block.Items[0].(Tag)
+	pos.Args = []string{"30", "40"}
+	block.Items[0] = pos
+	block.Items = append(block.Items, NewTag("c", "&H0000FF&"))
+	text[0] = block
+
+	tr := text[2].(OverrideBlock)
+	tag := tr.Items[0].(Tag)
+	tag.Nested = []BlockItem{NewTag("fs", "80")}
+	tr.Items[0] = tag
+
+	want := `{\pos(30,40)\b1\c&H0000FF&}Hi{\t(0,500,\fs80)}`
+	if got := text.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestASSText_PlainText(t *testing.T) {
+	got := ParseASSText(`{\an8}One\Ntwo\nthree\hfour{\p1}m 0 0 l 1 1{\p0}!`).PlainText()
+	if want := "One\ntwo three four!"; got != want {
+		t.Errorf("PlainText() = %q, want %q", got, want)
+	}
+}
+
+func TestSpokenText(t *testing.T) {
+	got := SpokenText(`{\an8\fad(200,200)}Wait,{\i1}  stop!{\i0}\N{comment}{\p1}m 0 0 l 10 10{\p0} - now`)
+	if want := "Wait, stop! - now"; got != want {
+		t.Errorf("SpokenText() = %q, want %q", got, want)
+	}
+	if got := SpokenText(`{\p1}m 0 0 l 10 10{\p0}`); got != "" {
+		t.Errorf("SpokenText(drawing) = %q, want empty", got)
+	}
+}
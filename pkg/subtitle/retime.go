@@ -0,0 +1,181 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Retime is a linear timing transform, t' = Scale*t + Offset, applied to
+// event times to match another release of the same video: a different intro
+// length (Shift), a frame rate change (FPS), or a sync to two known points
+// (TwoPointSync). Transforms compose with Then.
+//
+// A zero Scale is treated as 1, so the zero value is the identity.
+type Retime struct {
+	Scale  float64 // speed factor; 25/23.976 stretches, 23.976/25 shrinks
+	Offset float64 // nanoseconds added after scaling
+}
+
+// Shift returns a transform adding d (which may be negative) to every time.
+func Shift(d time.Duration) Retime {
+	return Retime{Scale: 1, Offset: float64(d)}
+}
+
+// ScaleBy returns a transform multiplying every time by ratio.
+func ScaleBy(ratio float64) Retime {
+	return Retime{Scale: ratio}
+}
+
+// FPS returns the transform for playing video made at from frames per second
+// at to, e.g. FPS(24000.0/1001, 25) for a PAL speedup: each frame keeps its
+// index, so times scale by from/to.
+func FPS(from, to float64) Retime {
+	return Retime{Scale: from / to}
+}
+
+// TwoPointSync returns the transform mapping source time src1 to dst1 and
+// src2 to dst2, for example the first and last line of a script matched by
+// hand against the target video.
+func TwoPointSync(src1, dst1, src2, dst2 uint64) (Retime, error) {
+	if src1 == src2 {
+		return Retime{}, fmt.Errorf("two-point sync needs two different source times")
+	}
+	scale := (float64(dst2) - float64(dst1)) / (float64(src2) - float64(src1))
+	if scale <= 0 {
+		return Retime{}, fmt.Errorf("two-point sync would reverse time (scale %g)", scale)
+	}
+	return Retime{Scale: scale, Offset: float64(dst1) - scale*float64(src1)}, nil
+}
+
+// scale returns Scale, with zero meaning 1.
+func (r Retime) scale() float64 {
+	if r.Scale == 0 {
+		return 1
+	}
+	return r.Scale
+}
+
+// Then returns the transform applying r and then next.
+func (r Retime) Then(next Retime) Retime {
+	return Retime{
+		Scale:  next.scale() * r.scale(),
+		Offset: next.scale()*r.Offset + next.Offset,
+	}
+}
+
+// IsIdentity reports whether r leaves times unchanged.
+func (r Retime) IsIdentity() bool {
+	return r.scale() == 1 && r.Offset == 0
+}
+
+// Apply transforms a time in nanoseconds. Times mapped before zero become zero.
+func (r Retime) Apply(t uint64) uint64 {
+	v := math.Round(r.scale()*float64(t) + r.Offset)
+	if v <= 0 {
+		return 0
+	}
+	return uint64(v)
+}
+
+// ApplyEvents transforms the Start and End of every event and, when the
+// transform changes speed, the relative times of its animated override tags
+// (see ScaleTagTimes).
+func (r Retime) ApplyEvents(events []SubtitleEvent) {
+	if r.IsIdentity() {
+		return
+	}
+	for i := range events {
+		ev := &events[i]
+		ev.Start, ev.End = r.Apply(ev.Start), r.Apply(ev.End)
+		if r.scale() != 1 {
+			ev.Text = ScaleTagTimes(ev.Text, r.scale())
+		}
+	}
+}
+
+// ScaleTagTimes multiplies the line-relative times in the override tags of
+// ASS dialogue text by scale: the times of \move, \t, \fad and \fade
+// (milliseconds) and the karaoke durations \k, \K, \kf, \ko and \kt
+// (centiseconds). Karaoke durations are rounded cumulatively so syllables
+// still add up to the scaled line. Text without tags is returned unchanged.
+func ScaleTagTimes(text string, scale float64) string {
+	parsed := ParseASSText(text)
+	changed := false
+	var karaokeSrc, karaokeDst float64 // running karaoke totals, centiseconds
+
+	for n, node := range parsed {
+		block, ok := node.(OverrideBlock)
+		if !ok {
+			continue
+		}
+		for i, item := range block.Items {
+			tag, ok := item.(Tag)
+			if !ok {
+				continue
+			}
+			var scaled bool
+			switch tag.Kind {
+			case TagMove:
+				// \move(x1,y1,x2,y2[,t1,t2])
+				scaled = scaleArgs(&tag, scale, 4, 5)
+			case TagTransform:
+				// \t([t1,t2,][accel,]tags): times only when there are two or three args
+				if len(tag.Args) >= 2 {
+					scaled = scaleArgs(&tag, scale, 0, 1)
+				}
+			case TagFade:
+				scaled = scaleArgs(&tag, scale, 0, 1)
+			case TagFadeComplex:
+				// \fade(a1,a2,a3,t1,t2,t3,t4)
+				scaled = scaleArgs(&tag, scale, 3, 4, 5, 6)
+			case TagKaraoke, TagKaraokeFill, TagKaraokeOutline:
+				if k, ok := tag.Float(); ok {
+					karaokeSrc += k
+					total := math.Round(karaokeSrc * scale)
+					tag.Args = []string{strconv.FormatFloat(total-karaokeDst, 'f', -1, 64)}
+					karaokeDst = total
+					scaled = true
+				}
+			case TagKaraokeTime:
+				if k, ok := tag.Float(); ok {
+					tag.Args = []string{strconv.FormatFloat(math.Round(k*scale), 'f', -1, 64)}
+					scaled = true
+				}
+			}
+			if scaled {
+				block.Items[i] = tag
+				changed = true
+			}
+		}
+		parsed[n] = block
+	}
+
+	if !changed {
+		return text
+	}
+	return parsed.String()
+}
+
+// scaleArgs scales the numeric arguments at the given indices of tag,
+// rounding to whole milliseconds. Missing or non-numeric arguments are left
+// alone; it reports whether any was scaled.
+func scaleArgs(tag *Tag, scale float64, indices ...int) bool {
+	scaled := false
+	for _, i := range indices {
+		if i >= len(tag.Args) {
+			continue
+		}
+		v, err := strconv.ParseFloat(tag.Arg(i), 64)
+		if err != nil {
+			continue
+		}
+		if !scaled {
+			tag.Args = append([]string(nil), tag.Args...)
+		}
+		tag.Args[i] = strconv.FormatFloat(math.Round(v*scale), 'f', -1, 64)
+		scaled = true
+	}
+	return scaled
+}
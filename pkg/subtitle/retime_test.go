@@ -0,0 +1,70 @@
+package subtitle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetime_Apply(t *testing.T) {
+	const s = uint64(time.Second)
+	sync, err := TwoPointSync(10*s, 12*s, 110*s, 132*s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		r    Retime
+		in   uint64
+		want uint64
+	}{
+		{"zero value", Retime{}, 5 * s, 5 * s},
+		{"shift", Shift(2500 * time.Millisecond), 5 * s, 7_500_000_000},
+		{"negative shift clamps", Shift(-10 * time.Second), 5 * s, 0},
+		{"scale", ScaleBy(2), 5 * s, 10 * s},
+		{"PAL speedup", FPS(24000.0/1001, 25), 25 * s, 23_976_023_976},
+		{"two-point sync", sync, 60 * s, 72 * s},
+		{"shift then scale", Shift(time.Second).Then(ScaleBy(2)), 5 * s, 12 * s},
+		{"scale then shift", ScaleBy(2).Then(Shift(time.Second)), 5 * s, 11 * s},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Apply(tt.in); got != tt.want {
+			t.Errorf("%s: Apply(%d) = %d, want %d", tt.name, tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := TwoPointSync(s, s, s, 2*s); err == nil {
+		t.Error("expected error for equal source times")
+	}
+	if _, err := TwoPointSync(s, 2*s, 2*s, s); err == nil {
+		t.Error("expected error for reversed sync")
+	}
+}
+
+func TestScaleTagTimes(t *testing.T) {
+	tests := []struct {
+		in, want string
+		scale    float64
+	}{
+		{`plain`, `plain`, 2},
+		{`{\move(0,0,100,100,100,500)\pos(1,2)}x`, `{\move(0,0,100,100,200,1000)\pos(1,2)}x`, 2},
+		{`{\move(0,0,100,100)}x`, `{\move(0,0,100,100)}x`, 2},
+		{`{\t(100,300,2,\frz90)\t(\fs20)}x`, `{\t(50,150,2,\frz90)\t(\fs20)}x`, 0.5},
+		{`{\fad(200,300)\fade(255,0,255,0,100,200,300)}x`, `{\fad(400,600)\fade(255,0,255,0,200,400,600)}x`, 2},
+		// 3 x 10cs at 1.05: cumulative rounding keeps the total at 32cs.
+		{`{\k10}a{\kf10}b{\ko10}c`, `{\k11}a{\kf10}b{\ko11}c`, 1.05},
+	}
+	for _, tt := range tests {
+		if got := ScaleTagTimes(tt.in, tt.scale); got != tt.want {
+			t.Errorf("ScaleTagTimes(%q, %g) = %q, want %q", tt.in, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestRetime_ApplyEvents(t *testing.T) {
+	events := []SubtitleEvent{{Start: 1_000_000_000, End: 2_000_000_000, Text: `{\k50}ka{\k50}ra`}}
+	ScaleBy(2).ApplyEvents(events)
+	if ev := events[0]; ev.Start != 2_000_000_000 || ev.End != 4_000_000_000 || ev.Text != `{\k100}ka{\k100}ra` {
+		t.Errorf("event = %+v", ev)
+	}
+}
@@ -0,0 +1,117 @@
+package subtitle
+
+import "unicode"
+
+// Script identifies the writing system that dominates a piece of subtitle text.
+type Script string
+
+// Writing systems recognised by DetectScript.
+const (
+	ScriptUnknown  Script = ""
+	ScriptLatin    Script = "Latn"
+	ScriptHan      Script = "Hani" // Chinese characters without kana
+	ScriptJapanese Script = "Jpan" // kana, optionally mixed with kanji
+	ScriptHangul   Script = "Hang"
+	ScriptArabic   Script = "Arab"
+	ScriptHebrew   Script = "Hebr"
+	ScriptCyrillic Script = "Cyrl"
+	ScriptGreek    Script = "Grek"
+	ScriptThai     Script = "Thai"
+)
+
+// scriptTables lists the Unicode ranges counted for each script, in the order
+// ties are resolved.
+var scriptTables = []struct {
+	script Script
+	table  *unicode.RangeTable
+}{
+	{ScriptLatin, unicode.Latin},
+	{ScriptHan, unicode.Han},
+	{ScriptJapanese, unicode.Hiragana},
+	{ScriptJapanese, unicode.Katakana},
+	{ScriptHangul, unicode.Hangul},
+	{ScriptArabic, unicode.Arabic},
+	{ScriptHebrew, unicode.Hebrew},
+	{ScriptCyrillic, unicode.Cyrillic},
+	{ScriptGreek, unicode.Greek},
+	{ScriptThai, unicode.Thai},
+}
+
+// CountScripts returns the number of letters per script in text. Markup is
+// ignored: anything inside {...} override blocks or <...> SRT tags, and the
+// \N, \n and \h escapes, does not contribute to the counts.
+func CountScripts(text string) map[Script]int {
+	counts := make(map[Script]int)
+	inOverride, inTag, escaped := false, false, false
+
+	for _, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+			continue
+		case inOverride:
+			inOverride = r != '}'
+			continue
+		case inTag:
+			inTag = r != '>'
+			continue
+		case r == '{':
+			inOverride = true
+			continue
+		case r == '<':
+			inTag = true
+			continue
+		case r == '\\':
+			escaped = true
+			continue
+		}
+
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for _, st := range scriptTables {
+			if unicode.Is(st.table, r) {
+				counts[st.script]++
+				break
+			}
+		}
+	}
+
+	return counts
+}
+
+// DetectScript returns the dominant script of text, or ScriptUnknown if it
+// contains no letters. Han characters count towards Japanese when the text
+// also contains kana, since kanji are part of ordinary Japanese writing.
+func DetectScript(text string) Script {
+	return dominantScript(CountScripts(text))
+}
+
+// ideographicWeight is how many alphabetic letters one Han, kana or Hangul
+// character is worth when comparing scripts: each carries roughly a syllable
+// or word, so "我是 Tom" counts as Chinese.
+const ideographicWeight = 3
+
+// dominantScript picks the script with the most (weighted) letters from counts.
+func dominantScript(counts map[Script]int) Script {
+	kana := counts[ScriptJapanese] > 0
+
+	best, bestCount := ScriptUnknown, 0
+	for _, st := range scriptTables {
+		n := counts[st.script]
+		switch {
+		case kana && st.script == ScriptHan:
+			n = 0
+		case kana && st.script == ScriptJapanese:
+			n += counts[ScriptHan]
+		}
+		switch st.script {
+		case ScriptHan, ScriptJapanese, ScriptHangul:
+			n *= ideographicWeight
+		}
+		if n > bestCount {
+			best, bestCount = st.script, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,45 @@
+package subtitle
+
+import "testing"
+
+func TestDetectScript(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Script
+	}{
+		{"english", "Hello there", ScriptLatin},
+		{"chinese", "你好，世界", ScriptHan},
+		{"japanese kana and kanji", "今日は良い天気ですね", ScriptJapanese},
+		{"korean", "안녕하세요", ScriptHangul},
+		{"arabic", "مرحبا بالعالم", ScriptArabic},
+		{"russian", "Привет, мир", ScriptCyrillic},
+		{"greek", "Γειά σου", ScriptGreek},
+		{"hebrew", "שלום", ScriptHebrew},
+		{"thai", "สวัสดี", ScriptThai},
+		{"digits only", "12:30 - 42!", ScriptUnknown},
+		{"empty", "", ScriptUnknown},
+		{"srt tags ignored", `<font color="red">你好</font>`, ScriptHan},
+		{"override blocks ignored", `{\fnArial\an8}你好`, ScriptHan},
+		{"line break escape ignored", `你好\N世界`, ScriptHan},
+		{"mostly chinese with a name", "我是 Tom", ScriptHan},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectScript(tt.text); got != tt.want {
+				t.Errorf("DetectScript(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountScripts(t *testing.T) {
+	counts := CountScripts("Hi 你好")
+	if counts[ScriptLatin] != 2 {
+		t.Errorf("Latin count = %d, want 2", counts[ScriptLatin])
+	}
+	if counts[ScriptHan] != 2 {
+		t.Errorf("Han count = %d, want 2", counts[ScriptHan])
+	}
+}
@@ -1,104 +1,386 @@
 package subtitle
 
 import (
-	"fmt"
-	"regexp"
+	"strconv"
 	"strings"
 )
 
-// Package-level compiled regexes for SRT tag conversion (compiled once, not per-call).
-var (
-	// Opening tags (case insensitive)
-	reBoldOpen      = regexp.MustCompile(`(?i)<b>`)
-	reBoldClose     = regexp.MustCompile(`(?i)</b>`)
-	reItalicOpen    = regexp.MustCompile(`(?i)<i>`)
-	reItalicClose   = regexp.MustCompile(`(?i)</i>`)
-	reUnderlineOpen = regexp.MustCompile(`(?i)<u>`)
-	reUnderlineClose = regexp.MustCompile(`(?i)</u>`)
-
-	// Font color with hex value: <font color="#RRGGBB"> or <font color='#RRGGBB'>
-	reFontColorHex = regexp.MustCompile(`(?i)<font\s+color=["']#([0-9a-fA-F]{6})["']>`)
-
-	// Font color with named color: <font color="red"> or <font color='red'>
-	reFontColorNamed = regexp.MustCompile(`(?i)<font\s+color=["']([a-zA-Z]+)["']>`)
-
-	// Closing font tag
-	reFontClose = regexp.MustCompile(`(?i)</font>`)
-
-	// Strip remaining unrecognized HTML tags
-	reStripTags = regexp.MustCompile(`<[^>]*>`)
-)
-
-// namedColors maps common SRT named colors to their RGB hex values.
-var namedColors = map[string]string{
-	"red":     "FF0000",
-	"blue":    "0000FF",
-	"green":   "008000",
-	"yellow":  "FFFF00",
-	"white":   "FFFFFF",
-	"cyan":    "00FFFF",
-	"magenta": "FF00FF",
-}
-
 // rgbToBGR reverses a 6-character RGB hex string to BGR order for ASS format.
 func rgbToBGR(rgb string) string {
 	return rgb[4:6] + rgb[2:4] + rgb[0:2]
 }
 
-// ConvertSRTTagsToASS converts SRT HTML-style tags to ASS override tags.
+// ConvertSRTTagsToASS converts SRT HTML-style markup to ASS override tags.
 //
 // Supported conversions:
-//   - <b>...</b>  -> {\b1}...{\b0}
-//   - <i>...</i>  -> {\i1}...{\i0}
-//   - <u>...</u>  -> {\u1}...{\u0}
-//   - <font color="#RRGGBB">...</font> -> {\c&HBBGGRR&}...{\c}
-//   - <font color="name">...</font>    -> {\c&HBBGGRR&}...{\c} (named colors)
+//   - <b>, <i>, <u>, <s> ... </b> etc. -> {\b1}...{\b0}, {\i1}, {\u1}, {\s1}
+//   - <font color="..."> -> {\c&HBBGGRR&}; colours may be #RRGGBB, #RGB,
+//     RRGGBB or any CSS/HTML colour name
+//   - <font face="Name"> -> {\fnName}, <font size="N"> -> {\fsN}
+//   - </font> restores the colour, face and size of the enclosing <font>,
+//     or resets them to the style ({\c}, {\fn}, {\fs}) at the outermost level
+//   - inline ASS blocks such as the {\an8} and {\pos(x,y)} hints common in SRT
+//     files are kept when valid: an \an outside 1-9, a \pos without two
+//     numbers, or any alignment/position tag after the first (renderers only
+//     honour the first) is dropped
+//   - a leading SubRip coordinate line ("X1:100 X2:300 Y1:50 Y2:80") becomes
+//     a \pos (see SRTCoordinates)
 //
-// Unknown HTML tags are stripped. Tags are case insensitive.
-// Unclosed tags are left as-is (ASS override tags reset per dialogue line).
+// Unknown HTML tags are stripped; a "<" that does not start a tag is kept.
+// Tags and attribute names are case insensitive. Unclosed tags are left as-is
+// (ASS override tags reset per dialogue line).
 func ConvertSRTTagsToASS(text string) string {
-	// Bold
-	text = reBoldOpen.ReplaceAllString(text, "{\\b1}")
-	text = reBoldClose.ReplaceAllString(text, "{\\b0}")
-
-	// Italic
-	text = reItalicOpen.ReplaceAllString(text, "{\\i1}")
-	text = reItalicClose.ReplaceAllString(text, "{\\i0}")
-
-	// Underline
-	text = reUnderlineOpen.ReplaceAllString(text, "{\\u1}")
-	text = reUnderlineClose.ReplaceAllString(text, "{\\u0}")
-
-	// Font color with hex value: RGB -> BGR
-	text = reFontColorHex.ReplaceAllStringFunc(text, func(match string) string {
-		submatch := reFontColorHex.FindStringSubmatch(match)
-		if len(submatch) < 2 {
+	var out strings.Builder
+	seen := make(map[positioning]bool)
+
+	if coords, rest, ok := cutSRTCoordinates(text); ok {
+		out.WriteString(coords.Tags())
+		seen[alignmentGroup], seen[positionGroup] = true, true
+		text = rest
+	}
+
+	var fonts []fontFrame
+	for text != "" {
+		i := strings.IndexAny(text, "<{")
+		if i < 0 {
+			out.WriteString(text)
+			break
+		}
+		out.WriteString(text[:i])
+		text = text[i:]
+
+		if text[0] == '{' {
+			end := strings.IndexByte(text, '}')
+			if end < 0 {
+				out.WriteString(text)
+				break
+			}
+			out.WriteString(checkOverrideBlock(text[:end+1], seen))
+			text = text[end+1:]
+			continue
+		}
+
+		end := strings.IndexByte(text, '>')
+		if end < 0 || !isHTMLTag(text[1:end]) {
+			out.WriteByte('<')
+			text = text[1:]
+			continue
+		}
+		out.WriteString(convertHTMLTag(text[1:end], &fonts))
+		text = text[end+1:]
+	}
+
+	return out.String()
+}
+
+// MapSRTText returns SRT subtitle text with fn applied to the text between
+// its markup: HTML tags and {...} override blocks are kept as they are.
+func MapSRTText(text string, fn func(string) string) string {
+	var out, run strings.Builder
+	flush := func() {
+		if run.Len() > 0 {
+			out.WriteString(fn(run.String()))
+			run.Reset()
+		}
+	}
+	for text != "" {
+		i := strings.IndexAny(text, "<{")
+		if i < 0 {
+			run.WriteString(text)
+			break
+		}
+		run.WriteString(text[:i])
+		text = text[i:]
+
+		closer := byte('}')
+		if text[0] == '<' {
+			closer = '>'
+		}
+		end := strings.IndexByte(text, closer)
+		if end < 0 || (closer == '>' && !isHTMLTag(text[1:end])) {
+			run.WriteByte(text[0])
+			text = text[1:]
+			continue
+		}
+		flush()
+		out.WriteString(text[:end+1])
+		text = text[end+1:]
+	}
+	flush()
+	return out.String()
+}
+
+// isHTMLTag reports whether the text between "<" and ">" looks like an HTML
+// tag: a name, optionally preceded by "/".
+func isHTMLTag(s string) bool {
+	s = strings.TrimPrefix(s, "/")
+	return s != "" && (s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z')
+}
+
+// styleTags maps the simple SRT style tags to their ASS tag names.
+var styleTags = map[string]string{"b": "b", "i": "i", "u": "u", "s": "s"}
+
+// fontFrame is one open <font> tag: the ASS values it set ("" if unset).
+type fontFrame struct {
+	colour, face, size string
+}
+
+// convertHTMLTag converts the inside of one <...> tag. fonts is the stack of
+// open <font> tags.
+func convertHTMLTag(tag string, fonts *[]fontFrame) string {
+	closing := strings.HasPrefix(tag, "/")
+	tag = strings.TrimPrefix(tag, "/")
+	name, attrs, _ := strings.Cut(strings.TrimSpace(tag), " ")
+	name = strings.ToLower(strings.TrimSuffix(name, "/"))
+
+	if ass, ok := styleTags[name]; ok {
+		if closing {
+			return `{\` + ass + `0}`
+		}
+		return `{\` + ass + `1}`
+	}
+	if name != "font" {
+		return ""
+	}
+
+	if closing {
+		if len(*fonts) == 0 {
 			return ""
 		}
-		bgr := rgbToBGR(strings.ToUpper(submatch[1]))
-		return fmt.Sprintf("{\\c&H%s&}", bgr)
-	})
+		top := (*fonts)[len(*fonts)-1]
+		*fonts = (*fonts)[:len(*fonts)-1]
 
-	// Font color with named color
-	text = reFontColorNamed.ReplaceAllStringFunc(text, func(match string) string {
-		submatch := reFontColorNamed.FindStringSubmatch(match)
-		if len(submatch) < 2 {
+		// Restore whatever the enclosing fonts set, or reset to the style.
+		outer := func(get func(fontFrame) string) string {
+			for i := len(*fonts) - 1; i >= 0; i-- {
+				if v := get((*fonts)[i]); v != "" {
+					return v
+				}
+			}
 			return ""
 		}
-		name := strings.ToLower(submatch[1])
-		rgb, ok := namedColors[name]
+		var tags []string
+		if top.colour != "" {
+			tags = append(tags, `\c`+outer(func(f fontFrame) string { return f.colour }))
+		}
+		if top.face != "" {
+			tags = append(tags, `\fn`+outer(func(f fontFrame) string { return f.face }))
+		}
+		if top.size != "" {
+			tags = append(tags, `\fs`+outer(func(f fontFrame) string { return f.size }))
+		}
+		return overrideBlock(tags)
+	}
+
+	var frame fontFrame
+	for key, value := range parseHTMLAttrs(attrs) {
+		switch key {
+		case "color":
+			if bgr, ok := parseSRTColour(value); ok {
+				frame.colour = "&H" + bgr + "&"
+			}
+		case "face":
+			frame.face = strings.TrimSpace(value)
+		case "size":
+			if size, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && size > 0 {
+				frame.size = strconv.FormatFloat(size, 'f', -1, 64)
+			}
+		}
+	}
+	*fonts = append(*fonts, frame)
+
+	var tags []string
+	if frame.colour != "" {
+		tags = append(tags, `\c`+frame.colour)
+	}
+	if frame.face != "" {
+		tags = append(tags, `\fn`+frame.face)
+	}
+	if frame.size != "" {
+		tags = append(tags, `\fs`+frame.size)
+	}
+	return overrideBlock(tags)
+}
+
+// overrideBlock wraps tags in {...}, or returns "" when there are none.
+func overrideBlock(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(tags, "") + "}"
+}
+
+// parseHTMLAttrs parses `key="value" key='value' key=value` attributes.
+// Keys are lower-cased.
+func parseHTMLAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t/")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return attrs
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		attrs[key] = value
+	}
+}
+
+// parseSRTColour converts an SRT colour value to ASS BGR hex (upper case).
+func parseSRTColour(v string) (string, bool) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if rgb, ok := namedColors[v]; ok {
+		return rgbToBGR(rgb), true
+	}
+
+	hex := strings.TrimPrefix(v, "#")
+	if len(hex) == 3 && hex != v {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		return "", false
+	}
+	return rgbToBGR(strings.ToUpper(hex)), true
+}
+
+// positioning groups the override tags of which renderers honour only the
+// first occurrence in a line.
+type positioning int
+
+const (
+	alignmentGroup positioning = iota // \an, \a
+	positionGroup                     // \pos, \move
+)
+
+// legacyAlignments are the valid SSA \a values.
+var legacyAlignments = map[int]bool{1: true, 2: true, 3: true, 5: true, 6: true, 7: true, 9: true, 10: true, 11: true}
+
+// checkOverrideBlock validates the alignment and position tags of an inline
+// {...} block, dropping invalid or repeated ones. Other tags and comments are
+// kept as written; a block left empty by the check is removed.
+func checkOverrideBlock(block string, seen map[positioning]bool) string {
+	parsed := ParseASSText(block)
+	b, ok := parsed[0].(OverrideBlock)
+	if !ok || len(parsed) != 1 {
+		return block
+	}
+
+	var kept []BlockItem
+	for _, item := range b.Items {
+		tag, ok := item.(Tag)
 		if !ok {
-			return "" // Unknown color name: strip the tag
+			kept = append(kept, item)
+			continue
 		}
-		bgr := rgbToBGR(rgb)
-		return fmt.Sprintf("{\\c&H%s&}", bgr)
-	})
 
-	// Close font tag -> reset color
-	text = reFontClose.ReplaceAllString(text, "{\\c}")
+		group, valid := positioning(-1), true
+		switch tag.Kind {
+		case TagAlignment:
+			n, ok := tag.Int()
+			group, valid = alignmentGroup, ok && n >= 1 && n <= 9
+		case TagLegacyAlignment:
+			n, ok := tag.Int()
+			group, valid = alignmentGroup, ok && legacyAlignments[n]
+		case TagPos:
+			nums, ok := tag.Numbers()
+			group, valid = positionGroup, ok && tag.Paren && len(nums) == 2
+		case TagMove:
+			nums, ok := tag.Numbers()
+			group, valid = positionGroup, ok && tag.Paren && (len(nums) == 4 || len(nums) == 6)
+		}
+		if group >= 0 {
+			if !valid || seen[group] {
+				continue
+			}
+			seen[group] = true
+		}
+		kept = append(kept, tag)
+	}
 
-	// Strip any remaining unrecognized HTML tags
-	text = reStripTags.ReplaceAllString(text, "")
+	if len(kept) == 0 && len(b.Items) > 0 {
+		return ""
+	}
+	return OverrideBlock{Items: kept}.String()
+}
+
+// SRTCoordinates is SubRip's extended positioning: a text box in video
+// pixels given after the timing line,
+//
+//	00:00:01,000 --> 00:00:02,000 X1:100 X2:300 Y1:50 Y2:80
+//
+// X2 and Y2 are optional (zero when absent).
+type SRTCoordinates struct {
+	X1, X2, Y1, Y2 int
+}
+
+// ParseSRTCoordinates parses "X1:100 X2:300 Y1:50 Y2:80" (in any order, case
+// insensitive). X1 and Y1 are required; any other token fails the parse.
+func ParseSRTCoordinates(s string) (SRTCoordinates, bool) {
+	var c SRTCoordinates
+	hasX1, hasY1 := false, false
+
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return c, false
+	}
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, ":")
+		n, err := strconv.Atoi(value)
+		if !ok || err != nil || n < 0 {
+			return SRTCoordinates{}, false
+		}
+		switch strings.ToUpper(key) {
+		case "X1":
+			c.X1, hasX1 = n, true
+		case "X2":
+			c.X2 = n
+		case "Y1":
+			c.Y1, hasY1 = n, true
+		case "Y2":
+			c.Y2 = n
+		default:
+			return SRTCoordinates{}, false
+		}
+	}
+	return c, hasX1 && hasY1
+}
+
+// Tags returns the override block placing a line in the box: centred at the
+// top edge ({\an8}) when the box has a width, otherwise with its top-left
+// corner at X1,Y1 ({\an7}). Coordinates are video pixels, which match the
+// script's when PlayRes follows the video resolution.
+func (c SRTCoordinates) Tags() string {
+	if c.X2 > c.X1 {
+		return `{\an8\pos(` + strconv.Itoa((c.X1+c.X2)/2) + "," + strconv.Itoa(c.Y1) + ")}"
+	}
+	return `{\an7\pos(` + strconv.Itoa(c.X1) + "," + strconv.Itoa(c.Y1) + ")}"
+}
 
-	return text
+// cutSRTCoordinates splits a leading coordinate line off text.
+func cutSRTCoordinates(text string) (SRTCoordinates, string, bool) {
+	line, rest, _ := strings.Cut(text, "\n")
+	c, ok := ParseSRTCoordinates(strings.TrimSuffix(line, "\r"))
+	if !ok {
+		return SRTCoordinates{}, text, false
+	}
+	return c, rest, true
 }
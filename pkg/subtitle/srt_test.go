@@ -1,6 +1,9 @@
 package subtitle
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestConvertSRTTagsToASS(t *testing.T) {
 	tests := []struct {
@@ -83,3 +86,98 @@ func TestConvertSRTTagsToASS(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertSRTTagsToASS_Font(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"strikeout", "<s>gone</s>", "{\\s1}gone{\\s0}"},
+		{"face and size", `<font face="Arial" size="40">x</font>`, "{\\fnArial\\fs40}x{\\fn\\fs}"},
+		{"unquoted attributes", "<font color=orange size=30>x</font>", "{\\c&H00A5FF&\\fs30}x{\\c\\fs}"},
+		{"css colour name", `<font color="DarkSlateGray">x</font>`, "{\\c&H4F4F2F&}x{\\c}"},
+		{"short hex", `<font color="#f80">x</font>`, "{\\c&H0088FF&}x{\\c}"},
+		{"hex without hash", `<font color="00ff00">x</font>`, "{\\c&H00FF00&}x{\\c}"},
+		{"unknown colour ignored", `<font color="notacolour">x</font>`, "x"},
+		{
+			"nested colour restores outer",
+			`<font color="red">a<font color="blue">b</font>c</font>`,
+			"{\\c&H0000FF&}a{\\c&HFF0000&}b{\\c&H0000FF&}c{\\c}",
+		},
+		{
+			"nested face keeps outer colour",
+			`<font color="red">a<font face="Serif">b</font>c</font>`,
+			"{\\c&H0000FF&}a{\\fnSerif}b{\\fn}c{\\c}",
+		},
+		{"stray closing font", "a</font>b", "ab"},
+		{"less-than kept", "1 < 2 and 3 <4", "1 < 2 and 3 <4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertSRTTagsToASS(tt.text); got != tt.want {
+				t.Errorf("ConvertSRTTagsToASS(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertSRTTagsToASS_Positioning(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"valid an8", "{\\an8}Top", "{\\an8}Top"},
+		{"valid pos", "{\\pos(320,50)}Sign", "{\\pos(320,50)}Sign"},
+		{"invalid an dropped", "{\\an10}x", "x"},
+		{"invalid pos dropped", "{\\pos(320)}x", "x"},
+		{"non-numeric pos dropped", "{\\pos(a,b)\\i1}x", "{\\i1}x"},
+		{"second an dropped", "{\\an8}a{\\an2}b", "{\\an8}ab"},
+		{"pos after move dropped", "{\\move(0,0,10,10)\\pos(5,5)}x", "{\\move(0,0,10,10)}x"},
+		{"comment block kept", "{note}x", "{note}x"},
+		{"coordinates", "X1:100 X2:300 Y1:50 Y2:80\nHello", "{\\an8\\pos(200,50)}Hello"},
+		{"coordinates without box", "X1:100 Y1:50\nHello", "{\\an7\\pos(100,50)}Hello"},
+		{"coordinates override inline pos", "X1:100 Y1:50\n{\\an2\\pos(1,1)}Hello", "{\\an7\\pos(100,50)}Hello"},
+		{"not coordinates", "X1 marks the spot\nHello", "X1 marks the spot\nHello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertSRTTagsToASS(tt.text); got != tt.want {
+				t.Errorf("ConvertSRTTagsToASS(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSRTCoordinates(t *testing.T) {
+	c, ok := ParseSRTCoordinates("y1:50 X1:100 X2:300 Y2:80")
+	if !ok || c != (SRTCoordinates{X1: 100, X2: 300, Y1: 50, Y2: 80}) {
+		t.Errorf("ParseSRTCoordinates = %+v, %v", c, ok)
+	}
+	for _, bad := range []string{"", "X1:100", "X1:100 Y1:x", "X1:100 Y1:50 Z1:3", "X1:-1 Y1:0"} {
+		if _, ok := ParseSRTCoordinates(bad); ok {
+			t.Errorf("ParseSRTCoordinates(%q): expected failure", bad)
+		}
+	}
+}
+
+func TestMapSRTText(t *testing.T) {
+	upper := strings.ToUpper
+	tests := []struct {
+		in, want string
+	}{
+		{"hello", "HELLO"},
+		{`<i>hi</i> there`, `<i>HI</i> THERE`},
+		{`<font face="arial">x</font>`, `<font face="arial">X</font>`},
+		{`{\an8}top`, `{\an8}TOP`},
+		{"a < b > c", "A < B > C"},
+		{"open {brace", "OPEN {BRACE"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := MapSRTText(tt.in, upper); got != tt.want {
+			t.Errorf("MapSRTText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
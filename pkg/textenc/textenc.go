@@ -0,0 +1,94 @@
+// Package textenc detects and decodes the legacy text encodings found in
+// subtitle tracks muxed without converting to UTF-8, such as GBK, Big5,
+// Shift_JIS or Windows-1252. Encodings are named by their WHATWG labels (the
+// names web browsers accept, such as "gbk", "big5", "shift_jis" or "cp1252"),
+// guessed with the ICU charset detector ported to Go by chardet, and decoded
+// with golang.org/x/text/encoding.
+package textenc
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Lookup returns the encoding named by a WHATWG label, such as "gbk",
+// "big5", "shift_jis", "cp1252" or "utf-8". Case is ignored.
+func Lookup(label string) (encoding.Encoding, error) {
+	enc, err := htmlindex.Get(strings.TrimSpace(label))
+	if err != nil || enc == encoding.Replacement {
+		return nil, fmt.Errorf("unknown text encoding %q (e.g. utf-8, gbk, big5, shift_jis, euc-kr, cp1252)", label)
+	}
+	return enc, nil
+}
+
+// Name returns the canonical WHATWG name of enc, such as "gbk", or "" if it
+// has none.
+func Name(enc encoding.Encoding) string {
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// detectorLabels maps the charset names of the detector that are not WHATWG
+// labels to one.
+var detectorLabels = map[string]string{
+	"GB-18030": "gb18030",
+}
+
+// Detect guesses the encoding of text that is not valid UTF-8 and returns it
+// with the detector's confidence, from 1 to 100. It returns nil when no
+// encoding that can be decoded fits, or when the text looks like UTF-8 with
+// a few broken bytes: decoding it as anything else would make it worse.
+func Detect(text []byte) (encoding.Encoding, int) {
+	if mostlyUTF8(text) {
+		return nil, 0
+	}
+	results, err := chardet.NewTextDetector().DetectAll(text)
+	if err != nil {
+		return nil, 0
+	}
+	for _, r := range results {
+		if r.Charset == "UTF-8" {
+			return nil, 0
+		}
+		label := r.Charset
+		if l, ok := detectorLabels[label]; ok {
+			label = l
+		}
+		if enc, err := Lookup(label); err == nil {
+			return enc, r.Confidence
+		}
+	}
+	return nil, 0
+}
+
+// mostlyUTF8 reports whether text holds at least ten valid UTF-8 multibyte
+// characters per invalid byte. Text in a legacy encoding has few or none:
+// its bytes seldom form valid sequences.
+func mostlyUTF8(text []byte) bool {
+	valid, invalid := 0, 0
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			invalid++
+		case size > 1:
+			valid++
+		}
+		text = text[size:]
+	}
+	return valid > 0 && valid >= 10*invalid
+}
+
+// Decode decodes text from enc to UTF-8. Bytes that are not valid in enc
+// become U+FFFD.
+func Decode(enc encoding.Encoding, text []byte) ([]byte, error) {
+	return enc.NewDecoder().Bytes(text)
+}
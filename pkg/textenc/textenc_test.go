@@ -0,0 +1,100 @@
+package textenc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"gbk", "gbk"},
+		{"GB2312", "gbk"},
+		{"big5", "big5"},
+		{"Shift_JIS", "shift_jis"},
+		{"sjis", "shift_jis"},
+		{"cp1252", "windows-1252"},
+		{"latin1", "windows-1252"},
+		{" utf-8 ", "utf-8"},
+	}
+	for _, tt := range tests {
+		enc, err := Lookup(tt.label)
+		if err != nil {
+			t.Errorf("Lookup(%q) error: %v", tt.label, err)
+			continue
+		}
+		if got := Name(enc); got != tt.want {
+			t.Errorf("Name(Lookup(%q)) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+
+	for _, label := range []string{"", "klingon", "iso-2022-kr"} {
+		if _, err := Lookup(label); err == nil {
+			t.Errorf("Lookup(%q) = nil error, want unknown encoding", label)
+		}
+	}
+}
+
+// encode encodes text to enc for the tests.
+func encode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("encode %q: %v", text, err)
+	}
+	return b
+}
+
+func TestDetect(t *testing.T) {
+	chinese := strings.Repeat("我们必须在天黑之前离开这里。没有人知道那天晚上发生了什么。\n", 10)
+	traditional := strings.Repeat("我們必須在天黑之前離開這裡。沒有人知道那天晚上發生了什麼。\n", 10)
+	japanese_ := strings.Repeat("今までどこにいたの？ここに戻ってくるなと言ったはずだ。\n", 10)
+	french := strings.Repeat("Où étais-tu passé ? Je t'ai déjà dit de ne pas revenir ici, c'est très dangereux.\n", 10)
+
+	tests := []struct {
+		name string
+		text []byte
+		want []string // acceptable detections
+	}{
+		{"GBK", encode(t, simplifiedchinese.GBK, chinese), []string{"gbk", "gb18030"}},
+		{"Big5", encode(t, traditionalchinese.Big5, traditional), []string{"big5"}},
+		{"Shift_JIS", encode(t, japanese.ShiftJIS, japanese_), []string{"shift_jis"}},
+		{"Windows-1252", encode(t, charmap.Windows1252, french), []string{"windows-1252"}},
+	}
+	for _, tt := range tests {
+		enc, conf := Detect(tt.text)
+		if enc == nil {
+			t.Errorf("%s: Detect() = nil", tt.name)
+			continue
+		}
+		name := Name(enc)
+		ok := false
+		for _, w := range tt.want {
+			ok = ok || name == w
+		}
+		if !ok || conf <= 0 {
+			t.Errorf("%s: Detect() = %s, %d, want one of %v", tt.name, name, conf, tt.want)
+		}
+	}
+
+	// Mostly UTF-8 text with a broken byte is left alone.
+	broken := append([]byte(chinese), 0xff)
+	if enc, _ := Detect(broken); enc != nil {
+		t.Errorf("Detect(broken UTF-8) = %s, want nil", Name(enc))
+	}
+}
+
+func TestDecode(t *testing.T) {
+	got, err := Decode(simplifiedchinese.GBK, encode(t, simplifiedchinese.GBK, "你好，世界"))
+	if err != nil || string(got) != "你好，世界" {
+		t.Errorf("Decode() = %q, %v", got, err)
+	}
+}
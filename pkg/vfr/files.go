@@ -0,0 +1,46 @@
+package vfr
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteTimecodesV2 writes frame timestamps (nanoseconds, presentation order)
+// as a Matroska timecodes v2 file: a header line and then one time in
+// milliseconds per frame, as written by mkvextract and read by Aegisub.
+func WriteTimecodesV2(w io.Writer, timestamps []uint64) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("# timecode format v2\n")
+	for _, t := range timestamps {
+		bw.WriteString(formatMillis(t))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// WriteKeyframes writes keyframe frame numbers as an XviD-style first pass
+// keyframes file in the "keyframe format v1" Aegisub reads: a header, an
+// "fps 0" line (the rate comes from the timecodes) and one frame number per
+// line.
+func WriteKeyframes(w io.Writer, keyframes []int) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("# keyframe format v1\nfps 0\n")
+	for _, k := range keyframes {
+		fmt.Fprintln(bw, k)
+	}
+	return bw.Flush()
+}
+
+// formatMillis formats nanoseconds as milliseconds with only the fractional
+// digits needed, e.g. 41708333 as "41.708333".
+func formatMillis(ns uint64) string {
+	ms := strconv.FormatUint(ns/1_000_000, 10)
+	frac := ns % 1_000_000
+	if frac == 0 {
+		return ms
+	}
+	return ms + "." + strings.TrimRight(fmt.Sprintf("%06d", frac), "0")
+}
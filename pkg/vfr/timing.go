@@ -0,0 +1,192 @@
+package vfr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// DefaultKeyframeSnap is the keyframe snapping used when none is given:
+// starts up to 5 frames before or 4 after a keyframe and ends up to 5 frames
+// before or 6 after one move onto it.
+var DefaultKeyframeSnap = KeyframeSnap{StartBefore: 5, StartAfter: 4, EndBefore: 5, EndAfter: 6}
+
+// KeyframeSnap holds the keyframe snapping thresholds of a TimingOptions,
+// in frames.
+type KeyframeSnap struct {
+	StartBefore int // a line starting this many frames before a keyframe starts on it
+	StartAfter  int // a line starting this many frames after a keyframe starts on it
+	EndBefore   int // a line ending this many frames before a keyframe ends at it
+	EndAfter    int // a line ending this many frames after a keyframe ends at it
+}
+
+// ParseKeyframeSnap parses "START_BEFORE,START_AFTER,END_BEFORE,END_AFTER"
+// frame thresholds, e.g. "5,4,5,6".
+func ParseKeyframeSnap(s string) (KeyframeSnap, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return KeyframeSnap{}, fmt.Errorf("expected four frame counts START_BEFORE,START_AFTER,END_BEFORE,END_AFTER, e.g. 5,4,5,6")
+	}
+	var v [4]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 {
+			return KeyframeSnap{}, fmt.Errorf("invalid frame count %q", p)
+		}
+		v[i] = n
+	}
+	return KeyframeSnap{StartBefore: v[0], StartAfter: v[1], EndBefore: v[2], EndAfter: v[3]}, nil
+}
+
+// TimingOptions configures PostProcess, a timing post-processor after
+// Aegisub's. The zero value leaves times unchanged.
+type TimingOptions struct {
+	LeadIn  uint64 // nanoseconds added before each start
+	LeadOut uint64 // nanoseconds added after each end
+
+	// LinkGap makes lines separated by a gap of at most this many
+	// nanoseconds continuous; 0 disables linking.
+	LinkGap uint64
+	// LinkBias is where linked lines meet within the gap, from 0 (at the
+	// later line's start: the earlier line is extended) to 1 (at the earlier
+	// line's end: the later line starts early).
+	LinkBias float64
+
+	// Keyframes snaps starts and ends near a keyframe onto it; nil disables
+	// snapping.
+	Keyframes *KeyframeSnap
+}
+
+// IsZero reports whether o leaves times unchanged.
+func (o TimingOptions) IsZero() bool {
+	return o.LeadIn == 0 && o.LeadOut == 0 && o.LinkGap == 0 && o.Keyframes == nil
+}
+
+// PostProcess adjusts event times in place as Aegisub's timing
+// post-processor does, in three passes: lead-in and lead-out padding,
+// linking of adjacent lines, then keyframe snapping.
+//
+// Lines are adjacent when they share a Style and follow each other in time,
+// so signs and songs on other styles never link to dialogue. Padding does
+// not run into an adjacent line that did not already overlap.
+//
+// Keyframe snapping needs tc and keyframes (frame indices, as from the
+// video track); it is skipped when either is missing. A snapped start or
+// end lies on the keyframe's frame boundary, so the line appears on, or
+// stops just before, the cut. Starts snap first; a snap that would leave a
+// line with no duration is not made.
+func PostProcess(events []subtitle.SubtitleEvent, opts TimingOptions, tc *Timecodes, keyframes []int) {
+	if opts.IsZero() || len(events) == 0 {
+		return
+	}
+
+	for _, lines := range byStyle(events) {
+		if opts.LeadIn > 0 || opts.LeadOut > 0 {
+			addLead(lines, opts.LeadIn, opts.LeadOut)
+		}
+		if opts.LinkGap > 0 {
+			link(lines, opts.LinkGap, min(max(opts.LinkBias, 0), 1))
+		}
+	}
+
+	if opts.Keyframes != nil && tc != nil && len(keyframes) > 0 {
+		keys := append([]int(nil), keyframes...)
+		sort.Ints(keys)
+		for i := range events {
+			snapToKeyframes(&events[i], tc, keys, *opts.Keyframes)
+		}
+	}
+}
+
+// byStyle groups events by Style, each group ordered by start time.
+func byStyle(events []subtitle.SubtitleEvent) [][]*subtitle.SubtitleEvent {
+	index := make(map[string]int)
+	var groups [][]*subtitle.SubtitleEvent
+	for i := range events {
+		ev := &events[i]
+		g, ok := index[ev.Style]
+		if !ok {
+			g = len(groups)
+			index[ev.Style] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], ev)
+	}
+	for _, lines := range groups {
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].Start < lines[j].Start })
+	}
+	return groups
+}
+
+// addLead pads lines by leadIn and leadOut, stopping at the neighbouring
+// line's original times unless the two already overlapped.
+func addLead(lines []*subtitle.SubtitleEvent, leadIn, leadOut uint64) {
+	starts := make([]uint64, len(lines))
+	ends := make([]uint64, len(lines))
+	for i, ev := range lines {
+		starts[i], ends[i] = ev.Start, ev.End
+	}
+
+	for i, ev := range lines {
+		if leadIn > 0 {
+			floor := uint64(0)
+			if i > 0 && ends[i-1] <= starts[i] {
+				floor = ends[i-1]
+			}
+			ev.Start = max(starts[i]-min(leadIn, starts[i]), floor)
+		}
+		if leadOut > 0 {
+			ceiling := ends[i] + leadOut
+			if i+1 < len(lines) && starts[i+1] >= ends[i] {
+				ceiling = min(ceiling, starts[i+1])
+			}
+			ev.End = ceiling
+		}
+	}
+}
+
+// link closes gaps of at most maxGap between consecutive lines, meeting at
+// bias through the gap measured back from the later line's start.
+func link(lines []*subtitle.SubtitleEvent, maxGap uint64, bias float64) {
+	for i := 0; i+1 < len(lines); i++ {
+		cur, next := lines[i], lines[i+1]
+		if next.Start <= cur.End || next.Start-cur.End > maxGap {
+			continue
+		}
+		gap := next.Start - cur.End
+		meet := next.Start - uint64(bias*float64(gap)+0.5)
+		cur.End, next.Start = meet, meet
+	}
+}
+
+// snapToKeyframes moves ev's start and end onto nearby keyframes.
+func snapToKeyframes(ev *subtitle.SubtitleEvent, tc *Timecodes, keys []int, snap KeyframeSnap) {
+	// The first frame shown, and the first frame no longer shown.
+	if k, ok := nearestKeyframe(keys, tc.frameFrom(ev.Start), snap.StartBefore, snap.StartAfter); ok {
+		if start := tc.StartTime(k); start < ev.End {
+			ev.Start = start
+		}
+	}
+	if k, ok := nearestKeyframe(keys, tc.frameFrom(ev.End), snap.EndBefore, snap.EndAfter); ok {
+		if end := tc.EndTime(k - 1); end > ev.Start {
+			ev.End = end
+		}
+	}
+}
+
+// nearestKeyframe returns the keyframe nearest frame f that is at most before
+// frames after it or at most after frames before it. keys must be sorted.
+func nearestKeyframe(keys []int, f, before, after int) (int, bool) {
+	i := sort.SearchInts(keys, f) // first keyframe at or after f
+	k, ok := 0, false
+	if i < len(keys) && keys[i]-f <= before {
+		k, ok = keys[i], true
+	}
+	if i > 0 && f-keys[i-1] <= after && (!ok || f-keys[i-1] < k-f) {
+		k, ok = keys[i-1], true
+	}
+	return k, ok
+}
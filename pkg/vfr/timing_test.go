@@ -0,0 +1,113 @@
+package vfr
+
+import (
+	"testing"
+
+	"mkv-sub-extractor/pkg/subtitle"
+)
+
+// line returns a Default-style event from start to end, in milliseconds.
+func line(start, end uint64) subtitle.SubtitleEvent {
+	return subtitle.SubtitleEvent{Start: start * ms, End: end * ms, Style: "Default"}
+}
+
+func TestPostProcess_Lead(t *testing.T) {
+	events := []subtitle.SubtitleEvent{line(100, 1000), line(1100, 2000), line(1900, 3000)}
+	PostProcess(events, TimingOptions{LeadIn: 200 * ms, LeadOut: 300 * ms}, nil, nil)
+
+	want := [][2]uint64{{0, 1100}, {1000, 2300}, {1700, 3300}}
+	for i, w := range want {
+		if events[i].Start != w[0]*ms || events[i].End != w[1]*ms {
+			t.Errorf("event %d = %d-%d ms, want %d-%d", i, events[i].Start/ms, events[i].End/ms, w[0], w[1])
+		}
+	}
+}
+
+func TestPostProcess_Link(t *testing.T) {
+	tests := []struct {
+		name      string
+		bias      float64
+		wantMeet  uint64
+		secondEnd uint64
+	}{
+		{"extend earlier line", 0, 1200, 2000},
+		{"start later line early", 1, 1000, 2000},
+		{"meet halfway", 0.5, 1100, 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := []subtitle.SubtitleEvent{line(0, 1000), line(1200, 2000), line(2600, 3000)}
+			PostProcess(events, TimingOptions{LinkGap: 500 * ms, LinkBias: tt.bias}, nil, nil)
+
+			if events[0].End != tt.wantMeet*ms || events[1].Start != tt.wantMeet*ms {
+				t.Errorf("linked at %d/%d ms, want %d", events[0].End/ms, events[1].Start/ms, tt.wantMeet)
+			}
+			if events[1].End != tt.secondEnd*ms || events[2].Start != 2600*ms {
+				t.Errorf("gap of 600 ms linked: %d-%d ms", events[1].End/ms, events[2].Start/ms)
+			}
+		})
+	}
+}
+
+func TestPostProcess_LinkSameStyleOnly(t *testing.T) {
+	sign := line(1100, 1500)
+	sign.Style = "Sign"
+	events := []subtitle.SubtitleEvent{line(0, 1000), sign, line(1200, 2000)}
+	PostProcess(events, TimingOptions{LinkGap: 500 * ms}, nil, nil)
+
+	if events[0].End != 1200*ms {
+		t.Errorf("dialogue not linked across a sign: end = %d ms", events[0].End/ms)
+	}
+	if events[1].Start != 1100*ms || events[1].End != 1500*ms {
+		t.Errorf("sign retimed to %d-%d ms", events[1].Start/ms, events[1].End/ms)
+	}
+}
+
+func TestPostProcess_Keyframes(t *testing.T) {
+	// 25 fps: frame f at 40f ms; boundaries between frames at 40f-20 ms.
+	times := make([]uint64, 200)
+	for i := range times {
+		times[i] = uint64(i) * 40 * ms
+	}
+	tc, _ := New(times)
+	keys := []int{0, 50, 100}
+	snap := DefaultKeyframeSnap
+
+	tests := []struct {
+		name       string
+		start, end uint64 // ms
+		wantStart  uint64
+		wantEnd    uint64
+	}{
+		{"start before keyframe", 1900, 3000, 1980, 3000},     // frame 48 -> 50
+		{"start after keyframe", 2100, 3000, 1980, 3000},      // frame 53 -> 50
+		{"start too far after", 2200, 3000, 2200, 3000},       // frame 55
+		{"end before keyframe", 1000, 3850, 1000, 3980},       // frame 97 -> 100
+		{"end after keyframe", 1000, 4200, 1000, 3980},        // frame 105 -> 100
+		{"end too far after", 1000, 4300, 1000, 4300},         // frame 108
+		{"both on one keyframe", 1970, 2050, 1980, 2050},      // end would empty it
+		{"short line across cut", 1960, 2100, 1980, 2100},     // start 49 -> 50
+		{"start exactly on keyframe", 1990, 2500, 1980, 2500}, // frame 50
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := []subtitle.SubtitleEvent{line(tt.start, tt.end)}
+			PostProcess(events, TimingOptions{Keyframes: &snap}, tc, keys)
+			if events[0].Start != tt.wantStart*ms || events[0].End != tt.wantEnd*ms {
+				t.Errorf("got %d-%d ms, want %d-%d", events[0].Start/ms, events[0].End/ms, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParseKeyframeSnap(t *testing.T) {
+	got, err := ParseKeyframeSnap("5, 4,5,6")
+	if err != nil || got != DefaultKeyframeSnap {
+		t.Errorf("ParseKeyframeSnap = %+v, %v", got, err)
+	}
+	for _, s := range []string{"", "1,2,3", "1,2,3,x", "1,2,-3,4"} {
+		if _, err := ParseKeyframeSnap(s); err == nil {
+			t.Errorf("ParseKeyframeSnap(%q): expected error", s)
+		}
+	}
+}
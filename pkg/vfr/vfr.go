@@ -0,0 +1,127 @@
+// Package vfr maps subtitle times to the frames of a video track, using the
+// frames' own timestamps so variable frame rate video works too.
+//
+// Frame boundaries follow Aegisub (libaegisub/vfr): a line shown from frame
+// s through frame e starts halfway between frames s-1 and s and ends halfway
+// between frames e and e+1, so renderers showing a line on every frame whose
+// timestamp t satisfies start <= t < end display exactly those frames.
+package vfr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// centisecond is the resolution of ASS timestamps, in nanoseconds.
+const centisecond = 10_000_000
+
+// Timecodes holds the presentation timestamps of a video track's frames.
+type Timecodes struct {
+	frames []uint64 // nanoseconds, ascending and distinct
+	avg    float64  // average frame duration, for times outside the video
+}
+
+// New builds Timecodes from frame timestamps in nanoseconds, in any order
+// (video blocks are stored in decode order). Duplicates are dropped. At least
+// two distinct timestamps are needed to know the frame rate.
+func New(timestamps []uint64) (*Timecodes, error) {
+	frames := append([]uint64(nil), timestamps...)
+	sort.Slice(frames, func(i, j int) bool { return frames[i] < frames[j] })
+
+	n := 0
+	for i, t := range frames {
+		if i == 0 || t != frames[n-1] {
+			frames[n] = t
+			n++
+		}
+	}
+	frames = frames[:n]
+	if len(frames) < 2 {
+		return nil, fmt.Errorf("need at least 2 video frames, got %d", len(frames))
+	}
+
+	avg := float64(frames[n-1]-frames[0]) / float64(n-1)
+	return &Timecodes{frames: frames, avg: avg}, nil
+}
+
+// Len returns the number of frames.
+func (tc *Timecodes) Len() int {
+	return len(tc.frames)
+}
+
+// TimeAtFrame returns the timestamp of frame f. Frames before the first or
+// after the last are extrapolated at the average frame rate, as in Aegisub.
+func (tc *Timecodes) TimeAtFrame(f int) int64 {
+	last := len(tc.frames) - 1
+	switch {
+	case f < 0:
+		return int64(tc.frames[0]) + int64(math.Round(float64(f)*tc.avg))
+	case f > last:
+		return int64(tc.frames[last]) + int64(math.Round(float64(f-last)*tc.avg))
+	}
+	return int64(tc.frames[f])
+}
+
+// FrameAtTime returns the frame showing at time t: the last frame whose
+// timestamp is not after t (-1 before the first frame; extrapolated after
+// the last).
+func (tc *Timecodes) FrameAtTime(t uint64) int {
+	last := len(tc.frames) - 1
+	if t > tc.frames[last] {
+		return last + int(float64(t-tc.frames[last])/tc.avg)
+	}
+	return sort.Search(len(tc.frames), func(i int) bool { return tc.frames[i] > t }) - 1
+}
+
+// StartTime returns the time at which a line must start to first appear on
+// frame f: halfway between frames f-1 and f, rounded up.
+func (tc *Timecodes) StartTime(f int) uint64 {
+	prev, cur := tc.TimeAtFrame(f-1), tc.TimeAtFrame(f)
+	return onGrid(prev, cur, prev+(cur-prev+1)/2)
+}
+
+// EndTime returns the time at which a line must end to last appear on frame
+// f: halfway between frames f and f+1, rounded up.
+func (tc *Timecodes) EndTime(f int) uint64 {
+	cur, next := tc.TimeAtFrame(f), tc.TimeAtFrame(f+1)
+	return onGrid(cur, next, cur+(next-cur+1)/2)
+}
+
+// onGrid moves t, which lies in (lo, hi], to the nearest centisecond that
+// stays in that interval, so the ASS timestamp still selects the same frame.
+// Times before zero become zero.
+func onGrid(lo, hi, t int64) uint64 {
+	g := (t + centisecond/2) / centisecond * centisecond
+	if g <= lo {
+		g = (lo/centisecond + 1) * centisecond
+	}
+	if g > hi {
+		g = hi / centisecond * centisecond
+	}
+	if g <= lo {
+		// The interval holds no centisecond; keep the exact midpoint.
+		g = t
+	}
+	return uint64(max(g, 0))
+}
+
+// Snap moves an event's start and end onto frame boundaries. The event keeps
+// the frames it covered: those with timestamps in [start, end). An event
+// falling between two frames is shown on the next one.
+func (tc *Timecodes) Snap(start, end uint64) (uint64, uint64) {
+	first := tc.frameFrom(start)
+	last := max(tc.frameFrom(end)-1, first)
+	return tc.StartTime(first), tc.EndTime(last)
+}
+
+// frameFrom returns the first frame whose timestamp is not before t: the
+// first frame a line starting at t is shown on, or the first frame a line
+// ending at t is no longer shown on.
+func (tc *Timecodes) frameFrom(t uint64) int {
+	f := tc.FrameAtTime(t)
+	if f < 0 || tc.TimeAtFrame(f) < int64(t) {
+		f++
+	}
+	return f
+}
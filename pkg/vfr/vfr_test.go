@@ -0,0 +1,116 @@
+package vfr
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const ms = 1_000_000
+
+// ntsc returns n frame timestamps at 24000/1001 fps, rounded to milliseconds
+// as Matroska stores them.
+func ntsc(n int) []uint64 {
+	ts := make([]uint64, n)
+	for i := range ts {
+		ts[i] = uint64(math.Round(float64(i)*1001/24)) * ms
+	}
+	return ts
+}
+
+func TestNew(t *testing.T) {
+	tc, err := New([]uint64{80 * ms, 0, 40 * ms, 40 * ms})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.Len() != 3 || tc.TimeAtFrame(1) != 40*ms {
+		t.Errorf("Len() = %d, TimeAtFrame(1) = %d", tc.Len(), tc.TimeAtFrame(1))
+	}
+	if tc.TimeAtFrame(4) != 160*ms || tc.TimeAtFrame(-1) != -40*ms {
+		t.Errorf("extrapolated frames = %d, %d", tc.TimeAtFrame(4), tc.TimeAtFrame(-1))
+	}
+	if _, err := New([]uint64{0}); err == nil {
+		t.Error("expected error for a single frame")
+	}
+}
+
+func TestFrameAtTime(t *testing.T) {
+	tc, _ := New(ntsc(100))
+	tests := []struct {
+		t    uint64
+		want int
+	}{
+		{0, 0},
+		{41 * ms, 0},
+		{42 * ms, 1},
+		{1001 * ms, 24},
+		{4128 * ms, 98},
+		{4129 * ms, 99},
+		{4200 * ms, 100},
+	}
+	for _, tt := range tests {
+		if got := tc.FrameAtTime(tt.t); got != tt.want {
+			t.Errorf("FrameAtTime(%d ms) = %d, want %d", tt.t/ms, got, tt.want)
+		}
+	}
+}
+
+func TestSnap_NTSC(t *testing.T) {
+	tc, _ := New(ntsc(100))
+
+	// Frames 24 (1001 ms) through 47 (1960 ms) lie in [1s, 2s).
+	start, end := tc.Snap(1000*ms, 2000*ms)
+	if start != 980*ms || end != 1980*ms {
+		t.Errorf("Snap(1s, 2s) = %d ms, %d ms, want 980, 1980", start/ms, end/ms)
+	}
+	// Snapping is stable.
+	if s2, e2 := tc.Snap(start, end); s2 != start || e2 != end {
+		t.Errorf("re-snap = %d ms, %d ms", s2/ms, e2/ms)
+	}
+	// The snapped times select the same frames.
+	if tc.FrameAtTime(start)+1 != 24 || tc.FrameAtTime(end) != 47 {
+		t.Errorf("snapped range covers frames %d-%d", tc.FrameAtTime(start)+1, tc.FrameAtTime(end))
+	}
+}
+
+func TestSnap_VFR(t *testing.T) {
+	// 25 fps, then 100 fps.
+	tc, _ := New([]uint64{0, 40 * ms, 80 * ms, 90 * ms, 100 * ms, 110 * ms})
+
+	// An event between frames 1 and 2 is shown on frame 2 only: from
+	// midway after frame 1 to the first centisecond after frame 2 that
+	// does not reach frame 3's midpoint.
+	start, end := tc.Snap(50*ms, 60*ms)
+	if start != 60*ms || end != 90*ms {
+		t.Errorf("Snap(50ms, 60ms) = %d ms, %d ms, want 60, 90", start/ms, end/ms)
+	}
+
+	// Frame 3 at 100 fps: the midpoints 85 and 95 ms round onto frame
+	// timestamps, which still select exactly frame 3.
+	start, end = tc.Snap(90*ms, 100*ms)
+	if start != 90*ms || end != 100*ms {
+		t.Errorf("Snap(90ms, 100ms) = %d ms, %d ms, want 90, 100", start/ms, end/ms)
+	}
+}
+
+func TestWriteTimecodesV2(t *testing.T) {
+	var b strings.Builder
+	if err := WriteTimecodesV2(&b, []uint64{0, 41708333, 83 * ms}); err != nil {
+		t.Fatal(err)
+	}
+	want := "# timecode format v2\n0\n41.708333\n83\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestWriteKeyframes(t *testing.T) {
+	var b strings.Builder
+	if err := WriteKeyframes(&b, []int{0, 250, 498}); err != nil {
+		t.Fatal(err)
+	}
+	want := "# keyframe format v1\nfps 0\n0\n250\n498\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,71 @@
+package zhconv
+
+import (
+	"bufio"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// forms holds the characters written only in Simplified and only in
+// Traditional form.
+type forms struct {
+	simplified, traditional map[rune]bool
+}
+
+// loadForms reads the character dictionaries: a character converts to other
+// forms only, never to itself, exactly when it is written in one form only.
+var loadForms = sync.OnceValue(func() forms {
+	f := forms{
+		simplified:  onlyForm("STCharacters"),
+		traditional: onlyForm("TSCharacters"),
+	}
+	for r := range f.simplified {
+		if f.traditional[r] {
+			delete(f.simplified, r)
+			delete(f.traditional, r)
+		}
+	}
+	return f
+})
+
+// onlyForm returns the characters of the named character dictionary none of
+// whose conversions is the character itself.
+func onlyForm(name string) map[rune]bool {
+	f, err := dictFiles.Open("dict/" + name + ".txt")
+	if err != nil {
+		panic(err) // the files are embedded: a missing one is a build error
+	}
+	defer f.Close()
+
+	only := make(map[rune]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		char, convs, ok := strings.Cut(sc.Text(), "\t")
+		if !ok || utf8.RuneCountInString(char) != 1 {
+			continue
+		}
+		if !strings.Contains(" "+convs+" ", " "+char+" ") {
+			r, _ := utf8.DecodeRuneInString(char)
+			only[r] = true
+		}
+	}
+	return only
+}
+
+// CountForms returns the number of characters of text written only in
+// Simplified form (such as 发 or 们) and only in Traditional form (such as
+// 發 or 們). Characters written alike in both, or that the other form also
+// uses (such as 干 or 著), count for neither.
+func CountForms(text string) (simplified, traditional int) {
+	f := loadForms()
+	for _, r := range text {
+		switch {
+		case f.simplified[r]:
+			simplified++
+		case f.traditional[r]:
+			traditional++
+		}
+	}
+	return simplified, traditional
+}
@@ -0,0 +1,267 @@
+// Package zhconv converts Chinese text between Simplified and Traditional
+// characters, and to the Traditional standards of Taiwan and Hong Kong, the
+// way OpenCC (Open Chinese Convert) does and with its dictionaries, which are
+// embedded in the binary.
+//
+// Conversion goes by phrase before character: the text is split into the
+// longest words of a phrase dictionary, and each word then passes through a
+// chain of dictionaries, longest match first. A character with several
+// conversions thus takes the one its word calls for: 头发 becomes 頭髮 but
+// 发展 becomes 發展.
+package zhconv
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed dict/*.txt
+var dictFiles embed.FS
+
+// Variant is a written form of Chinese to convert to.
+type Variant int
+
+const (
+	None        Variant = iota // no conversion
+	Simplified                 // Simplified characters, as in Mainland China
+	Traditional                // Traditional characters (OpenCC standard)
+	Taiwan                     // Traditional characters and vocabulary of Taiwan
+	HongKong                   // Traditional characters as written in Hong Kong
+)
+
+// variants lists each variant's BCP 47 tag and the names ParseVariant
+// accepts for it.
+var variants = []struct {
+	variant Variant
+	tag     string
+	names   []string
+}{
+	{None, "", []string{"none", "keep"}},
+	{Simplified, "zh-Hans", []string{"s", "cn", "hans", "zh-hans", "zh-cn", "chs"}},
+	{Traditional, "zh-Hant", []string{"t", "hant", "zh-hant", "cht"}},
+	{Taiwan, "zh-TW", []string{"tw", "zh-tw"}},
+	{HongKong, "zh-HK", []string{"hk", "zh-hk"}},
+}
+
+// ParseVariant parses a variant name: "cn" (or "s", "zh-Hans"), "t" (or
+// "zh-Hant"), "tw" (or "zh-TW"), "hk" (or "zh-HK"), or "none". Case is
+// ignored.
+func ParseVariant(s string) (Variant, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for _, v := range variants {
+		for _, n := range v.names {
+			if n == name {
+				return v.variant, nil
+			}
+		}
+	}
+	return None, fmt.Errorf("unknown Chinese variant %q (available: cn, t, tw, hk, none)", s)
+}
+
+// Tag returns the variant's BCP 47 language tag, such as "zh-TW", or "" for
+// None.
+func (v Variant) Tag() string {
+	for _, e := range variants {
+		if e.variant == v {
+			return e.tag
+		}
+	}
+	return ""
+}
+
+// String returns the variant's tag, or "none".
+func (v Variant) String() string {
+	if v == None {
+		return "none"
+	}
+	return v.Tag()
+}
+
+// configs lists, as OpenCC's s2t, s2twp, s2hk and t2s configurations do, the
+// dictionary that splits text into words and the chain of dictionary groups
+// each word then passes through. Within a group, earlier files take
+// precedence.
+var configs = map[Variant]struct {
+	segment string
+	chain   [][]string
+}{
+	Simplified:  {"TSPhrases", [][]string{{"TSPhrases", "TSCharacters"}}},
+	Traditional: {"STPhrases", [][]string{{"STPhrases", "STCharacters"}}},
+	Taiwan:      {"STPhrases", [][]string{{"STPhrases", "STCharacters"}, {"TWPhrases"}, {"TWVariants"}}},
+	HongKong:    {"STPhrases", [][]string{{"STPhrases", "STCharacters"}, {"HKVariants"}}},
+}
+
+// Converter converts text to one variant. It is safe for concurrent use.
+type Converter struct {
+	segment *dict
+	chain   []*dict
+}
+
+var (
+	convertersMu sync.Mutex
+	converters   = make(map[Variant]*Converter)
+)
+
+// For returns the converter to v, loading its dictionaries on first use. The
+// converter for None returns text unchanged.
+func For(v Variant) *Converter {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	if c, ok := converters[v]; ok {
+		return c
+	}
+	c := &Converter{}
+	if cfg, ok := configs[v]; ok {
+		c.segment = loadDict(cfg.segment)
+		for _, group := range cfg.chain {
+			c.chain = append(c.chain, loadDict(group...))
+		}
+	}
+	converters[v] = c
+	return c
+}
+
+// Convert converts text to v.
+func Convert(text string, v Variant) string {
+	return For(v).Convert(text)
+}
+
+// Convert converts text. Text that is not Chinese, such as Latin letters,
+// markup and punctuation, is kept as it is.
+func (c *Converter) Convert(text string) string {
+	if c.segment == nil || !strings.ContainsFunc(text, isHan) {
+		return text
+	}
+
+	var out strings.Builder
+	out.Grow(len(text))
+	pending := 0 // start of the text not matching the segment dictionary
+	for i := 0; i < len(text); {
+		n, _ := c.segment.match(text[i:])
+		if n == 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		if pending < i {
+			out.WriteString(c.convertWord(text[pending:i]))
+		}
+		out.WriteString(c.convertWord(text[i : i+n]))
+		i += n
+		pending = i
+	}
+	if pending < len(text) {
+		out.WriteString(c.convertWord(text[pending:]))
+	}
+	return out.String()
+}
+
+// convertWord passes one segment of text through the conversion chain.
+func (c *Converter) convertWord(word string) string {
+	for _, d := range c.chain {
+		word = d.convert(word)
+	}
+	return word
+}
+
+// isHan reports whether r is a Chinese character.
+func isHan(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+// dict is a conversion dictionary: words mapped to their conversion.
+type dict struct {
+	entries map[string]string
+	maxLen  int // length of the longest word, in bytes
+}
+
+var (
+	filesMu sync.Mutex
+	files   = make(map[string]map[string]string) // parsed dictionary files by name
+)
+
+// loadDict returns the dictionary made of the named files, earlier files
+// taking precedence for words in several.
+func loadDict(names ...string) *dict {
+	d := &dict{entries: make(map[string]string)}
+	for _, name := range names {
+		for word, conv := range loadFile(name) {
+			if _, ok := d.entries[word]; !ok {
+				d.entries[word] = conv
+				d.maxLen = max(d.maxLen, len(word))
+			}
+		}
+	}
+	return d
+}
+
+// loadFile parses an embedded dictionary file: one word per line, a tab and
+// its space-separated conversions, of which the first is kept.
+func loadFile(name string) map[string]string {
+	filesMu.Lock()
+	defer filesMu.Unlock()
+	if entries, ok := files[name]; ok {
+		return entries
+	}
+
+	f, err := dictFiles.Open("dict/" + name + ".txt")
+	if err != nil {
+		panic(err) // the files are embedded: a missing one is a build error
+	}
+	defer f.Close()
+
+	entries := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		word, convs, ok := strings.Cut(sc.Text(), "\t")
+		if !ok || word == "" {
+			continue
+		}
+		conv, _, _ := strings.Cut(convs, " ")
+		if _, ok := entries[word]; !ok {
+			entries[word] = conv
+		}
+	}
+	files[name] = entries
+	return entries
+}
+
+// match returns the length in bytes of the longest word of d that s starts
+// with, and its conversion; 0 if none.
+func (d *dict) match(s string) (int, string) {
+	end := min(len(s), d.maxLen)
+	for end < len(s) && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	for end > 0 {
+		if conv, ok := d.entries[s[:end]]; ok {
+			return end, conv
+		}
+		_, size := utf8.DecodeLastRuneInString(s[:end])
+		end -= size
+	}
+	return 0, ""
+}
+
+// convert converts s word by word, longest match first, keeping characters
+// without an entry.
+func (d *dict) convert(s string) string {
+	var out strings.Builder
+	out.Grow(len(s))
+	for i := 0; i < len(s); {
+		if n, conv := d.match(s[i:]); n > 0 {
+			out.WriteString(conv)
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		out.WriteString(s[i : i+size])
+		i += size
+	}
+	return out.String()
+}
@@ -0,0 +1,99 @@
+package zhconv
+
+import "testing"
+
+func TestParseVariant(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Variant
+	}{
+		{"cn", Simplified},
+		{"zh-Hans", Simplified},
+		{"T", Traditional},
+		{"tw", Taiwan},
+		{" zh-HK ", HongKong},
+		{"none", None},
+	}
+	for _, tt := range tests {
+		got, err := ParseVariant(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseVariant(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseVariant("jp"); err == nil {
+		t.Error("ParseVariant(jp) succeeded")
+	}
+}
+
+func TestVariant_Tag(t *testing.T) {
+	for v, want := range map[Variant]string{None: "", Simplified: "zh-Hans", Traditional: "zh-Hant", Taiwan: "zh-TW", HongKong: "zh-HK"} {
+		if got := v.Tag(); got != want {
+			t.Errorf("%d.Tag() = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		in   string
+		v    Variant
+		want string
+	}{
+		// Phrases pick among a character's conversions.
+		{"头发和发展", Traditional, "頭髮和發展"},
+		{"皇后的后面", Traditional, "皇后的後面"},
+		{"什么时候回来？", Traditional, "什麼時候回來？"},
+		// Regional characters and vocabulary.
+		{"里面的台湾", Taiwan, "裡面的臺灣"},
+		{"鼠标和软件", Taiwan, "滑鼠和軟體"},
+		{"里面的台湾", HongKong, "裏面的台灣"},
+		{"我们一起吃面", HongKong, "我們一起吃麪"},
+		// Back to Simplified.
+		{"頭髮和發展", Simplified, "头发和发展"},
+		{"乾淨的天干", Simplified, "干净的天干"},
+		// Text already in the target form, and text that is not Chinese.
+		{"頭髮", Traditional, "頭髮"},
+		{"头发", Simplified, "头发"},
+		{"Hello, 头发!", Traditional, "Hello, 頭髮!"},
+		{"Hello", Taiwan, "Hello"},
+		{"头发", None, "头发"},
+		{"", Traditional, ""},
+	}
+	for _, tt := range tests {
+		if got := Convert(tt.in, tt.v); got != tt.want {
+			t.Errorf("Convert(%q, %v) = %q, want %q", tt.in, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestDict_Match(t *testing.T) {
+	d := &dict{entries: map[string]string{"发": "發", "头发": "頭髮"}, maxLen: len("头发")}
+	if n, conv := d.match("头发长"); n != len("头发") || conv != "頭髮" {
+		t.Errorf("match(头发长) = %d, %q", n, conv)
+	}
+	if n, _ := d.match("长发"); n != 0 {
+		t.Errorf("match(长发) = %d, want 0", n)
+	}
+	if got := d.convert("长发头发"); got != "长發頭髮" {
+		t.Errorf("convert() = %q", got)
+	}
+}
+
+func TestCountForms(t *testing.T) {
+	tests := []struct {
+		in         string
+		simp, trad int
+	}{
+		{"我们的头发", 3, 0}, // 们, 头, 发
+		{"我們的頭髮", 0, 3}, // 們, 頭, 髮
+		{"天干和著名", 0, 0}, // alike in both forms
+		{"Hello 发發", 1, 1},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		simp, trad := CountForms(tt.in)
+		if simp != tt.simp || trad != tt.trad {
+			t.Errorf("CountForms(%q) = %d, %d; want %d, %d", tt.in, simp, trad, tt.simp, tt.trad)
+		}
+	}
+}